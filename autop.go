@@ -0,0 +1,195 @@
+package wpimport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// autopBlocks is the list of block-level elements wpautop never wraps in
+// paragraphs
+const autopBlocks = `(?:table|thead|tfoot|caption|col|colgroup|tbody|tr|td|th|div|dl|dd|dt|ul|ol|li|pre|form|map|area|blockquote|address|style|p|h[1-6]|hr|fieldset|legend|section|article|aside|hgroup|header|footer|nav|figure|figcaption|details|menu|summary)`
+
+var (
+	autopDoubleBR       = regexp.MustCompile(`<br\s*/?>\s*<br\s*/?>`)
+	autopBlockOpen      = regexp.MustCompile(`(<` + autopBlocks + `[\s/>])`)
+	autopBlockClose     = regexp.MustCompile(`(</` + autopBlocks + `>)`)
+	autopHR             = regexp.MustCompile(`(<hr\s*?/?>)`)
+	autopHTMLTag        = regexp.MustCompile(`(?s)<!--.*?-->|<[^>]*>`)
+	autopParagraphSplit = regexp.MustCompile(`\n\s*\n`)
+	autopEmptyP         = regexp.MustCompile(`<p>\s*</p>`)
+	autopUnclosedP      = regexp.MustCompile(`<p>([^<]+)</(div|address|form)>`)
+	autopWrappedBlock   = regexp.MustCompile(`<p>\s*(</?` + autopBlocks + `[^>]*>)\s*</p>`)
+	autopWrappedLI      = regexp.MustCompile(`<p>(<li.+?)</p>`)
+	autopBlockquoteP    = regexp.MustCompile(`(?i)<p><blockquote([^>]*)>`)
+	autopPBeforeBlock   = regexp.MustCompile(`<p>\s*(</?` + autopBlocks + `[^>]*>)`)
+	autopPAfterBlock    = regexp.MustCompile(`(</?` + autopBlocks + `[^>]*>)\s*</p>`)
+	autopPreserveNL     = regexp.MustCompile(`(?s)<(script|style|svg|math)\b.*?</(?:script|style|svg|math)>`)
+	autopNewline        = regexp.MustCompile(`\s*\n`)
+	autopBRAfterBlock   = regexp.MustCompile(`(</?` + autopBlocks + `[^>]*>)\s*<br />`)
+	autopBRBeforeBlock  = regexp.MustCompile(`<br />(\s*</?(?:p|li|div|dl|dd|dt|th|pre|td|ul|ol)[^>]*>)`)
+	autopParagraphTag   = regexp.MustCompile(`(?i)<p[\s>]`)
+	autopShortcodeP     = regexp.MustCompile(`(?s)<p>\s*(\[[\w-]+[^\]]*\](?:.*?\[/[\w-]+\])?)\s*</p>`)
+)
+
+// Autop adds paragraph and line break tags to classic-editor content the
+// way WordPress's wpautop does at render time. Double line breaks become
+// paragraphs, single line breaks become <br />, and block-level elements
+// and <pre> contents are left alone.
+func Autop(content string) string {
+	return autop(content, true)
+}
+
+// Helper function to check whether content needs wpautop: classic content
+// has neither block markup nor paragraph tags
+func needsAutop(content string) bool {
+	return !hasBlockMarkup(content) && !autopParagraphTag.MatchString(content) && strings.TrimSpace(content) != ""
+}
+
+// Helper function implementing wpautop, optionally converting single line
+// breaks to <br />
+func autop(text string, br bool) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	text += "\n"
+
+	// Pre tags shouldn't be touched, swap them out for placeholders
+	preTags := make(map[string]string)
+	if strings.Contains(text, "<pre") {
+		parts := strings.Split(text, "</pre>")
+		last := parts[len(parts)-1]
+		text = ""
+		i := 0
+		for _, part := range parts[:len(parts)-1] {
+			start := strings.Index(part, "<pre")
+			if start == -1 {
+				text += part
+				continue
+			}
+			name := fmt.Sprintf("<pre wp-pre-tag-%d></pre>", i)
+			preTags[name] = part[start:] + "</pre>"
+			text += part[:start] + name
+			i++
+		}
+		text += last
+	}
+
+	// Change multiple <br>s into two line breaks
+	text = autopDoubleBR.ReplaceAllString(text, "\n\n")
+
+	// Add double line breaks around block-level tags
+	text = autopBlockOpen.ReplaceAllString(text, "\n\n$1")
+	text = autopBlockClose.ReplaceAllString(text, "$1\n\n")
+	text = autopHR.ReplaceAllString(text, "$1\n\n")
+
+	// Standardize newline characters
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+
+	// Protect newlines inside tags and comments
+	text = autopHTMLTag.ReplaceAllStringFunc(text, func(tag string) string {
+		return strings.ReplaceAll(tag, "\n", " <!-- wpnl --> ")
+	})
+
+	// Collapse line breaks around elements that must not be wrapped
+	if strings.Contains(text, "<option") {
+		text = regexp.MustCompile(`\s*<option`).ReplaceAllString(text, "<option")
+		text = regexp.MustCompile(`</option>\s*`).ReplaceAllString(text, "</option>")
+	}
+	if strings.Contains(text, "</object>") {
+		text = regexp.MustCompile(`(<object[^>]*>)\s*`).ReplaceAllString(text, "$1")
+		text = regexp.MustCompile(`\s*</object>`).ReplaceAllString(text, "</object>")
+		text = regexp.MustCompile(`\s*(</?(?:param|embed)[^>]*>)\s*`).ReplaceAllString(text, "$1")
+	}
+	if strings.Contains(text, "<source") || strings.Contains(text, "<track") {
+		text = regexp.MustCompile(`([<\[](?:audio|video)[^>\]]*[>\]])\s*`).ReplaceAllString(text, "$1")
+		text = regexp.MustCompile(`\s*([<\[]/(?:audio|video)[>\]])`).ReplaceAllString(text, "$1")
+		text = regexp.MustCompile(`\s*(<(?:source|track)[^>]*>)\s*`).ReplaceAllString(text, "$1")
+	}
+	if strings.Contains(text, "<figcaption") {
+		text = regexp.MustCompile(`\s*(<figcaption[^>]*>)`).ReplaceAllString(text, "$1")
+		text = regexp.MustCompile(`</figcaption>\s*`).ReplaceAllString(text, "</figcaption>")
+	}
+
+	// Remove more than two contiguous line breaks
+	text = regexp.MustCompile(`\n\n+`).ReplaceAllString(text, "\n\n")
+
+	// Wrap each chunk separated by a blank line in a paragraph
+	var sb strings.Builder
+	for _, paragraph := range autopParagraphSplit.Split(text, -1) {
+		if paragraph == "" {
+			continue
+		}
+		sb.WriteString("<p>" + strings.Trim(paragraph, "\n") + "</p>\n")
+	}
+	text = sb.String()
+
+	// Undo the paragraphs that wrap block-level elements
+	text = autopEmptyP.ReplaceAllString(text, "")
+	text = autopUnclosedP.ReplaceAllString(text, "<p>$1</p></$2>")
+	text = autopWrappedBlock.ReplaceAllString(text, "$1")
+	text = autopWrappedLI.ReplaceAllString(text, "$1")
+	text = autopBlockquoteP.ReplaceAllString(text, "<blockquote$1><p>")
+	text = strings.ReplaceAll(text, "</blockquote></p>", "</p></blockquote>")
+	text = autopPBeforeBlock.ReplaceAllString(text, "$1")
+	text = autopPAfterBlock.ReplaceAllString(text, "$1")
+
+	if br {
+		// Newlines in script, style, svg and math must survive
+		text = autopPreserveNL.ReplaceAllStringFunc(text, func(match string) string {
+			return strings.ReplaceAll(match, "\n", "<WPPreserveNewline />")
+		})
+
+		text = strings.NewReplacer("<br>", "<br />", "<br/>", "<br />").Replace(text)
+		text = replaceNewlinesWithBR(text)
+		text = strings.ReplaceAll(text, "<WPPreserveNewline />", "\n")
+	}
+
+	// Remove line breaks next to block-level tags
+	text = autopBRAfterBlock.ReplaceAllString(text, "$1")
+	text = autopBRBeforeBlock.ReplaceAllString(text, "$1")
+	if strings.HasSuffix(text, "\n</p>") {
+		text = strings.TrimSuffix(text, "\n</p>") + "</p>"
+	}
+
+	// Put the <pre> contents back
+	for name, pre := range preTags {
+		text = strings.Replace(text, name, pre, 1)
+	}
+
+	// Restore newlines inside tags
+	text = strings.ReplaceAll(text, " <!-- wpnl --> ", "\n")
+	text = strings.ReplaceAll(text, "<!-- wpnl -->", "\n")
+
+	return text
+}
+
+// Helper function to turn every newline that is not already preceded by a
+// <br /> into one (wpautop's (?<!<br />)\s*\n)
+func replaceNewlinesWithBR(text string) string {
+	var sb strings.Builder
+	pos := 0
+	for _, loc := range autopNewline.FindAllStringIndex(text, -1) {
+		sb.WriteString(text[pos:loc[0]])
+		if strings.HasSuffix(text[:loc[0]], "<br />") {
+			sb.WriteString(text[loc[0]:loc[1]])
+		} else {
+			sb.WriteString("<br />\n")
+		}
+		pos = loc[1]
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// Helper function to unwrap shortcodes that wpautop left alone in a
+// paragraph, like WordPress's shortcode_unautop
+func shortcodeUnautop(text string) string {
+	return autopShortcodeP.ReplaceAllStringFunc(text, func(match string) string {
+		inner := strings.TrimSpace(autopShortcodeP.FindStringSubmatch(match)[1])
+		if _, start, end, escaped, ok := nextShortcode(inner, 0); ok && !escaped && start == 0 && end == len(inner) {
+			return inner
+		}
+		return match
+	})
+}
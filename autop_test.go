@@ -0,0 +1,81 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestAutop tests wpautop emulation against WordPress's own output
+func TestAutop(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "paragraphs and line breaks",
+			input:    "First line\nsecond line\n\nSecond paragraph",
+			expected: "<p>First line<br />\nsecond line</p>\n<p>Second paragraph</p>\n",
+		},
+		{
+			name:     "block-level elements are not wrapped",
+			input:    "Intro\n<ul>\n<li>One</li>\n</ul>\n<h2>Title</h2>\nOutro",
+			expected: "<p>Intro</p>\n<ul>\n<li>One</li>\n</ul>\n<h2>Title</h2>\n<p>Outro</p>\n",
+		},
+		{
+			name:     "pre contents are preserved",
+			input:    "Code:\n\n<pre>line 1\n\nline 2</pre>",
+			expected: "<p>Code:</p>\n<pre>line 1\n\nline 2</pre>\n",
+		},
+		{
+			name:     "newlines inside tags are preserved",
+			input:    "<a\nhref=\"x\">link</a> text",
+			expected: "<p><a\nhref=\"x\">link</a> text</p>\n",
+		},
+		{
+			name:     "blockquote paragraphs move inside",
+			input:    "<blockquote>Quoted</blockquote>",
+			expected: "<blockquote><p>Quoted</p></blockquote>\n",
+		},
+		{
+			name:     "empty content",
+			input:    "  \n ",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Autop(tt.input); got != tt.expected {
+				t.Errorf("Autop(%q)\n got: %q\nwant: %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestAutopAppliedToClassicContent tests that conversions add paragraphs to
+// classic-editor content automatically
+func TestAutopAppliedToClassicContent(t *testing.T) {
+	classic := "First paragraph\nwith a break\n\nSecond paragraph\n\n[caption width=\"100\"]<img src=\"a.jpg\" alt=\"A\" /> Caption[/caption]"
+
+	markdown := ConvertToMarkdown(classic)
+	if !strings.Contains(markdown, "First paragraph\\\nwith a break\n\nSecond paragraph") {
+		t.Errorf("Expected a hard line break and separate Markdown paragraphs, got:\n%s", markdown)
+	}
+	if markdown := ConvertToMarkdown("<p>Trailing break<br /></p>"); markdown != "Trailing break" {
+		t.Errorf("Expected no break at the end of a paragraph, got %q", markdown)
+	}
+
+	cleaned := CleanHTML(classic)
+	if !strings.Contains(cleaned, "<p>Second paragraph</p>") {
+		t.Errorf("Expected paragraphs in cleaned HTML, got:\n%s", cleaned)
+	}
+	if strings.Contains(cleaned, "<p><figure") {
+		t.Errorf("Expected caption shortcode to be unwrapped from its paragraph, got:\n%s", cleaned)
+	}
+
+	// Content that already has paragraphs is left alone
+	if got := CleanHTML("<p>One\ntwo</p>"); strings.Contains(got, "<br") {
+		t.Errorf("Expected no autop on content with paragraphs, got %q", got)
+	}
+}
@@ -0,0 +1,311 @@
+package wpimport
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// BlockRenderer renders a Gutenberg block to Markdown. Renderers can use
+// the converter to render inner blocks or convert HTML fragments.
+type BlockRenderer func(c *Converter, block Block) string
+
+// BlockRendererRegistry maps block names to renderers. Names can be exact
+// ("core/image") or a namespace wildcard ("acf/*").
+type BlockRendererRegistry struct {
+	mu        sync.RWMutex
+	renderers map[string]BlockRenderer
+}
+
+// DefaultBlockRenderers is the registry used by ConvertToMarkdown
+var DefaultBlockRenderers = NewBlockRendererRegistry()
+
+// NewBlockRendererRegistry creates a registry with the built-in core block
+// renderers registered
+func NewBlockRendererRegistry() *BlockRendererRegistry {
+	r := &BlockRendererRegistry{renderers: make(map[string]BlockRenderer)}
+	r.Register("core/image", renderImageBlock)
+	r.Register("core/embed", renderEmbedBlock)
+	r.Register("core-embed/*", renderEmbedBlock)
+	r.Register("core/columns", renderInnerBlocks)
+	r.Register("core/column", renderInnerBlocks)
+	r.Register("core/group", renderInnerBlocks)
+	r.Register("core/buttons", renderInnerBlocks)
+	r.Register("core/code", renderCodeBlock)
+	r.Register("core/table", renderTableBlock)
+	r.Register("core/list", renderListBlock)
+	r.Register("core/gallery", renderGalleryBlock)
+	r.Register("core/button", renderButtonBlock)
+	r.Register("core/quote", renderQuoteBlock)
+	r.Register("core/separator", renderSeparatorBlock)
+	return r
+}
+
+// Register adds or replaces the renderer for a block name or namespace
+// wildcard such as "jetpack/*"
+func (r *BlockRendererRegistry) Register(name string, renderer BlockRenderer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renderers[name] = renderer
+}
+
+// Unregister removes the renderer for a block name or wildcard
+func (r *BlockRendererRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.renderers, name)
+}
+
+// Lookup returns the renderer for a block name, trying an exact match before
+// the namespace wildcard. It returns nil if no renderer is registered.
+func (r *BlockRendererRegistry) Lookup(name string) BlockRenderer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if renderer, ok := r.renderers[name]; ok {
+		return renderer
+	}
+	if idx := strings.Index(name, "/"); idx != -1 {
+		if renderer, ok := r.renderers[name[:idx]+"/*"]; ok {
+			return renderer
+		}
+	}
+	return nil
+}
+
+// RegisterBlockRenderer registers a renderer in the default registry
+func RegisterBlockRenderer(name string, renderer BlockRenderer) {
+	DefaultBlockRenderers.Register(name, renderer)
+}
+
+// renderBlockHTML is the fallback renderer. It converts the block's own
+// HTML and splices in the rendered inner blocks where they appeared.
+func renderBlockHTML(c *Converter, block Block) string {
+	if len(block.InnerBlocks) == 0 {
+		return c.HTMLToMarkdown(block.InnerHTML)
+	}
+
+	var sb strings.Builder
+	verbatim := newVerbatimStore()
+	for i, chunk := range block.InnerContent {
+		sb.WriteString(chunk)
+		if i < len(block.InnerBlocks) {
+			rendered := strings.TrimSpace(c.RenderBlock(block.InnerBlocks[i]))
+			sb.WriteString("<div>" + verbatim.protect("\n\n"+rendered+"\n\n") + "</div>")
+		}
+	}
+
+	content := verbatim.restore(c.HTMLToMarkdown(sb.String()))
+	return strings.TrimSpace(regexp.MustCompile(`\n{3,}`).ReplaceAllString(content, "\n\n"))
+}
+
+// renderInnerBlocks flattens layout blocks such as columns and groups
+func renderInnerBlocks(c *Converter, block Block) string {
+	if len(block.InnerBlocks) == 0 {
+		return c.HTMLToMarkdown(block.InnerHTML)
+	}
+	return c.RenderBlocks(block.InnerBlocks)
+}
+
+// renderImageBlock renders core/image in the converter's figure style
+func renderImageBlock(c *Converter, block Block) string {
+	if findHTMLTag(block.InnerHTML, "img") == "" {
+		return ""
+	}
+	fig := parseImage(block.InnerHTML)
+	if m := figureCaption.FindStringSubmatch(block.InnerHTML); m != nil {
+		fig.Caption = strings.TrimSpace(m[1])
+	}
+
+	// Linked images keep their link
+	if href := block.StringAttr("href"); href != "" {
+		fig.Link = href
+	}
+	return c.renderFigure(fig, FormatMarkdown)
+}
+
+// renderEmbedBlock renders core/embed (and legacy core-embed/*) through the
+// converter's embed providers, leaving unrecognised URLs bare
+func renderEmbedBlock(c *Converter, block Block) string {
+	url := block.StringAttr("url")
+	if url == "" {
+		// Older embeds only carry the URL inside the wrapper div
+		inner := regexp.MustCompile(`<[^>]*>`).ReplaceAllString(block.InnerHTML, "")
+		url = strings.TrimSpace(html.UnescapeString(inner))
+		if idx := strings.IndexAny(url, " \n"); idx != -1 {
+			url = url[:idx]
+		}
+	}
+	if url == "" {
+		return ""
+	}
+	embed := c.RenderEmbed(url, FormatMarkdown)
+	if caption := extractFigcaption(c, block.InnerHTML); caption != "" {
+		return embed + "\n\n*" + caption + "*"
+	}
+	return embed
+}
+
+// renderCodeBlock renders core/code as a fenced code block with its language
+func renderCodeBlock(c *Converter, block Block) string {
+	language := block.StringAttr("language")
+	code := block.InnerHTML
+	if m := regexp.MustCompile(`(?s)<code([^>]*)>(.*?)</code>`).FindStringSubmatch(code); len(m) > 2 {
+		if language == "" {
+			if lm := regexp.MustCompile(`language-([A-Za-z0-9_+-]+)`).FindStringSubmatch(m[1]); len(lm) > 1 {
+				language = lm[1]
+			}
+		}
+		code = m[2]
+	} else {
+		code = regexp.MustCompile(`(?s)</?pre[^>]*>`).ReplaceAllString(code, "")
+	}
+	code = regexp.MustCompile(`<br\s*/?>`).ReplaceAllString(code, "\n")
+	code = html.UnescapeString(strings.Trim(code, "\n"))
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// renderTableBlock renders core/table with its caption below the table
+func renderTableBlock(c *Converter, block Block) string {
+	caption := extractFigcaption(c, block.InnerHTML)
+	table := regexp.MustCompile(`(?s)<figcaption[^>]*>.*?</figcaption>`).ReplaceAllString(block.InnerHTML, "")
+	content := c.HTMLToMarkdown(table)
+	if caption != "" {
+		content += "\n\n*" + caption + "*"
+	}
+	return content
+}
+
+// renderListBlock renders core/list. Since WordPress 6.1 each item is a
+// core/list-item block, so the list is put back together before conversion.
+func renderListBlock(c *Converter, block Block) string {
+	return c.HTMLToMarkdown(blockHTML(block))
+}
+
+// Helper function to rebuild a block's HTML with its inner blocks in place
+func blockHTML(block Block) string {
+	var sb strings.Builder
+	for i, chunk := range block.InnerContent {
+		sb.WriteString(chunk)
+		if i < len(block.InnerBlocks) {
+			sb.WriteString(blockHTML(block.InnerBlocks[i]))
+		}
+	}
+	return sb.String()
+}
+
+// renderGalleryBlock renders core/gallery as a list of images
+func renderGalleryBlock(c *Converter, block Block) string {
+	var items []string
+	if len(block.InnerBlocks) > 0 {
+		// WordPress 5.9+ galleries nest core/image blocks
+		for _, inner := range block.InnerBlocks {
+			if rendered := strings.TrimSpace(c.RenderBlock(inner)); rendered != "" {
+				items = append(items, "- "+strings.ReplaceAll(rendered, "\n", "\n  "))
+			}
+		}
+	} else {
+		// Older galleries keep the images in a <ul> of figures
+		figures := regexp.MustCompile(`(?s)<figure[^>]*>.*?</figure>`).FindAllString(block.InnerHTML, -1)
+		for _, fragment := range figures {
+			fig, ok := parseFigure(fragment)
+			if !ok {
+				continue
+			}
+			items = append(items, "- "+strings.ReplaceAll(c.renderFigure(fig, FormatMarkdown), "\n", "\n  "))
+		}
+	}
+
+	content := strings.Join(items, "\n")
+	if m := regexp.MustCompile(`(?s)<figcaption[^>]*blocks-gallery-caption[^>]*>(.*?)</figcaption>`).FindStringSubmatch(closingContent(block)); len(m) > 1 {
+		if caption := strings.TrimSpace(c.HTMLToMarkdown(m[1])); caption != "" {
+			content += "\n\n*" + caption + "*"
+		}
+	}
+	return content
+}
+
+// renderButtonBlock renders core/button as a link
+func renderButtonBlock(c *Converter, block Block) string {
+	m := regexp.MustCompile(`(?s)<a([^>]*)>(.*?)</a>`).FindStringSubmatch(block.InnerHTML)
+	if len(m) < 3 {
+		return c.HTMLToMarkdown(block.InnerHTML)
+	}
+	text := regexp.MustCompile(`<[^>]*>`).ReplaceAllString(m[2], "")
+	text = strings.TrimSpace(html.UnescapeString(text))
+	return fmt.Sprintf("[%s](%s)", text, getHTMLAttribute("<a"+m[1]+">", "href"))
+}
+
+// renderQuoteBlock renders core/quote, including quotes whose paragraphs
+// are inner blocks, with every line prefixed by "> "
+func renderQuoteBlock(c *Converter, block Block) string {
+	var body string
+	if len(block.InnerBlocks) > 0 {
+		body = c.RenderBlocks(block.InnerBlocks)
+	} else {
+		inner := regexp.MustCompile(`(?s)<cite[^>]*>.*?</cite>`).ReplaceAllString(block.InnerHTML, "")
+		inner = regexp.MustCompile(`(?s)</?blockquote[^>]*>`).ReplaceAllString(inner, "")
+		body = c.HTMLToMarkdown(inner)
+	}
+
+	var lines []string
+	if body = strings.TrimSpace(body); body != "" {
+		for _, line := range strings.Split(body, "\n") {
+			lines = append(lines, strings.TrimRight("> "+line, " "))
+		}
+	}
+	if m := regexp.MustCompile(`(?s)<cite[^>]*>(.*?)</cite>`).FindStringSubmatch(closingContent(block)); len(m) > 1 {
+		if cite := strings.TrimSpace(c.HTMLToMarkdown(m[1])); cite != "" {
+			if len(lines) > 0 {
+				lines = append(lines, ">")
+			}
+			lines = append(lines, "> — "+cite)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderSeparatorBlock renders core/separator as a horizontal rule
+func renderSeparatorBlock(c *Converter, block Block) string {
+	return "---"
+}
+
+// Helper function to get the HTML after a block's inner blocks, where
+// captions and citations are. Blocks built by hand may have none.
+func closingContent(block Block) string {
+	if len(block.InnerContent) == 0 {
+		return ""
+	}
+	return block.InnerContent[len(block.InnerContent)-1]
+}
+
+// Helper function to extract and convert a figure caption
+func extractFigcaption(c *Converter, content string) string {
+	m := regexp.MustCompile(`(?s)<figcaption[^>]*>(.*?)</figcaption>`).FindStringSubmatch(content)
+	if len(m) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(c.HTMLToMarkdown(m[1]))
+}
+
+// Helper function to find the first opening tag with the given name
+func findHTMLTag(content, tag string) string {
+	return regexp.MustCompile(`(?i)<` + tag + `\b[^>]*>`).FindString(content)
+}
+
+// Helper function to read an attribute value from an opening tag,
+// regardless of attribute order or quote style
+func getHTMLAttribute(tag, name string) string {
+	re := regexp.MustCompile(`(?i)\s` + regexp.QuoteMeta(name) + `\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	m := re.FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return html.UnescapeString(m[1] + m[2] + m[3])
+}
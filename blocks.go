@@ -0,0 +1,218 @@
+package wpimport
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Block represents a parsed Gutenberg block
+type Block struct {
+	// Name is the fully qualified block name, e.g. "core/paragraph".
+	// Freeform HTML between blocks has an empty name.
+	Name string
+
+	// Attrs holds the JSON attributes from the block comment delimiter
+	Attrs map[string]interface{}
+
+	// InnerBlocks holds nested blocks (columns, groups, galleries, ...)
+	InnerBlocks []Block
+
+	// InnerHTML is the block's own HTML with the inner blocks left out
+	InnerHTML string
+
+	// InnerContent holds the HTML chunks around the inner blocks. Inner
+	// block i sits between InnerContent[i] and InnerContent[i+1], so there
+	// is always one more chunk than there are inner blocks.
+	InnerContent []string
+}
+
+// StringAttr returns a block attribute as a string, or "" if it is missing
+// or not a string
+func (b *Block) StringAttr(key string) string {
+	if v, ok := b.Attrs[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// IntAttr returns a numeric block attribute as an int, or 0 if it is missing
+func (b *Block) IntAttr(key string) int {
+	if v, ok := b.Attrs[key].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
+
+// blockDelimiter is a single <!-- wp:name {attrs} /--> style comment
+type blockDelimiter struct {
+	name   string
+	attrs  map[string]interface{}
+	closer bool
+	void   bool
+	start  int
+	end    int
+}
+
+// ParseBlocks parses Gutenberg block markup into a block tree. HTML outside
+// of any block is returned as freeform blocks with an empty Name.
+func ParseBlocks(content string) []Block {
+	var output []Block
+	var stack []*Block
+	freeform := ""
+
+	// addBlock attaches a finished block to its parent or the output
+	addBlock := func(block Block) {
+		if len(stack) == 0 {
+			if strings.TrimSpace(freeform) != "" {
+				output = append(output, newFreeformBlock(freeform))
+			}
+			freeform = ""
+			output = append(output, block)
+			return
+		}
+		parent := stack[len(stack)-1]
+		parent.InnerBlocks = append(parent.InnerBlocks, block)
+		parent.InnerContent = append(parent.InnerContent, "")
+	}
+
+	// addHTML appends HTML to the open block or the pending freeform text
+	addHTML := func(text string) {
+		if len(stack) == 0 {
+			freeform += text
+			return
+		}
+		parent := stack[len(stack)-1]
+		parent.InnerHTML += text
+		parent.InnerContent[len(parent.InnerContent)-1] += text
+	}
+
+	pos := 0
+	for pos < len(content) {
+		delim, ok := nextBlockDelimiter(content, pos)
+		if !ok {
+			addHTML(content[pos:])
+			break
+		}
+		addHTML(content[pos:delim.start])
+		pos = delim.end
+
+		switch {
+		case delim.closer:
+			if len(stack) == 0 {
+				// Stray closer, nothing to close
+				continue
+			}
+			block := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			addBlock(*block)
+		case delim.void:
+			addBlock(Block{Name: delim.name, Attrs: delim.attrs, InnerContent: []string{""}})
+		default:
+			stack = append(stack, &Block{Name: delim.name, Attrs: delim.attrs, InnerContent: []string{""}})
+		}
+	}
+
+	// Close any blocks left open by malformed content
+	for len(stack) > 0 {
+		block := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		addBlock(*block)
+	}
+
+	if strings.TrimSpace(freeform) != "" {
+		output = append(output, newFreeformBlock(freeform))
+	}
+
+	return output
+}
+
+// Helper function to create a freeform (classic) block from raw HTML
+func newFreeformBlock(html string) Block {
+	return Block{InnerHTML: html, InnerContent: []string{html}}
+}
+
+// Helper function to find the next block delimiter comment at or after pos
+func nextBlockDelimiter(content string, pos int) (blockDelimiter, bool) {
+	for {
+		idx := strings.Index(content[pos:], "<!--")
+		if idx == -1 {
+			return blockDelimiter{}, false
+		}
+		start := pos + idx
+		if delim, ok := parseBlockDelimiter(content, start); ok {
+			return delim, true
+		}
+		pos = start + 4
+	}
+}
+
+// Helper function to parse a block delimiter comment starting at start
+func parseBlockDelimiter(content string, start int) (blockDelimiter, bool) {
+	endIdx := strings.Index(content[start:], "-->")
+	if endIdx == -1 {
+		return blockDelimiter{}, false
+	}
+	inner := strings.TrimSpace(content[start+4 : start+endIdx])
+	delim := blockDelimiter{start: start, end: start + endIdx + 3}
+
+	if strings.HasPrefix(inner, "/wp:") {
+		delim.closer = true
+		inner = inner[4:]
+	} else if strings.HasPrefix(inner, "wp:") {
+		inner = inner[3:]
+	} else {
+		return blockDelimiter{}, false
+	}
+
+	if strings.HasSuffix(inner, "/") {
+		delim.void = true
+		inner = strings.TrimSpace(strings.TrimSuffix(inner, "/"))
+	}
+
+	name := inner
+	attrs := ""
+	if idx := strings.IndexAny(inner, " \t\n{"); idx != -1 {
+		name = inner[:idx]
+		attrs = strings.TrimSpace(inner[idx:])
+	}
+	if !isValidBlockName(name) {
+		return blockDelimiter{}, false
+	}
+	if !strings.Contains(name, "/") {
+		name = "core/" + name
+	}
+	delim.name = name
+
+	if strings.HasPrefix(attrs, "{") {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(attrs), &parsed); err == nil {
+			delim.attrs = parsed
+		}
+	}
+
+	return delim, true
+}
+
+// Helper function to validate a block name such as "paragraph" or "acf/hero"
+func isValidBlockName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '/' && i > 0:
+		case (r >= '0' && r <= '9') || r == '-' || r == '_':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return strings.Count(name, "/") <= 1 && !strings.HasSuffix(name, "/")
+}
+
+// Helper function to check for block delimiter comments
+func hasBlockMarkup(content string) bool {
+	return strings.Contains(content, "<!-- wp:")
+}
@@ -0,0 +1,150 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseBlocks tests parsing of Gutenberg block markup into a tree
+func TestParseBlocks(t *testing.T) {
+	content := `<!-- wp:paragraph -->
+<p>Intro</p>
+<!-- /wp:paragraph -->
+
+<!-- wp:columns -->
+<div class="wp-block-columns"><!-- wp:column -->
+<div class="wp-block-column"><!-- wp:heading {"level":3} -->
+<h3>Left</h3>
+<!-- /wp:heading --></div>
+<!-- /wp:column --></div>
+<!-- /wp:columns -->
+
+<!-- wp:block {"ref":123} /-->
+<p>Classic tail</p>`
+
+	blocks := ParseBlocks(content)
+	if len(blocks) != 4 {
+		t.Fatalf("Expected 4 top-level blocks, got %d", len(blocks))
+	}
+
+	if blocks[0].Name != "core/paragraph" {
+		t.Errorf("Expected core/paragraph, got '%s'", blocks[0].Name)
+	}
+
+	columns := blocks[1]
+	if columns.Name != "core/columns" || len(columns.InnerBlocks) != 1 {
+		t.Fatalf("Expected core/columns with 1 inner block, got %s with %d", columns.Name, len(columns.InnerBlocks))
+	}
+	if len(columns.InnerContent) != len(columns.InnerBlocks)+1 {
+		t.Errorf("Expected %d inner content chunks, got %d", len(columns.InnerBlocks)+1, len(columns.InnerContent))
+	}
+	heading := columns.InnerBlocks[0].InnerBlocks[0]
+	if heading.IntAttr("level") != 3 {
+		t.Errorf("Expected heading level 3, got %d", heading.IntAttr("level"))
+	}
+
+	if blocks[2].Name != "core/block" || blocks[2].IntAttr("ref") != 123 {
+		t.Errorf("Expected void core/block with ref 123, got %s %v", blocks[2].Name, blocks[2].Attrs)
+	}
+
+	if blocks[3].Name != "" || !strings.Contains(blocks[3].InnerHTML, "Classic tail") {
+		t.Errorf("Expected freeform block with trailing HTML, got %+v", blocks[3])
+	}
+}
+
+// TestBlockAwareMarkdown tests the built-in block renderers
+func TestBlockAwareMarkdown(t *testing.T) {
+	content := `<!-- wp:image {"id":5} -->
+<figure class="wp-block-image"><img src="https://example.com/a.jpg" class="wp-image-5" alt="A cat"/><figcaption>A <em>sleepy</em> cat</figcaption></figure>
+<!-- /wp:image -->
+
+<!-- wp:embed {"url":"https://www.youtube.com/watch?v=abc123","providerNameSlug":"youtube"} -->
+<figure class="wp-block-embed"><div class="wp-block-embed__wrapper">
+https://www.youtube.com/watch?v=abc123
+</div></figure>
+<!-- /wp:embed -->
+
+<!-- wp:code -->
+<pre class="wp-block-code"><code class="language-go">fmt.Println("a &lt; b")</code></pre>
+<!-- /wp:code -->
+
+<!-- wp:table -->
+<figure class="wp-block-table"><table><tr><th>H</th></tr><tr><td>C</td></tr></table><figcaption>Table caption</figcaption></figure>
+<!-- /wp:table -->
+
+<!-- wp:gallery {"linkTo":"none"} -->
+<figure class="wp-block-gallery"><!-- wp:image -->
+<figure class="wp-block-image"><img src="https://example.com/1.jpg" alt="One"/></figure>
+<!-- /wp:image -->
+<!-- wp:image -->
+<figure class="wp-block-image"><img src="https://example.com/2.jpg" alt="Two"/></figure>
+<!-- /wp:image --></figure>
+<!-- /wp:gallery -->
+
+<!-- wp:buttons -->
+<div class="wp-block-buttons"><!-- wp:button -->
+<div class="wp-block-button"><a class="wp-block-button__link" href="https://example.com/buy">Buy now</a></div>
+<!-- /wp:button --></div>
+<!-- /wp:buttons -->`
+
+	markdown := ConvertToMarkdown(content)
+
+	expected := []string{
+		"![A cat](https://example.com/a.jpg)\n*A *sleepy* cat*",
+		"https://www.youtube.com/watch?v=abc123",
+		"```go\nfmt.Println(\"a < b\")\n```",
+		"| H   |\n| --- |\n| C   |",
+		"*Table caption*",
+		"- ![One](https://example.com/1.jpg)\n- ![Two](https://example.com/2.jpg)",
+		"[Buy now](https://example.com/buy)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, markdown)
+		}
+	}
+}
+
+// TestRegisterBlockRenderer tests custom renderers for third-party blocks
+func TestRegisterBlockRenderer(t *testing.T) {
+	registry := NewBlockRendererRegistry()
+	registry.Register("acf/*", func(c *Converter, block Block) string {
+		return "ACF:" + block.StringAttr("name")
+	})
+
+	c := NewConverter()
+	c.Blocks = registry
+
+	markdown := c.ToMarkdown(`<!-- wp:acf/testimonial {"name":"acf/testimonial"} /-->`)
+	if markdown != "ACF:acf/testimonial" {
+		t.Errorf("Expected wildcard renderer output, got %q", markdown)
+	}
+
+	// The default registry is untouched
+	if DefaultBlockRenderers.Lookup("acf/testimonial") != nil {
+		t.Error("Expected default registry to have no acf renderer")
+	}
+}
+
+// TestEmptyBlocks tests self-closing blocks and blocks built without content
+func TestEmptyBlocks(t *testing.T) {
+	c := NewConverter()
+	if markdown := c.ToMarkdown(`<!-- wp:quote /-->`); markdown != "" {
+		t.Errorf("Expected nothing for an empty quote, got %q", markdown)
+	}
+	for _, name := range []string{"core/quote", "core/gallery"} {
+		if markdown := c.RenderBlocks([]Block{{Name: name}}); markdown != "" {
+			t.Errorf("Expected nothing for %s without content, got %q", name, markdown)
+		}
+	}
+}
+
+// TestBlockHTMLInnerBlocks tests splicing inner blocks into the HTML of a
+// block without a renderer
+func TestBlockHTMLInnerBlocks(t *testing.T) {
+	content := `<!-- wp:acme/box --><div class="box"><p>WPBLOCK0 is literal text</p><!-- wp:paragraph --><p>Inner</p><!-- /wp:paragraph --><p>After</p></div><!-- /wp:acme/box -->`
+	markdown := NewConverter().ToMarkdown(content)
+	if markdown != "WPBLOCK0 is literal text\n\nInner\n\nAfter" {
+		t.Errorf("Unexpected Markdown: %q", markdown)
+	}
+}
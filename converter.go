@@ -0,0 +1,253 @@
+package wpimport
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Converter converts WordPress content with configurable behaviour.
+// Create one with NewConverter; the package-level Convert functions use a
+// converter with the default settings.
+type Converter struct {
+	// Site is the export the content belongs to. When set, references to
+	// other items in the export (such as reusable blocks) are resolved.
+	Site *WordPressSite
+
+	// Item is the item whose content is being converted, or nil. Shortcodes
+	// such as [gallery] without ids use the files attached to it. Use
+	// ForItem to set it on a copy of a shared converter.
+	Item *Item
+
+	// Blocks holds the renderers used for Gutenberg blocks in Markdown output
+	Blocks *BlockRendererRegistry
+
+	// Shortcodes holds the shortcode handlers; nil leaves shortcodes as-is
+	Shortcodes *ShortcodeRegistry
+
+	// Embeds holds the providers used to turn bare URLs into embeds; nil
+	// leaves URLs as-is
+	Embeds *EmbedRegistry
+
+	// EmbedFormat is how recognised embeds are written out
+	EmbedFormat EmbedFormat
+
+	// FigureFormat is how images with captions are written in Markdown
+	FigureFormat FigureFormat
+
+	// Links, when set, rewrites links to other items in the export and
+	// records the ones it cannot resolve
+	Links *LinkResolver
+
+	// Media, when set, points upload URLs at the files' new location and
+	// records them in its manifest. Upload URLs are then left alone by
+	// Links.
+	Media *MediaMapper
+
+	// Sanitize, when set, limits HTML output to what the policy allows
+	Sanitize *SanitizePolicy
+
+	// PlainText controls wrapping, links, headings, blockquotes and images
+	// in plain text output
+	PlainText PlainTextOptions
+
+	// UnresolvedBlock, when set, is called for each reusable block
+	// reference in the content that could not be expanded: the block is
+	// missing from the export, or cycle is true when it refers back to a
+	// block that is already being expanded. Requires Site.
+	UnresolvedBlock func(ref int, cycle bool)
+
+	// EmbedThumbnailPlaceholder is the image used for EmbedLink output when
+	// the provider's thumbnail cannot be derived from the URL. When empty a
+	// plain link is written instead.
+	EmbedThumbnailPlaceholder string
+}
+
+// NewConverter creates a converter using the default block renderers,
+// shortcode handlers and embed providers
+func NewConverter() *Converter {
+	return &Converter{
+		Blocks:     DefaultBlockRenderers,
+		Shortcodes: DefaultShortcodes,
+		Embeds:     DefaultEmbeds,
+	}
+}
+
+// NewSiteConverter creates a converter that resolves references against
+// the given site
+func NewSiteConverter(site *WordPressSite) *Converter {
+	c := NewConverter()
+	c.Site = site
+	return c
+}
+
+// ForItem returns a copy of the converter for converting the content of
+// the given item
+func (c *Converter) ForItem(item *Item) *Converter {
+	converter := *c
+	converter.Item = item
+	return &converter
+}
+
+// ToHTML returns cleaned HTML for WordPress content, sanitized when the
+// converter has a Sanitize policy
+func (c *Converter) ToHTML(content string) string {
+	verbatim := newVerbatimStore()
+	content = verbatim.restore(cleanHTML(c.prepare(content, FormatHTML, verbatim)))
+	if c.Sanitize != nil {
+		content = c.Sanitize.Sanitize(content)
+	}
+	return content
+}
+
+// ToPlainText converts WordPress content to plain text, formatted
+// according to the converter's PlainText options
+func (c *Converter) ToPlainText(content string) string {
+	verbatim := newVerbatimStore()
+	content = c.prepare(content, FormatPlainText, verbatim)
+	var urls []string
+	if c.PlainText.LinkReferences {
+		content, urls = plainTextLinks(content)
+	}
+	text := verbatim.restore(c.plainText(content, verbatim))
+	if len(urls) > 0 {
+		text += "\n\n" + plainTextReferences(urls)
+	}
+	return text
+}
+
+// ToMarkdown converts WordPress content to Markdown. Content with Gutenberg
+// block markup is rendered block by block; classic content is converted
+// from its HTML.
+func (c *Converter) ToMarkdown(content string) string {
+	verbatim := newVerbatimStore()
+	content = c.prepare(content, FormatMarkdown, verbatim)
+	if !hasBlockMarkup(content) {
+		return verbatim.restore(c.HTMLToMarkdown(content))
+	}
+	return verbatim.restore(c.RenderBlocks(ParseBlocks(content)))
+}
+
+// HTMLToMarkdown converts an HTML fragment to Markdown without any block
+// handling. Block renderers use it for the HTML inside a block.
+func (c *Converter) HTMLToMarkdown(fragment string) string {
+	verbatim := newVerbatimStore()
+	fragment = c.replaceLists(fragment, FormatMarkdown, verbatim)
+	fragment = c.replaceTables(fragment, FormatMarkdown, verbatim)
+	fragment = c.replaceFigures(fragment, FormatMarkdown, verbatim)
+	return verbatim.restore(convertHTMLToMarkdown(fragment))
+}
+
+// RenderBlocks renders a list of blocks to Markdown, separating them with
+// blank lines
+func (c *Converter) RenderBlocks(blocks []Block) string {
+	var parts []string
+	for _, block := range blocks {
+		if rendered := strings.TrimSpace(c.RenderBlock(block)); rendered != "" {
+			parts = append(parts, rendered)
+		}
+	}
+	content := strings.Join(parts, "\n\n")
+	return regexp.MustCompile(`\n{3,}`).ReplaceAllString(content, "\n\n")
+}
+
+// RenderBlock renders a single block to Markdown using the registered
+// renderer for its name, falling back to converting the block's HTML
+func (c *Converter) RenderBlock(block Block) string {
+	if block.Name == "" {
+		return c.HTMLToMarkdown(block.InnerHTML)
+	}
+	if c.Blocks != nil {
+		if renderer := c.Blocks.Lookup(block.Name); renderer != nil {
+			return renderer(c, block)
+		}
+	}
+	return renderBlockHTML(c, block)
+}
+
+// Helper function to resolve references to other items, add paragraphs to
+// classic-editor content, process shortcodes, rewrite internal links and
+// media URLs and detect embeds before conversion. Output that must not be
+// converted is kept in verbatim.
+func (c *Converter) prepare(content string, format OutputFormat, verbatim *verbatimStore) string {
+	if c.Site != nil {
+		report := c.UnresolvedBlock
+		if report == nil {
+			report = func(int, bool) {}
+		}
+		content = c.Site.expandReusableBlocks(content, map[int]bool{}, report)
+	}
+	if needsAutop(content) {
+		content = shortcodeUnautop(Autop(content))
+	}
+	if c.Shortcodes != nil {
+		content = c.Shortcodes.do(content, &ShortcodeContext{Format: format, Site: c.Site, Item: c.Item, Registry: c.Shortcodes})
+	}
+	if c.Links != nil {
+		var skip func(string) bool
+		if c.Media != nil {
+			skip = c.Media.IsUpload
+		}
+		content, _ = c.Links.rewriteHTML(content, skip)
+	}
+	if c.Media != nil {
+		content, _ = c.Media.RewriteHTML(content)
+	}
+	return c.replaceEmbeds(content, format, verbatim)
+}
+
+// verbatimStore holds fragments that are swapped out for placeholders while
+// the surrounding content is converted, such as embed HTML in Markdown.
+// Each store has its own ID so nested conversions don't mix placeholders.
+type verbatimStore struct {
+	id        int64
+	fragments []string
+}
+
+var (
+	verbatimStoreID     atomic.Int64
+	verbatimPlaceholder = regexp.MustCompile("\uE000(\\d+):(\\d+)\uE001")
+)
+
+// Helper function to create an empty verbatim store
+func newVerbatimStore() *verbatimStore {
+	return &verbatimStore{id: verbatimStoreID.Add(1)}
+}
+
+// Helper function to swap a fragment out for a placeholder
+func (v *verbatimStore) protect(fragment string) string {
+	if v == nil {
+		return fragment
+	}
+	v.fragments = append(v.fragments, fragment)
+	return fmt.Sprintf("\uE000%d:%d\uE001", v.id, len(v.fragments)-1)
+}
+
+// Helper function to put the protected fragments back. Fragments may hold
+// placeholders for fragments protected before them.
+func (v *verbatimStore) restore(content string) string {
+	if v == nil || len(v.fragments) == 0 {
+		return content
+	}
+	return verbatimPlaceholder.ReplaceAllStringFunc(content, func(match string) string {
+		m := verbatimPlaceholder.FindStringSubmatch(match)
+		id, _ := strconv.ParseInt(m[1], 10, 64)
+		i, err := strconv.Atoi(m[2])
+		if id != v.id || err != nil || i >= len(v.fragments) {
+			return match
+		}
+		return v.restore(v.fragments[i])
+	})
+}
+
+// Helper function to measure the display width of content as restored,
+// taking the widest line
+func (v *verbatimStore) measure(content string) int {
+	width := 0
+	for _, line := range strings.Split(v.restore(content), "\n") {
+		width = max(width, displayWidth(line))
+	}
+	return width
+}
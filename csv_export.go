@@ -0,0 +1,377 @@
+package wpimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVTable names a table of the CSV export
+type CSVTable string
+
+const (
+	// CSVItems has a row per post, page, attachment or other item
+	CSVItems CSVTable = "items"
+	// CSVComments has a row per comment, linked by item_id
+	CSVComments CSVTable = "comments"
+	// CSVAuthors has a row per author, linked from items by author_login
+	CSVAuthors CSVTable = "authors"
+	// CSVTerms has a row per category, tag or custom taxonomy term
+	CSVTerms CSVTable = "terms"
+	// CSVItemTerms joins items to terms by item_id and taxonomy and slug
+	CSVItemTerms CSVTable = "item_terms"
+	// CSVPostMeta has a row per post meta entry, linked by item_id
+	CSVPostMeta CSVTable = "postmeta"
+)
+
+// CSVTables lists the tables of the CSV export in the order they are
+// written
+var CSVTables = []CSVTable{CSVItems, CSVComments, CSVAuthors, CSVTerms, CSVItemTerms, CSVPostMeta}
+
+// CSVExporter writes a site as CSV tables linked by IDs, for auditing
+// content in a spreadsheet. The output follows RFC 4180: fields are quoted
+// when needed and lines end in CRLF.
+type CSVExporter struct {
+	// Tables lists the tables Export writes; all of them when empty
+	Tables []CSVTable
+
+	// Columns selects and orders the columns of a table; tables that are
+	// not in the map get every column. CSVColumns lists the columns.
+	Columns map[CSVTable][]string
+
+	// RawContent exports item and comment content as exported instead of
+	// as plain text
+	RawContent bool
+
+	// MaxContentLength truncates content and excerpts to this many
+	// characters, ending them in "…". 0 keeps them whole.
+	MaxContentLength int
+
+	// BOM starts each file with a UTF-8 byte order mark, so Excel reads
+	// it as UTF-8
+	BOM bool
+
+	// EscapeFormulas prefixes fields starting with =, +, - or @ with a
+	// quote, so spreadsheets do not run them as formulas
+	EscapeFormulas bool
+
+	// Types limits the items, and the comments, terms and meta linked to
+	// them, to these post types; all items when empty
+	Types []string
+
+	// Converter converts content to plain text. When nil, a converter with
+	// the default settings for the site is used.
+	Converter *Converter
+}
+
+// NewCSVExporter creates a CSV exporter writing every table and column
+// with content as plain text
+func NewCSVExporter() *CSVExporter {
+	return &CSVExporter{}
+}
+
+// csvRow is what the columns of a row are taken from; each table fills in
+// its own fields
+type csvRow struct {
+	item    *Item
+	comment *Comment
+	author  *Author
+	term    JSONTerm
+	meta    PostMeta
+	count   int
+}
+
+// csvColumn is a column and how to get its value from a row
+type csvColumn struct {
+	name  string
+	value func(w *csvWriter, row *csvRow) string
+}
+
+// csvColumns holds the columns of each table in their default order
+var csvColumns = map[CSVTable][]csvColumn{
+	CSVItems: {
+		{"id", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.item.PostID) }},
+		{"type", func(w *csvWriter, r *csvRow) string { return r.item.PostType }},
+		{"status", func(w *csvWriter, r *csvRow) string { return r.item.Status }},
+		{"title", func(w *csvWriter, r *csvRow) string { return itemTitle(r.item) }},
+		{"slug", func(w *csvWriter, r *csvRow) string { return r.item.PostName }},
+		{"link", func(w *csvWriter, r *csvRow) string { return r.item.Link }},
+		{"guid", func(w *csvWriter, r *csvRow) string { return r.item.GUID }},
+		{"author_login", func(w *csvWriter, r *csvRow) string { return r.item.Creator }},
+		{"date", func(w *csvWriter, r *csvRow) string { return r.item.PostDate }},
+		{"date_gmt", func(w *csvWriter, r *csvRow) string { return r.item.PostDateGMT }},
+		{"modified", func(w *csvWriter, r *csvRow) string { return r.item.PostModified }},
+		{"parent_id", func(w *csvWriter, r *csvRow) string { return csvID(r.item.PostParent) }},
+		{"menu_order", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.item.MenuOrder) }},
+		{"sticky", func(w *csvWriter, r *csvRow) string { return strconv.FormatBool(r.item.IsSticky != 0) }},
+		{"password_protected", func(w *csvWriter, r *csvRow) string { return strconv.FormatBool(r.item.PostPassword != "") }},
+		{"categories", func(w *csvWriter, r *csvRow) string { return strings.Join(itemTermNames(r.item, "category"), ", ") }},
+		{"tags", func(w *csvWriter, r *csvRow) string { return strings.Join(itemTermNames(r.item, "post_tag"), ", ") }},
+		{"comment_count", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(len(r.item.Comments)) }},
+		{"attachment_url", func(w *csvWriter, r *csvRow) string { return r.item.AttachmentURL }},
+		{"excerpt", func(w *csvWriter, r *csvRow) string { return w.content(r.item, r.item.Excerpt) }},
+		{"content", func(w *csvWriter, r *csvRow) string { return w.content(r.item, r.item.Content) }},
+	},
+	CSVComments: {
+		{"id", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.comment.ID) }},
+		{"item_id", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.item.PostID) }},
+		{"parent_id", func(w *csvWriter, r *csvRow) string { return csvID(r.comment.Parent) }},
+		{"author", func(w *csvWriter, r *csvRow) string { return r.comment.Author }},
+		{"author_email", func(w *csvWriter, r *csvRow) string { return r.comment.AuthorEmail }},
+		{"author_url", func(w *csvWriter, r *csvRow) string { return r.comment.AuthorURL }},
+		{"author_ip", func(w *csvWriter, r *csvRow) string { return r.comment.AuthorIP }},
+		{"user_id", func(w *csvWriter, r *csvRow) string { return csvID(r.comment.UserID) }},
+		{"date", func(w *csvWriter, r *csvRow) string { return r.comment.Date }},
+		{"date_gmt", func(w *csvWriter, r *csvRow) string { return r.comment.DateGMT }},
+		{"status", func(w *csvWriter, r *csvRow) string { return commentStatus(r.comment.Approved) }},
+		{"type", func(w *csvWriter, r *csvRow) string { return r.comment.Type }},
+		{"content", func(w *csvWriter, r *csvRow) string { return w.content(nil, r.comment.Content) }},
+	},
+	CSVAuthors: {
+		{"id", func(w *csvWriter, r *csvRow) string { return csvID(r.author.ID) }},
+		{"login", func(w *csvWriter, r *csvRow) string { return r.author.Login }},
+		{"email", func(w *csvWriter, r *csvRow) string { return r.author.Email }},
+		{"display_name", func(w *csvWriter, r *csvRow) string { return r.author.DisplayName }},
+		{"first_name", func(w *csvWriter, r *csvRow) string { return r.author.FirstName }},
+		{"last_name", func(w *csvWriter, r *csvRow) string { return r.author.LastName }},
+		{"item_count", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.count) }},
+	},
+	CSVTerms: {
+		{"id", func(w *csvWriter, r *csvRow) string { return csvID(r.term.ID) }},
+		{"taxonomy", func(w *csvWriter, r *csvRow) string { return r.term.Taxonomy }},
+		{"slug", func(w *csvWriter, r *csvRow) string { return r.term.Slug }},
+		{"name", func(w *csvWriter, r *csvRow) string { return r.term.Name }},
+		{"parent", func(w *csvWriter, r *csvRow) string { return r.term.Parent }},
+		{"description", func(w *csvWriter, r *csvRow) string { return r.term.Description }},
+		{"item_count", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.count) }},
+	},
+	CSVItemTerms: {
+		{"item_id", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.item.PostID) }},
+		{"term_id", func(w *csvWriter, r *csvRow) string { return csvID(r.term.ID) }},
+		{"taxonomy", func(w *csvWriter, r *csvRow) string { return r.term.Taxonomy }},
+		{"slug", func(w *csvWriter, r *csvRow) string { return r.term.Slug }},
+		{"name", func(w *csvWriter, r *csvRow) string { return r.term.Name }},
+	},
+	CSVPostMeta: {
+		{"item_id", func(w *csvWriter, r *csvRow) string { return strconv.Itoa(r.item.PostID) }},
+		{"meta_key", func(w *csvWriter, r *csvRow) string { return r.meta.Key }},
+		{"meta_value", func(w *csvWriter, r *csvRow) string { return r.meta.Value }},
+	},
+}
+
+// CSVColumns returns the names of a table's columns in their default
+// order
+func CSVColumns(table CSVTable) []string {
+	names := make([]string, 0, len(csvColumns[table]))
+	for _, column := range csvColumns[table] {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+// Export writes each table to <table>.csv under dir
+func (e *CSVExporter) Export(site *WordPressSite, dir string) error {
+	tables := e.Tables
+	if len(tables) == 0 {
+		tables = CSVTables
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		name := filepath.Join(dir, string(table)+".csv")
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := e.WriteTable(f, site, table); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTable writes one table with a header row
+func (e *CSVExporter) WriteTable(w io.Writer, site *WordPressSite, table CSVTable) error {
+	columns, err := e.columns(table)
+	if err != nil {
+		return err
+	}
+	if e.BOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+	}
+	cw := &csvWriter{exporter: e, site: site, columns: columns, out: csv.NewWriter(w)}
+	cw.out.UseCRLF = true
+	cw.header()
+
+	switch table {
+	case CSVItems:
+		e.eachItem(site, func(item *Item) {
+			cw.row(&csvRow{item: item})
+		})
+	case CSVComments:
+		e.eachItem(site, func(item *Item) {
+			for i := range item.Comments {
+				cw.row(&csvRow{item: item, comment: &item.Comments[i]})
+			}
+		})
+	case CSVAuthors:
+		counts := make(map[string]int)
+		e.eachItem(site, func(item *Item) {
+			counts[item.Creator]++
+		})
+		for i := range site.Channel.Authors {
+			author := &site.Channel.Authors[i]
+			cw.row(&csvRow{author: author, count: counts[author.Login]})
+		}
+	case CSVTerms:
+		terms := newSiteTerms(site)
+		counts := make(map[string]int)
+		e.eachItem(site, func(item *Item) {
+			for _, category := range item.Categories {
+				term := terms.add(terms.find(category))
+				counts[term.Taxonomy+"\x00"+term.Slug]++
+			}
+		})
+		for _, term := range terms.list {
+			cw.row(&csvRow{term: term, count: counts[term.Taxonomy+"\x00"+term.Slug]})
+		}
+	case CSVItemTerms:
+		terms := newSiteTerms(site)
+		e.eachItem(site, func(item *Item) {
+			for _, category := range item.Categories {
+				cw.row(&csvRow{item: item, term: terms.find(category)})
+			}
+		})
+	case CSVPostMeta:
+		e.eachItem(site, func(item *Item) {
+			for _, meta := range item.PostMeta {
+				cw.row(&csvRow{item: item, meta: meta})
+			}
+		})
+	}
+
+	cw.out.Flush()
+	return cw.out.Error()
+}
+
+// Helper function to get the selected columns of a table
+func (e *CSVExporter) columns(table CSVTable) ([]csvColumn, error) {
+	all, ok := csvColumns[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown CSV table: %q", table)
+	}
+	names, ok := e.Columns[table]
+	if !ok {
+		return all, nil
+	}
+	columns := make([]csvColumn, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, column := range all {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q in CSV table %s", name, table)
+		}
+	}
+	return columns, nil
+}
+
+// Helper function to call fn for each item of the selected types
+func (e *CSVExporter) eachItem(site *WordPressSite, fn func(item *Item)) {
+	types := make(map[string]bool, len(e.Types))
+	for _, t := range e.Types {
+		types[t] = true
+	}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if len(types) == 0 || types[item.PostType] {
+			fn(item)
+		}
+	}
+}
+
+// csvWriter writes the rows of one table
+type csvWriter struct {
+	exporter  *CSVExporter
+	site      *WordPressSite
+	converter *Converter
+	columns   []csvColumn
+	out       *csv.Writer
+}
+
+// Helper function to write the header row
+func (w *csvWriter) header() {
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		record[i] = column.name
+	}
+	w.out.Write(record)
+}
+
+// Helper function to write a row. Write errors are kept by the csv.Writer
+// and reported after flushing.
+func (w *csvWriter) row(row *csvRow) {
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		value := column.value(w, row)
+		if w.exporter.EscapeFormulas && value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+			value = "'" + value
+		}
+		record[i] = value
+	}
+	w.out.Write(record)
+}
+
+// Helper function to prepare content for a cell: plain text unless raw,
+// and truncated to MaxContentLength. The item is the one the content
+// belongs to, or nil.
+func (w *csvWriter) content(item *Item, content string) string {
+	if content == "" {
+		return ""
+	}
+	if !w.exporter.RawContent {
+		if w.converter == nil {
+			w.converter = w.exporter.Converter
+			if w.converter == nil {
+				w.converter = NewSiteConverter(w.site)
+			}
+		}
+		content = strings.TrimSpace(w.converter.ForItem(item).ToPlainText(content))
+	}
+	return truncateText(content, w.exporter.MaxContentLength)
+}
+
+// Helper function to cut text to at most max characters, ending it in an
+// ellipsis. A max of 0 or less keeps the text whole.
+func truncateText(text string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimRight(string(runes[:max-1]), " \t\r\n") + "…"
+}
+
+// Helper function to write an ID, leaving 0 (none) empty
+func csvID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,126 @@
+package wpimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper function to write a CSV table and read it back
+func readCSVTable(t *testing.T, e *CSVExporter, site *WordPressSite, table CSVTable) [][]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := e.WriteTable(&buf, site, table); err != nil {
+		t.Fatalf("WriteTable(%s) failed: %v", table, err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\uFEFF"))).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV for %s: %v", table, err)
+	}
+	return records
+}
+
+// TestCSVExport tests the tables and how they link
+func TestCSVExport(t *testing.T) {
+	site := jsonTestSite()
+	e := NewCSVExporter()
+
+	items := readCSVTable(t, e, site, CSVItems)
+	if strings.Join(items[0], ",") != strings.Join(CSVColumns(CSVItems), ",") || len(items) != 9 {
+		t.Fatalf("Expected a header and 8 items, got %d rows: %v", len(items), items[0])
+	}
+	post := map[string]string{}
+	for i, name := range items[0] {
+		post[name] = items[1][i]
+	}
+	if post["title"] != `Hello & "World"` || post["categories"] != "News" || post["tags"] != "Go, WordPress" || post["comment_count"] != "5" {
+		t.Errorf("Unexpected item row: %v", post)
+	}
+	if post["content"] != "See the team." || post["excerpt"] != "A short summary." {
+		t.Errorf("Expected plain text content and excerpt, got %q and %q", post["content"], post["excerpt"])
+	}
+
+	comments := readCSVTable(t, e, site, CSVComments)
+	if len(comments) != 6 || comments[2][1] != "10" || comments[2][2] != "1" || comments[3][10] != "pending" {
+		t.Errorf("Unexpected comments: %v", comments)
+	}
+
+	authors := readCSVTable(t, e, site, CSVAuthors)
+	if len(authors) != 2 || authors[1][1] != "jane" || authors[1][6] != "1" {
+		t.Errorf("Unexpected authors: %v", authors)
+	}
+
+	// Terms the items use but the export does not declare are added
+	terms := readCSVTable(t, e, site, CSVTerms)
+	if len(terms) != 5 || terms[1][0] != "3" || terms[1][6] != "1" || terms[2][1] != "genre" || terms[2][6] != "2" || terms[3][2] != "go" {
+		t.Errorf("Unexpected terms: %v", terms)
+	}
+
+	joins := readCSVTable(t, e, site, CSVItemTerms)
+	if len(joins) != 6 || joins[1][0] != "10" || joins[1][1] != "3" || joins[5][0] != "22" || joins[5][1] != "7" {
+		t.Errorf("Unexpected item terms: %v", joins)
+	}
+
+	meta := readCSVTable(t, e, site, CSVPostMeta)
+	if len(meta) != 3 || meta[2][1] != "_sizes" || !strings.HasPrefix(meta[2][2], "a:2:{") {
+		t.Errorf("Unexpected post meta: %v", meta)
+	}
+}
+
+// TestCSVExportOptions tests columns, raw and truncated content, the BOM
+// and formula escaping
+func TestCSVExportOptions(t *testing.T) {
+	site := jsonTestSite()
+	site.Channel.Items[2].Title = "=HYPERLINK(\"x\")"
+	e := &CSVExporter{
+		Columns:          map[CSVTable][]string{CSVItems: {"title", "id", "content"}},
+		RawContent:       true,
+		MaxContentLength: 10,
+		BOM:              true,
+		EscapeFormulas:   true,
+		Types:            []string{"page"},
+	}
+
+	var buf bytes.Buffer
+	if err := e.WriteTable(&buf, site, CSVItems); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	expected := "\uFEFFtitle,id,content\r\n\"'=HYPERLINK(\"\"x\"\")\",20,About us.\r\nTeam,21,The team.\r\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, got)
+	}
+
+	e.Types = []string{"post"}
+	e.EscapeFormulas = false
+	items := readCSVTable(t, e, site, CSVItems)
+	if items[1][2] != `<p>See <a…` {
+		t.Errorf("Expected raw content truncated to 10 characters, got %q", items[1][2])
+	}
+
+	e.Columns = map[CSVTable][]string{CSVItems: {"nope"}}
+	if err := e.WriteTable(&buf, site, CSVItems); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+	if err := e.WriteTable(&buf, site, "widgets"); err == nil {
+		t.Error("Expected an error for an unknown table")
+	}
+}
+
+// TestCSVExportFiles tests writing the tables to a directory
+func TestCSVExportFiles(t *testing.T) {
+	dir := t.TempDir()
+	e := &CSVExporter{Tables: []CSVTable{CSVAuthors, CSVTerms}}
+	if err := e.Export(jsonTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(entries))
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "authors.csv")); err != nil || !strings.HasPrefix(string(data), "id,login,") {
+		t.Errorf("Unexpected authors.csv: %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,275 @@
+package wpimport
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// EmbedFormat controls how detected embeds are written out
+type EmbedFormat int
+
+const (
+	// EmbedLink renders a link, with a thumbnail image when one is known
+	EmbedLink EmbedFormat = iota
+	// EmbedHugoShortcode renders a Hugo shortcode such as {{< youtube id >}}
+	EmbedHugoShortcode
+	// EmbedIframe renders the provider's iframe (or embed) HTML
+	EmbedIframe
+)
+
+// EmbedProvider describes an oEmbed-style provider recognised from its URLs
+// without any network access. Patterns use named groups; "id" is required
+// and other groups (such as "user" or "kind") are available to the
+// templates as {name}. Templates can also use {url} and {url_escaped}.
+type EmbedProvider struct {
+	// Name is a short identifier, e.g. "youtube"
+	Name string
+
+	// Label is the human-readable provider name, e.g. "YouTube"
+	Label string
+
+	// Patterns match the provider's URLs
+	Patterns []*regexp.Regexp
+
+	// Hugo is the Hugo shortcode template
+	Hugo string
+
+	// Iframe is the HTML embed template
+	Iframe string
+
+	// Thumbnail is an optional thumbnail URL template
+	Thumbnail string
+}
+
+// Embed is a URL matched to a provider
+type Embed struct {
+	Provider *EmbedProvider
+	URL      string
+	ID       string
+
+	// Params holds all named groups from the matching pattern
+	Params map[string]string
+}
+
+// EmbedRegistry holds the known embed providers
+type EmbedRegistry struct {
+	mu        sync.RWMutex
+	providers []*EmbedProvider
+}
+
+// DefaultEmbeds is the registry used by converters created with NewConverter
+var DefaultEmbeds = NewEmbedRegistry()
+
+// NewEmbedRegistry creates a registry with the built-in providers: YouTube,
+// Vimeo, Twitter/X, Instagram, SoundCloud and Spotify
+func NewEmbedRegistry() *EmbedRegistry {
+	r := &EmbedRegistry{}
+	r.Register(&EmbedProvider{
+		Name:  "youtube",
+		Label: "YouTube",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https?://(?:www\.|m\.)?youtube\.com/(?:watch\?(?:[^#]*&)?v=|embed/|shorts/|live/|v/)(?P<id>[\w-]{11})`),
+			regexp.MustCompile(`^https?://youtu\.be/(?P<id>[\w-]{11})`),
+		},
+		Hugo:      `{{< youtube {id} >}}`,
+		Iframe:    `<iframe width="560" height="315" src="https://www.youtube-nocookie.com/embed/{id}" title="YouTube video" frameborder="0" allowfullscreen></iframe>`,
+		Thumbnail: `https://img.youtube.com/vi/{id}/hqdefault.jpg`,
+	})
+	r.Register(&EmbedProvider{
+		Name:  "vimeo",
+		Label: "Vimeo",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https?://(?:www\.|player\.)?vimeo\.com/(?:video/|channels/[\w-]+/|groups/[\w-]+/videos/)?(?P<id>\d+)`),
+		},
+		Hugo:   `{{< vimeo {id} >}}`,
+		Iframe: `<iframe width="640" height="360" src="https://player.vimeo.com/video/{id}" title="Vimeo video" frameborder="0" allowfullscreen></iframe>`,
+	})
+	r.Register(&EmbedProvider{
+		Name:  "twitter",
+		Label: "X",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https?://(?:www\.|mobile\.)?(?:twitter|x)\.com/(?P<user>\w+)/status(?:es)?/(?P<id>\d+)`),
+		},
+		Hugo:   `{{< x user="{user}" id="{id}" >}}`,
+		Iframe: `<blockquote class="twitter-tweet"><a href="https://twitter.com/{user}/status/{id}"></a></blockquote>`,
+	})
+	r.Register(&EmbedProvider{
+		Name:  "instagram",
+		Label: "Instagram",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https?://(?:www\.)?instagr(?:\.am|am\.com)/(?:[\w.]+/)?(?P<kind>p|reel|tv)/(?P<id>[\w-]+)`),
+		},
+		Hugo:   `{{< instagram {id} >}}`,
+		Iframe: `<iframe width="400" height="480" src="https://www.instagram.com/{kind}/{id}/embed" title="Instagram post" frameborder="0"></iframe>`,
+	})
+	r.Register(&EmbedProvider{
+		Name:  "soundcloud",
+		Label: "SoundCloud",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https?://(?:www\.|m\.)?soundcloud\.com/(?P<id>[\w-]+/(?:sets/)?[\w-]+)`),
+		},
+		Hugo:   `{{< soundcloud url="{url}" >}}`,
+		Iframe: `<iframe width="100%" height="166" scrolling="no" frameborder="no" src="https://w.soundcloud.com/player/?url={url_escaped}"></iframe>`,
+	})
+	r.Register(&EmbedProvider{
+		Name:  "spotify",
+		Label: "Spotify",
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https?://open\.spotify\.com/(?:intl-[\w-]+/)?(?P<kind>track|album|playlist|episode|show|artist)/(?P<id>\w+)`),
+		},
+		Hugo:   `{{< spotify type="{kind}" id="{id}" >}}`,
+		Iframe: `<iframe width="100%" height="352" src="https://open.spotify.com/embed/{kind}/{id}" frameborder="0" allow="encrypted-media"></iframe>`,
+	})
+	return r
+}
+
+// Register adds a provider. Providers registered later take precedence, so
+// built-in providers can be overridden.
+func (r *EmbedRegistry) Register(provider *EmbedProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers = append([]*EmbedProvider{provider}, r.providers...)
+}
+
+// Match finds the provider for a URL and extracts its ID
+func (r *EmbedRegistry) Match(rawURL string) (Embed, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rawURL = strings.TrimSpace(rawURL)
+	for _, provider := range r.providers {
+		for _, pattern := range provider.Patterns {
+			m := pattern.FindStringSubmatch(rawURL)
+			if m == nil {
+				continue
+			}
+			params := make(map[string]string)
+			for i, name := range pattern.SubexpNames() {
+				if name != "" {
+					params[name] = m[i]
+				}
+			}
+			return Embed{Provider: provider, URL: rawURL, ID: params["id"], Params: params}, true
+		}
+	}
+	return Embed{}, false
+}
+
+// DetectEmbed matches a URL against the default providers
+func DetectEmbed(rawURL string) (Embed, bool) {
+	return DefaultEmbeds.Match(rawURL)
+}
+
+// Render writes the embed in the given style for the given output format.
+// Plain text always gets a "[Label: URL]" reference. placeholder is the
+// thumbnail used by EmbedLink for providers without a known thumbnail.
+func (e Embed) Render(style EmbedFormat, format OutputFormat, placeholder string) string {
+	if format == FormatPlainText {
+		return "[" + e.Provider.Label + ": " + e.URL + "]"
+	}
+
+	switch style {
+	case EmbedHugoShortcode:
+		if e.Provider.Hugo != "" {
+			return e.expand(e.Provider.Hugo)
+		}
+	case EmbedIframe:
+		if e.Provider.Iframe != "" {
+			return e.expand(e.Provider.Iframe)
+		}
+	}
+
+	thumbnail := placeholder
+	if e.Provider.Thumbnail != "" {
+		thumbnail = e.expand(e.Provider.Thumbnail)
+	}
+	label := e.Provider.Label + " embed"
+	if format == FormatMarkdown {
+		if thumbnail == "" {
+			return "[" + label + "](" + e.URL + ")"
+		}
+		return "[![" + label + "](" + thumbnail + ")](" + e.URL + ")"
+	}
+	if thumbnail == "" {
+		return `<a href="` + html.EscapeString(e.URL) + `">` + html.EscapeString(label) + "</a>"
+	}
+	return `<a href="` + html.EscapeString(e.URL) + `"><img src="` + html.EscapeString(thumbnail) + `" alt="` + html.EscapeString(label) + `"></a>`
+}
+
+// Helper function to fill in an embed template
+func (e Embed) expand(template string) string {
+	replacements := []string{"{url}", e.URL, "{url_escaped}", url.QueryEscape(e.URL)}
+	for name, value := range e.Params {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+var (
+	embedParagraphURL = regexp.MustCompile(`(?i)(<p(?: [^>]*)?>\s*)(https?://[^\s<>"]+)(\s*</p>)`)
+	embedLineURL      = regexp.MustCompile(`(?im)^(\s*)(https?://[^\s<>"]+)(\s*)$`)
+	embedWrapperURL   = regexp.MustCompile(`(?i)(<div class="wp-block-embed__wrapper">\s*)(https?://[^\s<>"]+)(\s*</div>)`)
+	embedPreBlock     = regexp.MustCompile(`(?is)<pre\b.*?</pre>`)
+	embedLinkedURL    = regexp.MustCompile(`(?i)(<p(?: [^>]*)?>\s*)<a href="(https?://[^\s<>"]+)">\s*(https?://[^\s<>"]+)\s*</a>(\s*</p>)`)
+)
+
+// Helper function to replace URLs on their own line, alone in a paragraph
+// (bare or self-linked) or inside a block embed wrapper with rendered embeds
+func (c *Converter) replaceEmbeds(content string, format OutputFormat, verbatim *verbatimStore) string {
+	if c.Embeds == nil || !strings.Contains(content, "http") {
+		return content
+	}
+	render := func(prefix, rawURL, suffix, match string) string {
+		embed, ok := c.Embeds.Match(html.UnescapeString(rawURL))
+		if !ok {
+			return match
+		}
+		return prefix + verbatim.protect(embed.Render(c.EmbedFormat, format, c.EmbedThumbnailPlaceholder)) + suffix
+	}
+
+	// A paragraph holding nothing but a link to itself, as written by the
+	// [embed] shortcode
+	content = embedLinkedURL.ReplaceAllStringFunc(content, func(match string) string {
+		m := embedLinkedURL.FindStringSubmatch(match)
+		if m[2] != m[3] {
+			return match
+		}
+		return render(m[1], m[2], m[4], match)
+	})
+	for _, re := range []*regexp.Regexp{embedWrapperURL, embedParagraphURL, embedLineURL} {
+		content = replaceOutsidePre(content, func(text string) string {
+			return re.ReplaceAllStringFunc(text, func(match string) string {
+				m := re.FindStringSubmatch(match)
+				return render(m[1], m[2], m[3], match)
+			})
+		})
+	}
+	return content
+}
+
+// RenderEmbed renders a URL as an embed using the converter's settings. URLs
+// that match no provider are returned unchanged.
+func (c *Converter) RenderEmbed(rawURL string, format OutputFormat) string {
+	if c.Embeds != nil {
+		if embed, ok := c.Embeds.Match(rawURL); ok {
+			return embed.Render(c.EmbedFormat, format, c.EmbedThumbnailPlaceholder)
+		}
+	}
+	return rawURL
+}
+
+// Helper function to apply replace to everything except <pre> blocks, so
+// URLs in code samples stay as they are
+func replaceOutsidePre(content string, replace func(string) string) string {
+	var sb strings.Builder
+	pos := 0
+	for _, loc := range embedPreBlock.FindAllStringIndex(content, -1) {
+		sb.WriteString(replace(content[pos:loc[0]]))
+		sb.WriteString(content[loc[0]:loc[1]])
+		pos = loc[1]
+	}
+	sb.WriteString(replace(content[pos:]))
+	return sb.String()
+}
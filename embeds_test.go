@@ -0,0 +1,103 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDetectEmbed tests provider and ID extraction for the built-in providers
+func TestDetectEmbed(t *testing.T) {
+	tests := []struct {
+		url      string
+		provider string
+		id       string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "youtube", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/watch?feature=share&v=dQw4w9WgXcQ", "youtube", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", "youtube", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", "youtube", "dQw4w9WgXcQ"},
+		{"https://vimeo.com/76979871", "vimeo", "76979871"},
+		{"https://player.vimeo.com/video/76979871", "vimeo", "76979871"},
+		{"https://twitter.com/golang/status/1234567890", "twitter", "1234567890"},
+		{"https://x.com/golang/status/1234567890", "twitter", "1234567890"},
+		{"https://www.instagram.com/p/CxYz123_ab/", "instagram", "CxYz123_ab"},
+		{"https://soundcloud.com/artist/track-name", "soundcloud", "artist/track-name"},
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", "spotify", "4uLU6hMCjMI75M1A2tKUQC"},
+	}
+
+	for _, tt := range tests {
+		embed, ok := DetectEmbed(tt.url)
+		if !ok {
+			t.Errorf("Expected %s to be detected", tt.url)
+			continue
+		}
+		if embed.Provider.Name != tt.provider || embed.ID != tt.id {
+			t.Errorf("Expected %s/%s for %s, got %s/%s", tt.provider, tt.id, tt.url, embed.Provider.Name, embed.ID)
+		}
+	}
+
+	if _, ok := DetectEmbed("https://example.com/watch?v=dQw4w9WgXcQ"); ok {
+		t.Error("Expected unknown URL not to be detected")
+	}
+}
+
+// TestEmbedConversion tests embed rendering for bare URLs and embed blocks
+func TestEmbedConversion(t *testing.T) {
+	classic := "Watch this:\n\nhttps://www.youtube.com/watch?v=dQw4w9WgXcQ\n\nSee <a href=\"https://vimeo.com/1\">https://vimeo.com/1</a> inline."
+	block := `<!-- wp:embed {"url":"https://vimeo.com/76979871","providerNameSlug":"vimeo"} -->
+<figure class="wp-block-embed is-provider-vimeo"><div class="wp-block-embed__wrapper">
+https://vimeo.com/76979871
+</div></figure>
+<!-- /wp:embed -->`
+
+	c := NewConverter()
+
+	markdown := c.ToMarkdown(classic)
+	if !strings.Contains(markdown, "[![YouTube embed](https://img.youtube.com/vi/dQw4w9WgXcQ/hqdefault.jpg)](https://www.youtube.com/watch?v=dQw4w9WgXcQ)") {
+		t.Errorf("Expected thumbnail link, got:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "[https://vimeo.com/1](https://vimeo.com/1) inline") {
+		t.Errorf("Expected inline link to be left alone, got:\n%s", markdown)
+	}
+	if got := c.ToMarkdown(block); got != "[Vimeo embed](https://vimeo.com/76979871)" {
+		t.Errorf("Expected plain link for provider without thumbnail, got %q", got)
+	}
+
+	c.EmbedFormat = EmbedHugoShortcode
+	markdown = c.ToMarkdown(classic)
+	if !strings.Contains(markdown, "{{< youtube dQw4w9WgXcQ >}}") {
+		t.Errorf("Expected Hugo shortcode, got:\n%s", markdown)
+	}
+	if got := c.ToMarkdown(block); got != "{{< vimeo 76979871 >}}" {
+		t.Errorf("Expected Hugo shortcode for block, got %q", got)
+	}
+
+	c.EmbedFormat = EmbedIframe
+	if got := c.ToHTML(block); !strings.Contains(got, `<iframe width="640" height="360" src="https://player.vimeo.com/video/76979871"`) {
+		t.Errorf("Expected iframe in HTML, got:\n%s", got)
+	}
+	if got := c.ToMarkdown(classic); !strings.Contains(got, `src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"`) {
+		t.Errorf("Expected iframe to survive Markdown conversion, got:\n%s", got)
+	}
+
+	text := c.ToPlainText(classic)
+	if !strings.Contains(text, "[YouTube: https://www.youtube.com/watch?v=dQw4w9WgXcQ]") {
+		t.Errorf("Expected plain text embed reference, got:\n%s", text)
+	}
+}
+
+// TestEmbedShortcodeConversion tests that [embed] URLs are detected
+func TestEmbedShortcodeConversion(t *testing.T) {
+	c := NewConverter()
+	c.EmbedFormat = EmbedHugoShortcode
+
+	got := c.ToMarkdown(`<p>Intro</p>[embed]https://x.com/golang/status/42[/embed]`)
+	if !strings.Contains(got, `{{< x user="golang" id="42" >}}`) {
+		t.Errorf("Expected X shortcode, got:\n%s", got)
+	}
+
+	got = c.ToHTML(`<p>Intro</p>[embed]https://open.spotify.com/album/abc123[/embed]`)
+	if !strings.Contains(got, `{{< spotify type="album" id="abc123" >}}`) {
+		t.Errorf("Expected Spotify shortcode in HTML, got:\n%s", got)
+	}
+}
@@ -0,0 +1,281 @@
+package wpimport
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Exporter writes a site as the source tree of a static site generator
+type Exporter interface {
+	// Export writes the site under dir
+	Export(site *WordPressSite, dir string) error
+}
+
+var (
+	_ Exporter = (*HugoExporter)(nil)
+	_ Exporter = (*JekyllExporter)(nil)
+	_ Exporter = (*TemplateExporter)(nil)
+)
+
+// exporterPresets holds the exporters NewExporter knows by name
+var exporterPresets = map[string]func() Exporter{
+	"hugo":       func() Exporter { return NewHugoExporter() },
+	"jekyll":     func() Exporter { return NewJekyllExporter() },
+	"eleventy":   func() Exporter { return NewEleventyExporter() },
+	"astro":      func() Exporter { return NewAstroExporter() },
+	"zola":       func() Exporter { return NewZolaExporter() },
+	"docusaurus": func() Exporter { return NewDocusaurusExporter() },
+}
+
+// NewExporter returns the exporter for a static site generator by name:
+// "hugo", "jekyll", "eleventy", "astro", "zola" or "docusaurus"
+func NewExporter(name string) (Exporter, error) {
+	preset, ok := exporterPresets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown exporter: %q", name)
+	}
+	return preset(), nil
+}
+
+// exportEntry is an item and where it is exported to
+type exportEntry struct {
+	item *Item
+	file string
+	url  string
+}
+
+// exportSkipTypes lists the post types that are not content of their own
+// and are never exported
+var exportSkipTypes = map[string]bool{
+	"attachment": true, "revision": true, "nav_menu_item": true, "wp_block": true,
+	"wp_template": true, "wp_template_part": true, "wp_global_styles": true,
+	"wp_navigation": true, "wp_font_family": true, "wp_font_face": true, "custom_css": true,
+	"customize_changeset": true, "oembed_cache": true, "user_request": true,
+}
+
+// Helper function to decide whether an item is exported: published and
+// scheduled items always, drafts, pending and private items when drafts is
+// set, and never trashed items or auto-drafts
+func exportable(item *Item, drafts bool) bool {
+	if exportSkipTypes[item.PostType] {
+		return false
+	}
+	switch item.Status {
+	case "publish", "future":
+		return true
+	case "draft", "pending", "private":
+		return drafts
+	}
+	return false
+}
+
+// Helper function to tell whether an exported item is not yet public
+func isDraft(item *Item) bool {
+	return item.Status != "publish" && item.Status != "future"
+}
+
+// Helper function to get the title of an item as text
+func itemTitle(item *Item) string {
+	return strings.TrimSpace(html.UnescapeString(item.Title))
+}
+
+// Helper function to get the names of an item's terms in a taxonomy, such
+// as "category" or "post_tag"
+func itemTermNames(item *Item, taxonomy string) []string {
+	var names []string
+	for _, term := range item.Categories {
+		if term.Domain == taxonomy && term.Name != "" {
+			names = append(names, strings.TrimSpace(html.UnescapeString(term.Name)))
+		}
+	}
+	return uniqueStrings(names)
+}
+
+// Helper function to list the taxonomies other than categories, tags and
+// post formats that an item has terms in, sorted
+func itemCustomTaxonomies(item *Item) []string {
+	var taxonomies []string
+	for _, term := range item.Categories {
+		switch term.Domain {
+		case "", "category", "post_tag", "post_format":
+			continue
+		}
+		taxonomies = append(taxonomies, term.Domain)
+	}
+	taxonomies = uniqueStrings(taxonomies)
+	sort.Strings(taxonomies)
+	return taxonomies
+}
+
+// Helper function to get the display name of an item's author, falling
+// back to the login
+func itemAuthorName(site *WordPressSite, item *Item) string {
+	for _, author := range site.Channel.Authors {
+		if author.Login == item.Creator && author.DisplayName != "" {
+			return author.DisplayName
+		}
+	}
+	return item.Creator
+}
+
+// Helper function to get the path of an item's permalink on the old site,
+// e.g. "/2019/05/my-post/". Links by query, such as "?p=123" for drafts,
+// have no path and give "".
+func permalinkPath(item *Item) string {
+	u, err := url.Parse(strings.TrimSpace(item.Link))
+	if err != nil || u.RawQuery != "" || strings.Trim(u.Path, "/") == "" {
+		return ""
+	}
+	return u.EscapedPath()
+}
+
+// Helper function to get the attachment set as an item's featured image
+func featuredImage(site *WordPressSite, item *Item) *Item {
+	id, err := strconv.Atoi(strings.TrimSpace(item.GetMetaValue("_thumbnail_id")))
+	if err != nil {
+		return nil
+	}
+	for i := range site.Channel.Items {
+		if attachment := &site.Channel.Items[i]; attachment.PostID == id && attachment.PostType == "attachment" {
+			return attachment
+		}
+	}
+	return nil
+}
+
+// Helper function to combine the fields an exporter renames from the ones
+// FrontMatter.SetItem writes, e.g. {"description": "summary"}, with the
+// user's mapping. The user's renames apply to the exporter's names.
+func exportMapping(mapping FrontMatterMapping, renames map[string]string) FrontMatterMapping {
+	rename := make(map[string]string, len(mapping.Rename)+len(renames))
+	for key, renamed := range mapping.Rename {
+		rename[key] = renamed
+	}
+	for key, renamed := range renames {
+		if final, ok := mapping.Rename[renamed]; ok {
+			renamed = final
+		}
+		rename[key] = renamed
+	}
+	mapping.Rename = rename
+	return mapping
+}
+
+// Helper function to add the common fields of an item with
+// FrontMatter.SetItem, leaving the fields of Mapping.Meta for the exporter
+// to add after its own
+func exportSetItem(fm *FrontMatter, site *WordPressSite, item *Item) {
+	meta := fm.Mapping.Meta
+	fm.Mapping.Meta = nil
+	fm.SetItem(site, item)
+	fm.Mapping.Meta = meta
+}
+
+// Helper function to get the converter for an export: a copy of base, or
+// of a converter for the site when base is nil. Unless base has its own
+// Links, links between the exported items are rewritten to their new URLs.
+func exportConverter(site *WordPressSite, base *Converter, entries []exportEntry) *Converter {
+	var converter Converter
+	if base != nil {
+		converter = *base
+	} else {
+		converter = *NewSiteConverter(site)
+	}
+	if converter.Links == nil {
+		urls := make(map[*Item]string)
+		for _, entry := range entries {
+			urls[entry.item] = entry.url
+		}
+		converter.Links = NewLinkResolver(site, func(item *Item) string { return urls[item] })
+	}
+	return &converter
+}
+
+// exportBundle collects the upload files to copy next to one exported
+// file, naming each after its file name. With keepPaths set, files keep
+// their uploads path instead, e.g. "uploads/2019/05/photo.jpg".
+type exportBundle struct {
+	uploadsDir string
+	keepPaths  bool
+	files      map[string]string
+	names      map[string]string
+}
+
+// Helper function to create a bundle of files from uploadsDir
+func newExportBundle(uploadsDir string) *exportBundle {
+	return &exportBundle{uploadsDir: uploadsDir, files: make(map[string]string), names: make(map[string]string)}
+}
+
+// Helper function to add an upload to the bundle and return its name
+// there. Resized copies that were not downloaded fall back to the original
+// upload. It returns "" when neither is in the uploads directory.
+func (b *exportBundle) add(file MediaFile) string {
+	for _, candidate := range []string{file.Path, file.Original} {
+		// Cleaning below the root keeps ".." from leaving the directory
+		candidate = strings.TrimPrefix(path.Clean("/"+candidate), "/")
+		src := filepath.Join(b.uploadsDir, filepath.FromSlash(candidate))
+		if info, err := os.Stat(src); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if name, ok := b.names[src]; ok {
+			return name
+		}
+		name := path.Base(candidate)
+		if b.keepPaths {
+			name = "uploads/" + candidate
+		} else if _, taken := b.files[name]; taken {
+			name = strings.ReplaceAll(candidate, "/", "-")
+		}
+		b.files[name] = src
+		b.names[src] = name
+		return name
+	}
+	return ""
+}
+
+// Helper function to copy the bundle's files into dir
+func (b *exportBundle) copyTo(dir string) error {
+	for name, src := range b.files {
+		if err := copyFile(src, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Helper function to write a file, creating its directory
+func writeExportFile(name, content string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(content), 0o644)
+}
+
+// Helper function to copy a file, creating the directory of dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
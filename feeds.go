@@ -0,0 +1,819 @@
+package wpimport
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedFormat is the format of a feed
+type FeedFormat int
+
+const (
+	// FeedRSS is RSS 2.0, the format of WordPress' /feed/
+	FeedRSS FeedFormat = iota
+	// FeedAtom is Atom 1.0, the format of WordPress' /feed/atom/
+	FeedAtom
+	// FeedJSON is JSON Feed 1.1
+	FeedJSON
+)
+
+// FeedOptions selects the posts of a feed and how they are written
+type FeedOptions struct {
+	// Title is the title of the feed. When empty it is the site title,
+	// followed by the name of the category or author the feed is for.
+	Title string
+
+	// SiteURL is the home page of the site, the channel link when empty
+	SiteURL string
+
+	// FeedURL is the URL the feed is published at
+	FeedURL string
+
+	// Category limits the feed to the posts in a category, by slug
+	Category string
+
+	// Author limits the feed to the posts of an author, by login
+	Author string
+
+	// Excerpt writes a summary of each post instead of its full content
+	Excerpt bool
+
+	// Limit is the number of posts, the newest first. 0 means 10, as in
+	// WordPress; a negative limit includes every post.
+	Limit int
+
+	// Types are the post types included, only posts when empty
+	Types []string
+
+	// URL returns the absolute URL of an item on the new site. When nil,
+	// or when it returns "", the item keeps its link. Links between items
+	// in the content are rewritten the same way unless the Converter has
+	// its own Links.
+	URL func(*Item) string
+
+	// Converter renders the content as HTML, sanitized with its Sanitize
+	// policy or PostBodyPolicy. When nil, a converter with the default
+	// settings for the site is used. Its Media, when set, also
+	// maps the URLs of enclosures.
+	Converter *Converter
+}
+
+// feedExcerptWords is the length of generated excerpts, as in WordPress
+const feedExcerptWords = 55
+
+// feed is a feed ready to be written in any format
+type feed struct {
+	title       string
+	link        string
+	feedURL     string
+	description string
+	language    string
+	updated     time.Time
+	entries     []feedEntry
+}
+
+// feedEntry is a post in a feed
+type feedEntry struct {
+	id         string
+	title      string
+	link       string
+	author     string
+	summary    string
+	content    string
+	image      string
+	published  time.Time
+	updated    time.Time
+	categories []string
+	enclosures []feedEnclosure
+}
+
+// feedEnclosure is a file attached to a post, such as a podcast episode
+type feedEnclosure struct {
+	url      string
+	mimeType string
+	length   int64
+}
+
+// WriteFeed writes a feed of the site's published posts. Password
+// protected posts are left out. Entries are identified by their GUID, as
+// in WordPress, so readers that subscribed to the old feed do not show
+// them again.
+func WriteFeed(w io.Writer, site *WordPressSite, format FeedFormat, opts FeedOptions) error {
+	return newFeed(site, opts, newCategoryParents(site)).write(w, format)
+}
+
+// Helper function to write a feed in the given format
+func (f *feed) write(w io.Writer, format FeedFormat) error {
+	switch format {
+	case FeedRSS:
+		return f.writeRSS(w)
+	case FeedAtom:
+		return f.writeAtom(w)
+	case FeedJSON:
+		return f.writeJSON(w)
+	}
+	return fmt.Errorf("unknown feed format: %d", format)
+}
+
+// Helper function to collect the posts of a feed
+func newFeed(site *WordPressSite, opts FeedOptions, categories categoryParents) *feed {
+	f := &feed{
+		title:       opts.Title,
+		link:        opts.SiteURL,
+		feedURL:     opts.FeedURL,
+		description: strings.TrimSpace(html.UnescapeString(site.Channel.Description)),
+		language:    site.Channel.Language,
+	}
+	if f.link == "" {
+		f.link = site.Channel.Link
+	}
+	if f.title == "" {
+		f.title = feedTitle(site, opts)
+	}
+
+	var items []*Item
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if !feedIncluded(item, opts) {
+			continue
+		}
+		if opts.Author != "" && item.Creator != opts.Author {
+			continue
+		}
+		if opts.Category != "" && !feedInCategory(categories, item, opts.Category) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, _ := items[i].PublishedTime()
+		b, _ := items[j].PublishedTime()
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return items[i].PostID > items[j].PostID
+	})
+	limit := opts.Limit
+	if limit == 0 {
+		limit = 10
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	converter := feedConverter(site, opts)
+	for _, item := range items {
+		entry := newFeedEntry(site, item, opts, converter)
+		if entry.updated.After(f.updated) {
+			f.updated = entry.updated
+		}
+		f.entries = append(f.entries, entry)
+	}
+	if f.updated.IsZero() {
+		f.updated, _ = time.Parse(time.RFC1123Z, strings.TrimSpace(site.Channel.PubDate))
+	}
+	return f
+}
+
+// Helper function to tell whether an item is a public post of one of
+// the feed's types
+func feedIncluded(item *Item, opts FeedOptions) bool {
+	if item.Status != "publish" || item.PostPassword != "" {
+		return false
+	}
+	if len(opts.Types) == 0 {
+		return item.PostType == "post"
+	}
+	for _, t := range opts.Types {
+		if item.PostType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to title a feed after the site and, like WordPress,
+// the category or author it is for
+func feedTitle(site *WordPressSite, opts FeedOptions) string {
+	title := strings.TrimSpace(html.UnescapeString(site.Channel.Title))
+	name := ""
+	if opts.Category != "" {
+		name = opts.Category
+		for _, c := range site.Channel.Categories {
+			if c.NiceName == opts.Category && c.Name != "" {
+				name = strings.TrimSpace(html.UnescapeString(c.Name))
+			}
+		}
+	} else if opts.Author != "" {
+		name = itemAuthorName(site, &Item{Creator: opts.Author})
+	}
+	if name == "" {
+		return title
+	}
+	if title == "" {
+		return name
+	}
+	return title + " » " + name
+}
+
+// Helper function to tell whether an item is in a category or, as in
+// WordPress' category feeds, one of its children
+func feedInCategory(categories categoryParents, item *Item, slug string) bool {
+	for _, term := range item.Categories {
+		if term.Domain != "category" {
+			continue
+		}
+		for _, segment := range strings.Split(categories.path(term.NiceName), "/") {
+			if segment == slug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Helper function to get the converter of a feed, rewriting links to
+// items when the feed has new URLs for them
+func feedConverter(site *WordPressSite, opts FeedOptions) *Converter {
+	var converter Converter
+	if opts.Converter != nil {
+		converter = *opts.Converter
+	} else {
+		converter = *NewSiteConverter(site)
+	}
+	if converter.Links == nil && opts.URL != nil {
+		converter.Links = NewLinkResolver(site, opts.URL)
+	}
+	return &converter
+}
+
+// Helper function to build the entry of a post
+func newFeedEntry(site *WordPressSite, item *Item, opts FeedOptions, converter *Converter) feedEntry {
+	converter = converter.ForItem(item)
+	entry := feedEntry{
+		id:         feedID(site, item),
+		title:      itemTitle(item),
+		link:       feedItemURL(item, opts),
+		author:     itemAuthorName(site, item),
+		categories: uniqueStrings(append(itemTermNames(item, "category"), itemTermNames(item, "post_tag")...)),
+	}
+	entry.published, _ = item.PublishedTime()
+	entry.updated, _ = item.ModifiedTime()
+	if entry.updated.Before(entry.published) {
+		entry.updated = entry.published
+	}
+
+	if strings.TrimSpace(item.Excerpt) != "" {
+		entry.summary = feedText(converter.ToPlainText(item.Excerpt))
+	} else {
+		words := strings.Fields(converter.ToPlainText(item.Content))
+		if len(words) > feedExcerptWords {
+			words = append(words[:feedExcerptWords], "[…]")
+		}
+		entry.summary = strings.Join(words, " ")
+	}
+	if !opts.Excerpt {
+		entry.content = strings.TrimSpace(feedHTML(converter, item.Content))
+	}
+
+	if image := featuredImage(site, item); image != nil {
+		entry.image = feedMediaURL(converter, image.GetAttachmentURL())
+	}
+	entry.enclosures = feedEnclosures(site, item, converter)
+	return entry
+}
+
+// Helper function to render the content of an entry as HTML. Unlike
+// Converter.ToHTML it keeps the markup of lists and the whitespace of
+// preformatted text, sanitizing with the converter's Sanitize policy, or
+// PostBodyPolicy when it has none.
+func feedHTML(converter *Converter, content string) string {
+	verbatim := newVerbatimStore()
+	content = verbatim.restore(converter.prepare(content, FormatHTML, verbatim))
+	policy := converter.Sanitize
+	if policy == nil {
+		policy = PostBodyPolicy()
+	}
+	return policy.Sanitize(content)
+}
+
+// Helper function to collapse the whitespace of plain text
+func feedText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Helper function to get the stable ID of a post: its GUID, or its link
+// when the export has none
+func feedID(site *WordPressSite, item *Item) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	return strings.TrimSuffix(site.Channel.Link, "/") + "/?p=" + strconv.Itoa(item.PostID)
+}
+
+// Helper function to get the URL of an item in a feed
+func feedItemURL(item *Item, opts FeedOptions) string {
+	if opts.URL != nil {
+		if u := opts.URL(item); u != "" {
+			return u
+		}
+	}
+	return strings.TrimSpace(item.Link)
+}
+
+// Helper function to map the URL of an upload with the converter's Media
+func feedMediaURL(converter *Converter, rawURL string) string {
+	if converter.Media == nil || rawURL == "" {
+		return rawURL
+	}
+	return converter.Media.rewrite(rawURL, &mediaRefs{})
+}
+
+// Helper function to get the enclosures of a post: the enclosure meta
+// WordPress records for audio and video links, then the files other than
+// images attached to the post. Images are left out, as they are part of
+// the content.
+func feedEnclosures(site *WordPressSite, item *Item, converter *Converter) []feedEnclosure {
+	var enclosures []feedEnclosure
+	seen := make(map[string]bool)
+	add := func(enclosure feedEnclosure) {
+		if enclosure.url == "" || seen[enclosure.url] {
+			return
+		}
+		seen[enclosure.url] = true
+		enclosures = append(enclosures, enclosure)
+	}
+
+	// The meta holds the URL, the length and the MIME type on separate
+	// lines
+	for _, meta := range item.PostMeta {
+		if meta.Key != "enclosure" {
+			continue
+		}
+		lines := strings.Split(strings.ReplaceAll(meta.Value, "\r", ""), "\n")
+		enclosure := feedEnclosure{url: strings.TrimSpace(lines[0])}
+		if len(lines) > 1 {
+			enclosure.length, _ = strconv.ParseInt(strings.TrimSpace(lines[1]), 10, 64)
+		}
+		if len(lines) > 2 {
+			enclosure.mimeType = strings.TrimSpace(lines[2])
+		}
+		if enclosure.mimeType == "" {
+			enclosure.mimeType = mediaMIMEType(enclosure.url)
+		}
+		enclosure.url = feedMediaURL(converter, enclosure.url)
+		add(enclosure)
+	}
+
+	for i := range site.Channel.Items {
+		attachment := &site.Channel.Items[i]
+		if attachment.PostType != "attachment" || attachment.PostParent != item.PostID || item.PostID == 0 {
+			continue
+		}
+		file := attachment.GetAttachmentURL()
+		mimeType := mediaMIMEType(file)
+		if file == "" || strings.HasPrefix(mimeType, "image/") {
+			continue
+		}
+		add(feedEnclosure{url: feedMediaURL(converter, file), mimeType: mimeType, length: attachmentFileSize(attachment)})
+	}
+	return enclosures
+}
+
+// RSS 2.0 documents, with the content, Dublin Core and Atom extensions
+// WordPress uses
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	AtomLink      *rssAtomLink `xml:"atom:link,omitempty"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	LastBuildDate string       `xml:"lastBuildDate,omitempty"`
+	Language      string       `xml:"language,omitempty"`
+	Generator     string       `xml:"generator"`
+	Items         []rssItem    `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link,omitempty"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Categories  []string      `xml:"category"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description"`
+	Content     string        `xml:"content:encoded,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// Helper function to write the feed as RSS 2.0. RSS allows one enclosure
+// per item, so only the first is written.
+func (f *feed) writeRSS(w io.Writer) error {
+	doc := rssFeed{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.title,
+			Link:        f.link,
+			Description: f.description,
+			Language:    f.language,
+			Generator:   "wp-import",
+		},
+	}
+	if f.feedURL != "" {
+		doc.Channel.AtomLink = &rssAtomLink{Href: f.feedURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	if !f.updated.IsZero() {
+		doc.Channel.LastBuildDate = f.updated.Format(time.RFC1123Z)
+	}
+	for _, entry := range f.entries {
+		item := rssItem{
+			Title:       entry.title,
+			Link:        entry.link,
+			Creator:     entry.author,
+			Categories:  entry.categories,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entry.id},
+			Description: entry.summary,
+			Content:     entry.content,
+		}
+		if !entry.published.IsZero() {
+			item.PubDate = entry.published.Format(time.RFC1123Z)
+		}
+		if len(entry.enclosures) > 0 {
+			e := entry.enclosures[0]
+			item.Enclosure = &rssEnclosure{URL: e.url, Length: e.length, Type: e.mimeType}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXMLDocument(w, doc)
+}
+
+// Atom 1.0 documents
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Helper function to write the feed as Atom 1.0. Entries without an
+// author are credited to the feed, as Atom requires an author.
+func (f *feed) writeAtom(w io.Writer) error {
+	id := f.feedURL
+	if id == "" {
+		id = f.link
+	}
+	doc := atomFeed{
+		Title:     f.title,
+		Subtitle:  f.description,
+		ID:        id,
+		Updated:   feedAtomTime(f.updated),
+		Generator: "wp-import",
+	}
+	if f.link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.link, Rel: "alternate", Type: "text/html"})
+	}
+	if f.feedURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.feedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, entry := range f.entries {
+		e := atomEntry{
+			Title:   entry.title,
+			ID:      entry.id,
+			Updated: feedAtomTime(entry.updated),
+			Author:  atomPerson{Name: entry.author},
+			Summary: &atomText{Type: "text", Body: entry.summary},
+		}
+		if e.Author.Name == "" {
+			e.Author.Name = f.title
+		}
+		if !entry.published.IsZero() {
+			e.Published = feedAtomTime(entry.published)
+		}
+		if entry.link != "" {
+			e.Links = append(e.Links, atomLink{Href: entry.link, Rel: "alternate", Type: "text/html"})
+		}
+		for _, enclosure := range entry.enclosures {
+			e.Links = append(e.Links, atomLink{Href: enclosure.url, Rel: "enclosure", Type: enclosure.mimeType, Length: enclosure.length})
+		}
+		for _, category := range entry.categories {
+			e.Categories = append(e.Categories, atomCategory{Term: category})
+		}
+		if entry.content != "" {
+			e.Content = &atomText{Type: "html", Body: entry.content}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return writeXMLDocument(w, doc)
+}
+
+// Helper function to format a time for Atom, which requires one for
+// every feed and entry
+func feedAtomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0).UTC()
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Helper function to write an XML document with its declaration
+func writeXMLDocument(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// JSON Feed 1.1 documents
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Language    string         `json:"language,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url,omitempty"`
+	Title         string               `json:"title,omitempty"`
+	ContentHTML   string               `json:"content_html,omitempty"`
+	ContentText   string               `json:"content_text,omitempty"`
+	Summary       string               `json:"summary,omitempty"`
+	Image         string               `json:"image,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	DateModified  string               `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor     `json:"authors,omitempty"`
+	Tags          []string             `json:"tags,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+}
+
+// Helper function to write the feed as JSON Feed 1.1. Items have HTML
+// content, or the summary as their text when the feed has excerpts only.
+func (f *feed) writeJSON(w io.Writer) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.title,
+		HomePageURL: f.link,
+		FeedURL:     f.feedURL,
+		Description: f.description,
+		Language:    f.language,
+		Items:       []jsonFeedItem{},
+	}
+	for _, entry := range f.entries {
+		item := jsonFeedItem{
+			ID:    entry.id,
+			URL:   entry.link,
+			Title: entry.title,
+			Image: entry.image,
+			Tags:  entry.categories,
+		}
+		if entry.content != "" {
+			item.ContentHTML = entry.content
+			item.Summary = entry.summary
+		} else {
+			item.ContentText = entry.summary
+		}
+		if !entry.published.IsZero() {
+			item.DatePublished = entry.published.Format(time.RFC3339)
+		}
+		if !entry.updated.IsZero() {
+			item.DateModified = entry.updated.Format(time.RFC3339)
+		}
+		if entry.author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: entry.author}}
+		}
+		for _, enclosure := range entry.enclosures {
+			item.Attachments = append(item.Attachments, jsonFeedAttachment{URL: enclosure.url, MimeType: enclosure.mimeType, SizeInBytes: enclosure.length})
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// FeedExporter writes a site's feeds at the paths WordPress serves them
+// from, so a static site keeps its subscribers: feed/index.xml (RSS),
+// feed/atom/index.xml and feed/json/index.json, and the same below
+// category/<path>/ and author/<name>/ for category and author feeds.
+type FeedExporter struct {
+	// Formats are the formats written, all of them when empty
+	Formats []FeedFormat
+
+	// Options apply to every feed. Their Title, FeedURL, Category and
+	// Author are set for each feed.
+	Options FeedOptions
+
+	// Categories writes a feed for each category with published posts,
+	// directly or in one of its children
+	Categories bool
+
+	// Authors writes a feed for each author with published posts
+	Authors bool
+}
+
+// NewFeedExporter creates an exporter writing the site feed in every
+// format, with category and author feeds
+func NewFeedExporter() *FeedExporter {
+	return &FeedExporter{Categories: true, Authors: true}
+}
+
+// feedFiles are the paths of each format below a feed's directory
+var feedFiles = map[FeedFormat]string{
+	FeedRSS:  "feed/index.xml",
+	FeedAtom: "feed/atom/index.xml",
+	FeedJSON: "feed/json/index.json",
+}
+
+// Export writes the feeds under dir
+func (e *FeedExporter) Export(site *WordPressSite, dir string) error {
+	formats := e.Formats
+	if len(formats) == 0 {
+		formats = []FeedFormat{FeedRSS, FeedAtom, FeedJSON}
+	}
+	siteURL := e.Options.SiteURL
+	if siteURL == "" {
+		siteURL = site.Channel.Link
+	}
+
+	for _, format := range formats {
+		if _, ok := feedFiles[format]; !ok {
+			return fmt.Errorf("unknown feed format: %d", format)
+		}
+	}
+
+	categories := newCategoryParents(site)
+	feeds := map[string]FeedOptions{"": e.Options}
+	if e.Categories || e.Authors {
+		for i := range site.Channel.Items {
+			item := &site.Channel.Items[i]
+			if !feedIncluded(item, e.Options) {
+				continue
+			}
+			if e.Authors && item.Creator != "" {
+				opts := e.Options
+				opts.Author = item.Creator
+				feeds["author/"+slugify(item.Creator)] = opts
+			}
+			for _, term := range item.Categories {
+				if !e.Categories || term.Domain != "category" || term.NiceName == "" {
+					continue
+				}
+				// The post is in the feeds of the category's parents too
+				segments := strings.Split(categories.path(term.NiceName), "/")
+				for i, slug := range segments {
+					opts := e.Options
+					opts.Category = slug
+					feeds["category/"+strings.Join(segments[:i+1], "/")] = opts
+				}
+			}
+		}
+	}
+
+	for _, base := range sortedFeedPaths(feeds) {
+		f := newFeed(site, feeds[base], categories)
+		for _, format := range formats {
+			file := path.Join(base, feedFiles[format])
+			if siteURL != "" {
+				f.feedURL = strings.TrimSuffix(siteURL, "/") + "/" + strings.TrimSuffix(path.Dir(file), "/") + "/"
+			}
+			var sb strings.Builder
+			if err := f.write(&sb, format); err != nil {
+				return fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			if err := writeExportFile(filepath.Join(dir, filepath.FromSlash(file)), sb.String()); err != nil {
+				return fmt.Errorf("failed to write %s: %w", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Helper function to list the feeds in a stable order
+func sortedFeedPaths(feeds map[string]FeedOptions) []string {
+	paths := make([]string, 0, len(feeds))
+	for p := range feeds {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// categoryParents maps the slug of each category to its parent's slug
+type categoryParents map[string]string
+
+// Helper function to index the parents of the site's categories
+func newCategoryParents(site *WordPressSite) categoryParents {
+	parents := make(categoryParents, len(site.Channel.Categories))
+	for _, c := range site.Channel.Categories {
+		parents[c.NiceName] = c.Parent
+	}
+	return parents
+}
+
+// Helper function to get the path of a category archive below
+// /category/, which includes its parents, e.g. "news/local"
+func (parents categoryParents) path(slug string) string {
+	segments := []string{slug}
+	seen := map[string]bool{slug: true}
+	for parent := parents[slug]; parent != "" && !seen[parent]; parent = parents[parent] {
+		seen[parent] = true
+		segments = append([]string{parent}, segments...)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,205 @@
+package wpimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a site with a second author, a podcast episode
+// and a password protected post
+func feedTestSite() *WordPressSite {
+	site := jsonTestSite()
+	site.Channel.Link = "https://oldsite.com"
+	site.Channel.Description = "News &amp; notes"
+	site.Channel.Language = "en-US"
+	site.Channel.Authors = append(site.Channel.Authors, Author{ID: 2, Login: "max", DisplayName: "Max"})
+	site.Channel.Categories = append(site.Channel.Categories, Category{TermID: 4, NiceName: "local", Parent: "news", Name: "Local"})
+	site.Channel.Items = append(site.Channel.Items,
+		Item{PostID: 40, PostType: "post", Status: "publish", PostName: "episode-1", Title: "Episode 1",
+			Link: "https://oldsite.com/2019/07/episode-1/", Creator: "max",
+			PostDate: "2019-07-01 09:00:00", PostDateGMT: "2019-07-01 07:00:00",
+			Content:    strings.Repeat("word ", 60),
+			Categories: []ItemCategory{{Domain: "category", NiceName: "local", Name: "Local"}}},
+		Item{PostID: 41, PostType: "attachment", PostParent: 40, AttachmentURL: "https://oldsite.com/wp-content/uploads/2019/07/episode-1.mp3",
+			PostMeta: []PostMeta{{Key: "_wp_attachment_metadata", Value: `a:1:{s:8:"filesize";i:12345;}`}}},
+		Item{PostID: 42, PostType: "attachment", PostParent: 40, AttachmentURL: "https://oldsite.com/wp-content/uploads/2019/07/cover.png"},
+		Item{PostID: 43, PostType: "post", Status: "publish", PostPassword: "secret", Title: "Hidden",
+			PostDate: "2019-08-01 00:00:00", Content: "Secret."},
+	)
+	return site
+}
+
+// TestFeedRSS tests the RSS feed of a site
+func TestFeedRSS(t *testing.T) {
+	var buf bytes.Buffer
+	opts := FeedOptions{FeedURL: "https://newsite.com/feed/"}
+	if err := WriteFeed(&buf, feedTestSite(), FeedRSS, opts); err != nil {
+		t.Fatalf("WriteFeed failed: %v", err)
+	}
+
+	var doc rssFeed
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid XML: %v", err)
+	}
+	channel := doc.Channel
+	if channel.Title != "Old & Gold" || channel.Description != "News & notes" || channel.Language != "en-US" {
+		t.Errorf("Unexpected channel: %+v", channel)
+	}
+	if channel.LastBuildDate != "Mon, 01 Jul 2019 09:00:00 +0200" {
+		t.Errorf("Expected the newest modification as build date, got %q", channel.LastBuildDate)
+	}
+
+	// Published posts only, newest first, without password protected ones
+	if len(channel.Items) != 2 || channel.Items[0].Title != "Episode 1" || channel.Items[1].Title != `Hello & "World"` {
+		t.Fatalf("Unexpected items: %+v", channel.Items)
+	}
+	episode, post := channel.Items[0], channel.Items[1]
+	if post.GUID.Value != "https://oldsite.com/?p=10" || post.GUID.IsPermaLink != "false" {
+		t.Errorf("Expected the GUID as ID, got %+v", post.GUID)
+	}
+	if episode.GUID.Value != "https://oldsite.com/2019/07/episode-1/" {
+		t.Errorf("Expected the link as ID without a GUID, got %q", episode.GUID.Value)
+	}
+	if post.PubDate != "Wed, 01 May 2019 10:30:00 +0200" || len(post.Categories) != 3 || post.Description != "A short summary." {
+		t.Errorf("Unexpected post: %+v", post)
+	}
+	if !strings.HasSuffix(episode.Description, "word […]") || len(strings.Fields(episode.Description)) != 56 {
+		t.Errorf("Expected a generated excerpt of 55 words, got %q", episode.Description)
+	}
+	if e := episode.Enclosure; e == nil || !strings.HasSuffix(e.URL, "episode-1.mp3") || e.Type != "audio/mpeg" || e.Length != 12345 {
+		t.Errorf("Expected the attached audio as enclosure, got %+v", e)
+	}
+	if !strings.Contains(buf.String(), "<content:encoded>&lt;p&gt;See") || !strings.Contains(buf.String(), `<atom:link href="https://newsite.com/feed/" rel="self"`) {
+		t.Errorf("Unexpected feed:\n%s", buf.String())
+	}
+}
+
+// TestFeedAtom tests the Atom feed of a category with excerpts and new URLs
+func TestFeedAtom(t *testing.T) {
+	var buf bytes.Buffer
+	opts := FeedOptions{
+		Category: "news",
+		Excerpt:  true,
+		URL:      func(item *Item) string { return "https://newsite.com/" + item.PostName + "/" },
+	}
+	if err := WriteFeed(&buf, feedTestSite(), FeedAtom, opts); err != nil {
+		t.Fatalf("WriteFeed failed: %v", err)
+	}
+
+	var doc atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid XML: %v", err)
+	}
+	if doc.Title != "Old & Gold » News" || doc.ID != "https://oldsite.com" || doc.Updated != "2019-07-01T09:00:00+02:00" {
+		t.Errorf("Unexpected feed: %+v", doc)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[0].Title != "Episode 1" {
+		t.Fatalf("Expected the posts in the category and its children, got %+v", doc.Entries)
+	}
+	entry := doc.Entries[1]
+	if entry.ID != "https://oldsite.com/?p=10" || entry.Links[0].Href != "https://newsite.com/hello-world/" || entry.Author.Name != "Jane Doe" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Content != nil || entry.Summary == nil || entry.Summary.Body != "A short summary." {
+		t.Errorf("Expected only the summary, got %+v and %+v", entry.Content, entry.Summary)
+	}
+}
+
+// TestFeedJSON tests the JSON feed of an author
+func TestFeedJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFeed(&buf, feedTestSite(), FeedJSON, FeedOptions{Author: "jane", Limit: -1}); err != nil {
+		t.Fatalf("WriteFeed failed: %v", err)
+	}
+
+	var doc jsonFeed
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if doc.Version != "https://jsonfeed.org/version/1.1" || doc.Title != "Old & Gold » Jane Doe" || len(doc.Items) != 1 {
+		t.Fatalf("Unexpected feed: %+v", doc)
+	}
+	item := doc.Items[0]
+	if item.ID != "https://oldsite.com/?p=10" || item.DatePublished != "2019-05-01T10:30:00+02:00" || item.DateModified != "2019-06-01T12:00:00+02:00" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if !strings.Contains(item.ContentHTML, `<a href="https://oldsite.com/about/team/">`) || item.Summary != "A short summary." {
+		t.Errorf("Expected full content, got %+v", item)
+	}
+	if item.Image != "https://oldsite.com/wp-content/uploads/2019/05/cover.jpg" || item.Authors[0].Name != "Jane Doe" {
+		t.Errorf("Expected the featured image and author, got %+v", item)
+	}
+}
+
+// TestFeedExport tests writing the feeds at WordPress' paths
+func TestFeedExport(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewFeedExporter().Export(feedTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	for _, name := range []string{
+		"feed/index.xml", "feed/atom/index.xml", "feed/json/index.json",
+		"category/news/feed/index.xml", "category/news/local/feed/atom/index.xml",
+		"author/jane/feed/index.xml", "author/max/feed/json/index.json",
+	} {
+		readExport(t, dir, name)
+	}
+	if rss := readExport(t, dir, "category/news/local/feed/index.xml"); !strings.Contains(rss, `href="https://oldsite.com/category/news/local/feed/"`) || strings.Contains(rss, "Hello") {
+		t.Errorf("Unexpected category feed:\n%s", rss)
+	}
+
+	if atom := readExport(t, dir, "category/news/local/feed/atom/index.xml"); !strings.Contains(atom, `href="https://oldsite.com/category/news/local/feed/atom/"`) {
+		t.Errorf("Expected the Atom feed's own URL, got:\n%s", atom)
+	}
+
+	// A parent category gets a feed from its children's posts alone
+	site := feedTestSite()
+	for i := range site.Channel.Items {
+		if site.Channel.Items[i].PostID != 40 {
+			site.Channel.Items[i].Categories = nil
+		}
+	}
+	dir = t.TempDir()
+	if err := (&FeedExporter{Categories: true}).Export(site, dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if rss := readExport(t, dir, "category/news/feed/index.xml"); !strings.Contains(rss, "<title>Episode 1</title>") {
+		t.Errorf("Expected the child category's post in the parent's feed, got:\n%s", rss)
+	}
+
+	var e Exporter = &FeedExporter{Formats: []FeedFormat{FeedFormat(9)}}
+	if err := e.Export(feedTestSite(), t.TempDir()); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+// TestFeedContentStructure tests that entry content keeps lists and
+// preformatted text
+func TestFeedContentStructure(t *testing.T) {
+	site := feedTestSite()
+	site.Channel.Items[0].Content = "<ul>\n<li>One</li>\n<li>Two</li>\n</ul>\n\n<pre>func main() {\n    fmt.Println(\"hi\")\n}</pre>\n\n<script>alert(1)</script>"
+
+	var buf bytes.Buffer
+	if err := WriteFeed(&buf, site, FeedJSON, FeedOptions{Author: "jane", Limit: -1}); err != nil {
+		t.Fatalf("WriteFeed failed: %v", err)
+	}
+	var doc jsonFeed
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if len(doc.Items) != 1 {
+		t.Fatalf("Expected one item, got %+v", doc.Items)
+	}
+	content := doc.Items[0].ContentHTML
+	for _, want := range []string{"<ul>", "<li>One</li>", "<li>Two</li>", "</ul>", "<pre>func main() {\n    fmt.Println(&#34;hi&#34;)\n}</pre>"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in content, got %q", want, content)
+		}
+	}
+	if strings.Contains(content, "<script") {
+		t.Errorf("Expected the script to be removed, got %q", content)
+	}
+}
@@ -0,0 +1,199 @@
+package wpimport
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// FigureFormat controls how images with captions are written in Markdown
+type FigureFormat int
+
+const (
+	// FigureMarkdown writes the image followed by an italic caption line.
+	// Markdown cannot express width and height, so they are dropped.
+	FigureMarkdown FigureFormat = iota
+	// FigureHugoShortcode writes Hugo's {{< figure >}} shortcode
+	FigureHugoShortcode
+	// FigureHTML keeps the figure as HTML inside the Markdown
+	FigureHTML
+)
+
+// figure is an image parsed from a <figure> or a bare <img>, with its
+// optional link and caption
+type figure struct {
+	Src    string
+	Alt    string
+	Title  string
+	Width  string
+	Height string
+	Link   string
+
+	// Caption is the figcaption content as HTML
+	Caption string
+}
+
+var (
+	figureCaption     = regexp.MustCompile(`(?is)<figcaption\b[^>]*>(.*?)</figcaption>`)
+	figureImageMarkup = regexp.MustCompile(`(?is)</?(?:a|img|picture|source|figure|span)\b[^>]*>`)
+	figureImageTag    = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	figureLinkedImage = regexp.MustCompile(`(?is)<a\b[^>]*>\s*<img\b[^>]*>\s*</a>`)
+	figureTagStrip    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Helper function to parse a <figure> element that holds a single image
+// (optionally linked) and a caption. Figures holding anything else, such as
+// tables or embeds, are not image figures.
+func parseFigure(fragment string) (figure, bool) {
+	var fig figure
+	body := fragment
+	if m := figureCaption.FindStringSubmatch(body); m != nil {
+		fig.Caption = strings.TrimSpace(m[1])
+		body = strings.Replace(body, m[0], "", 1)
+	}
+	if strings.TrimSpace(figureImageMarkup.ReplaceAllString(body, "")) != "" {
+		return figure{}, false
+	}
+	if len(figureImageTag.FindAllString(body, 2)) != 1 {
+		return figure{}, false
+	}
+	parsed := parseImage(body)
+	parsed.Caption = fig.Caption
+	return parsed, true
+}
+
+// Helper function to read an image and its wrapping link from HTML,
+// regardless of attribute order
+func parseImage(fragment string) figure {
+	img := findHTMLTag(fragment, "img")
+	fig := figure{
+		Src:    getHTMLAttribute(img, "src"),
+		Alt:    getHTMLAttribute(img, "alt"),
+		Title:  getHTMLAttribute(img, "title"),
+		Width:  getHTMLAttribute(img, "width"),
+		Height: getHTMLAttribute(img, "height"),
+	}
+	if linked := figureLinkedImage.FindString(fragment); linked != "" {
+		fig.Link = getHTMLAttribute(findHTMLTag(linked, "a"), "href")
+	}
+	return fig
+}
+
+// Helper function to replace image figures with their rendering in the
+// given format. Figures are matched innermost first, so gallery wrappers
+// are left in place around the rendered images.
+func (c *Converter) replaceFigures(content string, format OutputFormat, verbatim *verbatimStore) string {
+	if !strings.Contains(content, "</figure>") {
+		return content
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for {
+		end := strings.Index(content[pos:], "</figure>")
+		if end == -1 {
+			break
+		}
+		end += pos + len("</figure>")
+		start := strings.LastIndex(content[pos:end], "<figure")
+		if start == -1 {
+			sb.WriteString(content[pos:end])
+			pos = end
+			continue
+		}
+		start += pos
+
+		sb.WriteString(content[pos:start])
+		if fig, ok := parseFigure(content[start:end]); ok {
+			sb.WriteString("\n\n" + verbatim.protect(c.renderFigure(fig, format)) + "\n\n")
+		} else {
+			sb.WriteString(content[start:end])
+		}
+		pos = end
+	}
+	sb.WriteString(content[pos:])
+	return sb.String()
+}
+
+// Helper function to render a figure in the given output format
+func (c *Converter) renderFigure(fig figure, format OutputFormat) string {
+	switch format {
+	case FormatPlainText:
+		caption := strings.TrimSpace(html.UnescapeString(figureTagStrip.ReplaceAllString(fig.Caption, "")))
+		return html.UnescapeString(plainTextMediaReference("Image", mediaItem{
+			Src: fig.Src, Alt: fig.Alt, Title: fig.Title, Caption: caption,
+		}))
+	}
+
+	switch c.FigureFormat {
+	case FigureHugoShortcode:
+		return c.hugoFigure(fig)
+	case FigureHTML:
+		return figureHTML(fig)
+	}
+
+	image := "![" + escapeMarkdownText(fig.Alt) + "](" + fig.Src
+	if fig.Title != "" {
+		image += ` "` + strings.ReplaceAll(fig.Title, `"`, `\"`) + `"`
+	}
+	image += ")"
+	if fig.Link != "" {
+		image = "[" + image + "](" + fig.Link + ")"
+	}
+	if caption := strings.TrimSpace(c.HTMLToMarkdown(fig.Caption)); caption != "" {
+		image += "\n*" + caption + "*"
+	}
+	return image
+}
+
+// Helper function to write Hugo's figure shortcode
+func (c *Converter) hugoFigure(fig figure) string {
+	caption := strings.TrimSpace(c.HTMLToMarkdown(fig.Caption))
+	var sb strings.Builder
+	sb.WriteString("{{< figure")
+	for _, param := range [][2]string{
+		{"src", fig.Src}, {"alt", fig.Alt}, {"title", fig.Title}, {"caption", caption},
+		{"link", fig.Link}, {"width", fig.Width}, {"height", fig.Height},
+	} {
+		if param[1] == "" {
+			continue
+		}
+		value := strings.Join(strings.Fields(param[1]), " ")
+		sb.WriteString(" " + param[0] + `="` + strings.ReplaceAll(value, `"`, `\"`) + `"`)
+	}
+	sb.WriteString(" >}}")
+	return sb.String()
+}
+
+// Helper function to write a figure as clean HTML
+func figureHTML(fig figure) string {
+	img := `<img src="` + html.EscapeString(fig.Src) + `" alt="` + html.EscapeString(fig.Alt) + `"`
+	for _, attr := range [][2]string{{"title", fig.Title}, {"width", fig.Width}, {"height", fig.Height}} {
+		if attr[1] != "" {
+			img += " " + attr[0] + `="` + html.EscapeString(attr[1]) + `"`
+		}
+	}
+	img += ">"
+	if fig.Link != "" {
+		img = `<a href="` + html.EscapeString(fig.Link) + `">` + img + "</a>"
+	}
+	if fig.Caption == "" {
+		return "<figure>" + img + "</figure>"
+	}
+	return "<figure>" + img + "<figcaption>" + fig.Caption + "</figcaption></figure>"
+}
+
+// Helper function to escape brackets in Markdown link and image text
+func escapeMarkdownText(text string) string {
+	return strings.NewReplacer(`[`, `\[`, `]`, `\]`).Replace(text)
+}
+
+// Helper function to convert a bare <img> tag to a Markdown image
+func markdownImageTag(tag string) string {
+	fig := parseImage(tag)
+	image := "![" + html.EscapeString(escapeMarkdownText(fig.Alt)) + "](" + html.EscapeString(fig.Src)
+	if fig.Title != "" {
+		image += ` &quot;` + html.EscapeString(strings.ReplaceAll(fig.Title, `"`, `\"`)) + `&quot;`
+	}
+	return image + ")"
+}
@@ -0,0 +1,63 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// figureTestContent has a linked figure with attributes in an unusual order
+const figureTestContent = `<p>Intro</p>
+<figure class="wp-block-image size-large"><a href="https://example.com/full.jpg"><img width="600" height="400" title="Morning" alt="Don't stop" src="https://example.com/a.jpg" class="wp-image-9"/></a><figcaption>Photo by <a href="https://example.com/me">Me</a></figcaption></figure>
+<p>Outro <img alt="Icon" src="https://example.com/icon.png"></p>`
+
+// TestFigureConversion tests figures and captions in every output format
+func TestFigureConversion(t *testing.T) {
+	c := NewConverter()
+
+	markdown := c.ToMarkdown(figureTestContent)
+	for _, want := range []string{
+		"[![Don't stop](https://example.com/a.jpg \"Morning\")](https://example.com/full.jpg)\n*Photo by [Me](https://example.com/me)*",
+		"Outro ![Icon](https://example.com/icon.png)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected Markdown to contain %q, got:\n%s", want, markdown)
+		}
+	}
+
+	c.FigureFormat = FigureHugoShortcode
+	markdown = c.ToMarkdown(figureTestContent)
+	want := `{{< figure src="https://example.com/a.jpg" alt="Don't stop" title="Morning" caption="Photo by [Me](https://example.com/me)" link="https://example.com/full.jpg" width="600" height="400" >}}`
+	if !strings.Contains(markdown, want) {
+		t.Errorf("Expected Hugo figure shortcode, got:\n%s", markdown)
+	}
+
+	text := c.ToPlainText(figureTestContent)
+	if !strings.Contains(text, "Intro\n\n[Image: Don't stop — Photo by Me]\n\nOutro") {
+		t.Errorf("Unexpected figure plain text:\n%s", text)
+	}
+
+	cleaned := c.ToHTML(figureTestContent)
+	for _, want := range []string{
+		`<figure class="size-large"><a href="https://example.com/full.jpg"><img width="600" height="400" title="Morning"`,
+		`<figcaption>Photo by <a href="https://example.com/me">Me</a></figcaption></figure>`,
+	} {
+		if !strings.Contains(cleaned, want) {
+			t.Errorf("Expected HTML to contain %q, got:\n%s", want, cleaned)
+		}
+	}
+}
+
+// TestFigureFormatInBlocks tests that core/image blocks follow FigureFormat
+func TestFigureFormatInBlocks(t *testing.T) {
+	content := `<!-- wp:image {"id":5,"href":"https://example.com/big.jpg"} -->
+<figure class="wp-block-image"><img src="https://example.com/a.jpg" alt="A cat" width="300"/><figcaption>Sleepy</figcaption></figure>
+<!-- /wp:image -->`
+
+	c := NewConverter()
+	c.FigureFormat = FigureHTML
+	got := c.ToMarkdown(content)
+	want := `<figure><a href="https://example.com/big.jpg"><img src="https://example.com/a.jpg" alt="A cat" width="300"></a><figcaption>Sleepy</figcaption></figure>`
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,269 @@
+package wpimport
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrontMatterFormat is the format of the front matter at the top of
+// exported Markdown files
+type FrontMatterFormat int
+
+const (
+	// FrontMatterYAML writes YAML between "---" lines
+	FrontMatterYAML FrontMatterFormat = iota
+	// FrontMatterTOML writes TOML between "+++" lines
+	FrontMatterTOML
+	// FrontMatterJSON writes a JSON object, as Hugo reads it
+	FrontMatterJSON
+)
+
+// FrontMatterMapping changes the fields front matter is written with
+type FrontMatterMapping struct {
+	// Rename maps field keys to the keys to write them as, e.g.
+	// {"categories": "topics"}. Mapping a key to "-" leaves the field out.
+	Rename map[string]string
+
+	// Meta maps post meta keys to fields that get the meta's value, e.g.
+	// {"_yoast_wpseo_metadesc": "description"}. A field set from meta
+	// replaces one of the same key.
+	Meta map[string]string
+
+	// KeepEmpty writes fields with empty strings, lists and times instead
+	// of leaving them out
+	KeepEmpty bool
+}
+
+// FrontMatter builds front matter: an ordered set of fields written as
+// YAML, TOML or JSON. Values are strings, bools, ints, times and string
+// lists. Strings are always double-quoted, so titles with colons, quotes
+// or emoji stay intact, and times are written as RFC 3339 dates.
+type FrontMatter struct {
+	// Format is the format to write, YAML by default
+	Format FrontMatterFormat
+
+	// Mapping renames fields, adds fields from post meta and decides
+	// whether empty fields are kept
+	Mapping FrontMatterMapping
+
+	// Location is the time zone dates are written in. When nil, dates
+	// keep their own zone, which for item dates is the site's time zone
+	// when they were published.
+	Location *time.Location
+
+	fields []frontMatterField
+}
+
+// frontMatterField is a single key and value
+type frontMatterField struct {
+	key   string
+	value interface{}
+}
+
+// frontMatterBareKey matches keys that need no quoting in YAML or TOML
+var frontMatterBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewFrontMatter creates an empty front matter builder for a format
+func NewFrontMatter(format FrontMatterFormat) *FrontMatter {
+	return &FrontMatter{Format: format}
+}
+
+// Set adds a field under its mapped key. Empty strings, lists and times
+// are left out unless the mapping keeps them; bools are always written.
+// Setting a key again replaces its value in place.
+func (fm *FrontMatter) Set(key string, value interface{}) {
+	if renamed, ok := fm.Mapping.Rename[key]; ok {
+		key = renamed
+	}
+	if key == "-" || key == "" {
+		return
+	}
+	if !fm.Mapping.KeepEmpty && frontMatterEmpty(value) {
+		return
+	}
+	if t, ok := value.(time.Time); ok && fm.Location != nil && !t.IsZero() {
+		value = t.In(fm.Location)
+	}
+	fm.setRaw(key, value)
+}
+
+// SetItem adds the common fields of an item: title, date, lastmod (when it
+// differs from the date), draft, slug, author, categories, tags, one field
+// per custom taxonomy and description (the excerpt as plain text),
+// followed by the fields of Mapping.Meta
+func (fm *FrontMatter) SetItem(site *WordPressSite, item *Item) {
+	fm.Set("title", itemTitle(item))
+	date, _ := item.PublishedTime()
+	fm.Set("date", date)
+	if modified, err := item.ModifiedTime(); err == nil && !modified.Equal(date) {
+		fm.Set("lastmod", modified)
+	}
+	fm.Set("draft", isDraft(item))
+	fm.Set("slug", itemSlug(item))
+	fm.Set("author", itemAuthorName(site, item))
+	fm.Set("categories", itemTermNames(item, "category"))
+	fm.Set("tags", itemTermNames(item, "post_tag"))
+	for _, taxonomy := range itemCustomTaxonomies(item) {
+		fm.Set(taxonomy, itemTermNames(item, taxonomy))
+	}
+	if item.Excerpt != "" {
+		fm.Set("description", strings.TrimSpace(ConvertToPlainText(item.Excerpt)))
+	}
+	fm.SetMeta(item)
+}
+
+// SetMeta adds the fields of Mapping.Meta from the item's meta, in order
+// of the meta keys. The fields are named by Meta and not renamed again.
+func (fm *FrontMatter) SetMeta(item *Item) {
+	metaKeys := make([]string, 0, len(fm.Mapping.Meta))
+	for metaKey := range fm.Mapping.Meta {
+		metaKeys = append(metaKeys, metaKey)
+	}
+	sort.Strings(metaKeys)
+	for _, metaKey := range metaKeys {
+		key := fm.Mapping.Meta[metaKey]
+		value := strings.TrimSpace(item.GetMetaValue(metaKey))
+		if key == "" || key == "-" || (value == "" && !fm.Mapping.KeepEmpty) {
+			continue
+		}
+		fm.setRaw(key, value)
+	}
+}
+
+// Helper function to add or replace a field without mapping its key
+func (fm *FrontMatter) setRaw(key string, value interface{}) {
+	for i := range fm.fields {
+		if fm.fields[i].key == key {
+			fm.fields[i].value = value
+			return
+		}
+	}
+	fm.fields = append(fm.fields, frontMatterField{key: key, value: value})
+}
+
+// String writes the front matter with its delimiters. YAML, TOML and JSON
+// share the quoting of strings, lists and RFC 3339 dates, so only the key
+// separator and delimiters differ.
+func (fm *FrontMatter) String() string {
+	if fm.Format == FrontMatterJSON {
+		return fm.renderJSON()
+	}
+	delimiter, separator := "---", ": "
+	if fm.Format == FrontMatterTOML {
+		delimiter, separator = "+++", " = "
+	}
+	var sb strings.Builder
+	sb.WriteString(delimiter + "\n")
+	for _, field := range fm.fields {
+		key := field.key
+		if !frontMatterBareKey.MatchString(key) {
+			key = quoteFrontMatter(key)
+		}
+		sb.WriteString(key + separator + frontMatterValue(field.value) + "\n")
+	}
+	sb.WriteString(delimiter + "\n")
+	return sb.String()
+}
+
+// Helper function to write the fields as a JSON object. Dates are strings
+// in JSON.
+func (fm *FrontMatter) renderJSON() string {
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for i, field := range fm.fields {
+		value := field.value
+		if t, ok := value.(time.Time); ok {
+			value = frontMatterTime(t)
+		}
+		sb.WriteString("  " + quoteFrontMatter(field.key) + ": " + frontMatterValue(value))
+		if i < len(fm.fields)-1 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Helper function to tell whether a value counts as empty
+func frontMatterEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case time.Time:
+		return v.IsZero()
+	}
+	return false
+}
+
+// Helper function to write a time as RFC 3339, or "" for the zero time
+func frontMatterTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Helper function to write a front matter value
+func frontMatterValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return `""`
+	case string:
+		return quoteFrontMatter(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		if v.IsZero() {
+			return `""`
+		}
+		return frontMatterTime(v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = quoteFrontMatter(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	return quoteFrontMatter(fmt.Sprint(value))
+}
+
+// Helper function to quote a string so it is valid in YAML, TOML and
+// JSON: double quotes with backslash escapes, and \u escapes for control
+// characters
+func quoteFrontMatter(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f || r == 0x85 || r == 0x2028 || r == 0x2029 {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package wpimport
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFrontMatterQuoting tests that awkward strings survive quoting
+func TestFrontMatterQuoting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Plain", `"Plain"`},
+		{"Go: the good parts", `"Go: the good parts"`},
+		{`Say "hi" \ bye`, `"Say \"hi\" \\ bye"`},
+		{"Party 🎉 time", `"Party 🎉 time"`},
+		{"Line\nbreak\ttab", `"Line\nbreak\ttab"`},
+		{"Bell\x07", `"Bell\u0007"`},
+		{"- not a list", `"- not a list"`},
+	}
+	for _, tt := range tests {
+		got := quoteFrontMatter(tt.input)
+		if got != tt.expected {
+			t.Errorf("Expected %s for %q, got %s", tt.expected, tt.input, got)
+		}
+		// The quoted form is also a JSON string
+		var decoded string
+		if err := json.Unmarshal([]byte(got), &decoded); err != nil || decoded != tt.input {
+			t.Errorf("Expected %s to decode to %q, got %q (%v)", got, tt.input, decoded, err)
+		}
+	}
+}
+
+// TestFrontMatterSetItem tests the common fields of an item in each format
+func TestFrontMatterSetItem(t *testing.T) {
+	site := exportTestSite()
+	item := &site.Channel.Items[0]
+
+	fm := NewFrontMatter(FrontMatterYAML)
+	fm.SetItem(site, item)
+	expected := `---
+title: "Hello & \"World\""
+date: 2019-05-01T10:30:00+02:00
+lastmod: 2019-06-01T12:00:00+02:00
+draft: false
+slug: "hello-world"
+author: "Jane Doe"
+categories: ["News"]
+tags: ["Go", "WordPress"]
+genre: ["Sci-Fi"]
+description: "A short summary."
+---
+`
+	if got := fm.String(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	fm = NewFrontMatter(FrontMatterTOML)
+	fm.SetItem(site, item)
+	if got := fm.String(); !strings.HasPrefix(got, "+++\ntitle = \"Hello & \\\"World\\\"\"\ndate = 2019-05-01T10:30:00+02:00\n") {
+		t.Errorf("Unexpected TOML front matter:\n%s", got)
+	}
+
+	fm = NewFrontMatter(FrontMatterJSON)
+	fm.SetItem(site, item)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(fm.String()), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, fm.String())
+	}
+	if decoded["date"] != "2019-05-01T10:30:00+02:00" || decoded["draft"] != false || len(decoded["tags"].([]interface{})) != 2 {
+		t.Errorf("Unexpected JSON front matter: %v", decoded)
+	}
+}
+
+// TestFrontMatterMapping tests renaming, meta fields, empty values and the
+// time zone
+func TestFrontMatterMapping(t *testing.T) {
+	site := exportTestSite()
+	item := &site.Channel.Items[0]
+	item.PostMeta = append(item.PostMeta, PostMeta{Key: "_yoast_wpseo_metadesc", Value: "From: Yoast"})
+
+	fm := NewFrontMatter(FrontMatterYAML)
+	fm.Mapping = FrontMatterMapping{
+		Rename: map[string]string{"categories": "topics", "slug": "-"},
+		Meta:   map[string]string{"_yoast_wpseo_metadesc": "description", "_missing": "missing"},
+	}
+	fm.Location = time.UTC
+	fm.SetItem(site, item)
+	got := fm.String()
+	for _, want := range []string{"date: 2019-05-01T08:30:00Z\n", "topics: [\"News\"]\n", "description: \"From: Yoast\"\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"slug", "categories", "missing", "A short summary"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Expected no %q in:\n%s", unwanted, got)
+		}
+	}
+
+	// Empty values are kept on request
+	fm = NewFrontMatter(FrontMatterTOML)
+	fm.Mapping.KeepEmpty = true
+	fm.Set("summary", "")
+	fm.Set("tags", []string(nil))
+	fm.Set("date", time.Time{})
+	if got := fm.String(); got != "+++\nsummary = \"\"\ntags = []\ndate = \"\"\n+++\n" {
+		t.Errorf("Unexpected front matter with empty values:\n%s", got)
+	}
+
+	// Setting a key again replaces its value in place
+	fm = NewFrontMatter(FrontMatterYAML)
+	fm.Set("a", 1)
+	fm.Set("b", true)
+	fm.Set("a", 2)
+	if got := fm.String(); got != "---\na: 2\nb: true\n---\n" {
+		t.Errorf("Unexpected front matter:\n%s", got)
+	}
+}
+
+// TestHugoExportMapping tests front matter mapping in an exporter
+func TestHugoExportMapping(t *testing.T) {
+	site := exportTestSite()
+	site.Channel.Items[0].PostMeta = append(site.Channel.Items[0].PostMeta, PostMeta{Key: "_yoast_wpseo_metadesc", Value: "SEO text"})
+	dir := t.TempDir()
+	exporter := &HugoExporter{FrontMatter: FrontMatterJSON, Mapping: FrontMatterMapping{
+		Rename: map[string]string{"summary": "-"},
+		Meta:   map[string]string{"_yoast_wpseo_metadesc": "description"},
+	}}
+	if err := exporter.Export(site, dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	post := readExport(t, dir, "content/posts/hello-world/index.md")
+	if !strings.HasPrefix(post, "{\n  \"title\": ") || !strings.Contains(post, "  \"description\": \"SEO text\"\n}\n") || strings.Contains(post, "summary") {
+		t.Errorf("Unexpected JSON front matter:\n%s", post)
+	}
+}
@@ -0,0 +1,438 @@
+package wpimport
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlNode is an element, text or comment in a parsed HTML fragment
+type htmlNode struct {
+	// Tag is the lowercase element name, "" for text, "!--" for comments
+	// and "#fragment" for the root
+	Tag string
+
+	// Attrs holds the element's attributes with unescaped values
+	Attrs []htmlAttr
+
+	// Text is the raw (still escaped) text, or the comment body
+	Text string
+
+	Children []*htmlNode
+	Parent   *htmlNode
+
+	// Start and End are the byte range the node covers in the source
+	Start, End int
+}
+
+// htmlAttr is an attribute of an htmlNode
+type htmlAttr struct {
+	Name  string
+	Value string
+}
+
+// htmlVoidElements never have content or an end tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawTextElements hold text that is not parsed as HTML
+var htmlRawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// htmlClosesParagraph lists the elements whose start tag ends an open <p>
+var htmlClosesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true,
+	"dl": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "ul": true, "dd": true, "dt": true,
+}
+
+// htmlParser builds a tree from an HTML fragment. It is lenient like a
+// browser: stray end tags are ignored and the common implied end tags (p,
+// li, dt/dd, td/th, tr and table sections) are inserted.
+type htmlParser struct {
+	content string
+	stack   []*htmlNode
+}
+
+// Helper function to parse an HTML fragment into a tree
+func parseHTML(content string) *htmlNode {
+	root := &htmlNode{Tag: "#fragment", End: len(content)}
+	p := &htmlParser{content: content, stack: []*htmlNode{root}}
+
+	pos := 0
+	for pos < len(content) {
+		lt := strings.IndexByte(content[pos:], '<')
+		if lt == -1 {
+			p.addText(pos, len(content))
+			break
+		}
+		if lt > 0 {
+			p.addText(pos, pos+lt)
+		}
+		pos += lt
+		pos = p.parseMarkup(pos)
+	}
+	p.closeFrom(1, len(content))
+	return root
+}
+
+// Helper function to parse the markup starting with '<' at pos, returning
+// the position after it
+func (p *htmlParser) parseMarkup(pos int) int {
+	rest := p.content[pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		end := strings.Index(rest[4:], "-->")
+		if end == -1 {
+			p.append(&htmlNode{Tag: "!--", Text: rest[4:], Start: pos, End: len(p.content)})
+			return len(p.content)
+		}
+		p.append(&htmlNode{Tag: "!--", Text: rest[4 : 4+end], Start: pos, End: pos + 4 + end + 3})
+		return pos + 4 + end + 3
+	case strings.HasPrefix(rest, "</") && len(rest) > 2 && isASCIILetter(rest[2]):
+		end := strings.IndexByte(rest, '>')
+		if end == -1 {
+			p.addText(pos, len(p.content))
+			return len(p.content)
+		}
+		name := strings.ToLower(readTagName(rest[2:]))
+		p.closeTag(name, pos, pos+end+1)
+		return pos + end + 1
+	case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+		// Doctypes and processing instructions are dropped
+		end := strings.IndexByte(rest, '>')
+		if end == -1 {
+			return len(p.content)
+		}
+		return pos + end + 1
+	case len(rest) > 1 && isASCIILetter(rest[1]):
+		return p.parseStartTag(pos)
+	}
+	p.addText(pos, pos+1)
+	return pos + 1
+}
+
+// Helper function to parse a start tag and its attributes
+func (p *htmlParser) parseStartTag(pos int) int {
+	name := readTagName(p.content[pos+1:])
+	node := &htmlNode{Tag: strings.ToLower(name), Start: pos}
+	i := pos + 1 + len(name)
+	selfClosing := false
+attributes:
+	for i < len(p.content) {
+		c := p.content[i]
+		switch {
+		case c == '>':
+			i++
+			break attributes
+		case c == '/':
+			selfClosing = i+1 < len(p.content) && p.content[i+1] == '>'
+			i++
+			continue
+		case isHTMLSpace(c):
+			i++
+			continue
+		}
+
+		// Attribute name
+		nameStart := i
+		for i < len(p.content) && !isHTMLSpace(p.content[i]) && p.content[i] != '=' && p.content[i] != '>' && p.content[i] != '/' {
+			i++
+		}
+		if i == nameStart {
+			i++
+			continue
+		}
+		attr := htmlAttr{Name: strings.ToLower(p.content[nameStart:i])}
+		j := i
+		for j < len(p.content) && isHTMLSpace(p.content[j]) {
+			j++
+		}
+		if j < len(p.content) && p.content[j] == '=' {
+			j++
+			for j < len(p.content) && isHTMLSpace(p.content[j]) {
+				j++
+			}
+			if j < len(p.content) && (p.content[j] == '"' || p.content[j] == '\'') {
+				quote := p.content[j]
+				end := strings.IndexByte(p.content[j+1:], quote)
+				if end == -1 {
+					end = len(p.content) - j - 1
+				}
+				attr.Value = p.content[j+1 : j+1+end]
+				i = j + 1 + end + 1
+			} else {
+				valueStart := j
+				for j < len(p.content) && !isHTMLSpace(p.content[j]) && p.content[j] != '>' {
+					j++
+				}
+				attr.Value = p.content[valueStart:j]
+				i = j
+			}
+			attr.Value = html.UnescapeString(attr.Value)
+		}
+		node.Attrs = append(node.Attrs, attr)
+	}
+	if i > len(p.content) {
+		i = len(p.content)
+	}
+
+	p.closeImplied(node.Tag, pos)
+	p.append(node)
+	switch {
+	case htmlVoidElements[node.Tag] || selfClosing:
+		node.End = i
+	case htmlRawTextElements[node.Tag]:
+		end := strings.Index(strings.ToLower(p.content[i:]), "</"+node.Tag)
+		if end == -1 {
+			end = len(p.content) - i
+		}
+		if end > 0 {
+			node.Children = []*htmlNode{{Text: p.content[i : i+end], Parent: node, Start: i, End: i + end}}
+		}
+		i += end
+		if closeEnd := strings.IndexByte(p.content[i:], '>'); closeEnd != -1 {
+			i += closeEnd + 1
+		} else {
+			i = len(p.content)
+		}
+		node.End = i
+	default:
+		p.stack = append(p.stack, node)
+	}
+	return i
+}
+
+// Helper function to append a node to the current element
+func (p *htmlParser) append(node *htmlNode) {
+	parent := p.stack[len(p.stack)-1]
+	node.Parent = parent
+	parent.Children = append(parent.Children, node)
+}
+
+// Helper function to add source text, merging it with a preceding text node
+func (p *htmlParser) addText(start, end int) {
+	parent := p.stack[len(p.stack)-1]
+	if n := len(parent.Children); n > 0 && parent.Children[n-1].Tag == "" && parent.Children[n-1].End == start {
+		parent.Children[n-1].Text += p.content[start:end]
+		parent.Children[n-1].End = end
+		return
+	}
+	p.append(&htmlNode{Text: p.content[start:end], Start: start, End: end})
+}
+
+// Helper function to close the open elements from stack index i upwards
+func (p *htmlParser) closeFrom(i, end int) {
+	for j := len(p.stack) - 1; j >= i; j-- {
+		p.stack[j].End = end
+	}
+	p.stack = p.stack[:i]
+}
+
+// Helper function to find the innermost open element with one of the
+// given names, without looking past a boundary element. It returns -1 if
+// there is none.
+func (p *htmlParser) findOpen(names []string, boundaries []string) int {
+	for i := len(p.stack) - 1; i > 0; i-- {
+		tag := p.stack[i].Tag
+		for _, name := range names {
+			if tag == name {
+				return i
+			}
+		}
+		for _, boundary := range boundaries {
+			if tag == boundary {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// Helper function to close elements implicitly ended by a start tag
+func (p *htmlParser) closeImplied(tag string, pos int) {
+	var names, boundaries []string
+	switch tag {
+	case "li":
+		names, boundaries = []string{"li"}, []string{"ul", "ol", "table"}
+	case "dt", "dd":
+		names, boundaries = []string{"dt", "dd"}, []string{"dl", "table"}
+	case "td", "th":
+		names, boundaries = []string{"td", "th"}, []string{"tr", "table"}
+	case "tr":
+		names, boundaries = []string{"tr"}, []string{"thead", "tbody", "tfoot", "table"}
+	case "thead", "tbody", "tfoot":
+		names, boundaries = []string{"thead", "tbody", "tfoot"}, []string{"table"}
+	case "option":
+		names, boundaries = []string{"option"}, []string{"select"}
+	}
+	if names != nil {
+		if i := p.findOpen(names, boundaries); i != -1 {
+			p.closeFrom(i, pos)
+		}
+	}
+	if htmlClosesParagraph[tag] {
+		if i := p.findOpen([]string{"p"}, []string{"table", "td", "th", "caption", "button", "li", "dd", "dt", "blockquote", "div"}); i != -1 {
+			p.closeFrom(i, pos)
+		}
+	}
+}
+
+// Helper function to handle an end tag, ignoring it when the element is
+// not open
+func (p *htmlParser) closeTag(tag string, start, end int) {
+	var boundaries []string
+	switch tag {
+	case "td", "th", "tr", "thead", "tbody", "tfoot", "caption":
+		boundaries = []string{"table"}
+	case "li":
+		boundaries = []string{"ul", "ol"}
+	}
+	i := p.findOpen([]string{tag}, boundaries)
+	if i == -1 {
+		return
+	}
+	p.closeFrom(i+1, start)
+	p.stack[i].End = end
+	p.stack = p.stack[:i]
+}
+
+// Helper function to read a tag name at the start of s
+func readTagName(s string) string {
+	i := 0
+	for i < len(s) && (isASCIILetter(s[i]) || (s[i] >= '0' && s[i] <= '9') || s[i] == '-' || s[i] == ':' || s[i] == '_') {
+		i++
+	}
+	return s[:i]
+}
+
+// Helper function to check for an ASCII letter
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Helper function to check for HTML whitespace
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// Attr returns the value of an attribute, or "" if it is missing
+func (n *htmlNode) Attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// HasAttr reports whether the element has an attribute
+func (n *htmlNode) HasAttr(name string) bool {
+	for _, attr := range n.Attrs {
+		if attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Elements returns the element children of n
+func (n *htmlNode) Elements() []*htmlNode {
+	var elements []*htmlNode
+	for _, child := range n.Children {
+		if child.Tag != "" && child.Tag != "!--" {
+			elements = append(elements, child)
+		}
+	}
+	return elements
+}
+
+// Find returns all descendants with one of the given tags, in document
+// order, without descending into matches
+func (n *htmlNode) Find(tags ...string) []*htmlNode {
+	var found []*htmlNode
+	for _, child := range n.Children {
+		matched := false
+		for _, tag := range tags {
+			if child.Tag == tag {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			found = append(found, child)
+		} else {
+			found = append(found, child.Find(tags...)...)
+		}
+	}
+	return found
+}
+
+// OuterHTML serializes the node and its children
+func (n *htmlNode) OuterHTML() string {
+	var sb strings.Builder
+	n.writeHTML(&sb, nil)
+	return sb.String()
+}
+
+// InnerHTML serializes the node's children
+func (n *htmlNode) InnerHTML() string {
+	var sb strings.Builder
+	for _, child := range n.Children {
+		child.writeHTML(&sb, nil)
+	}
+	return sb.String()
+}
+
+// TextContent returns the unescaped text of the node and its descendants
+func (n *htmlNode) TextContent() string {
+	if n.Tag == "" {
+		return html.UnescapeString(n.Text)
+	}
+	var sb strings.Builder
+	for _, child := range n.Children {
+		if child.Tag != "!--" {
+			sb.WriteString(child.TextContent())
+		}
+	}
+	return sb.String()
+}
+
+// Helper function to serialize a node. keepAttr, when not nil, decides
+// which attributes are written.
+func (n *htmlNode) writeHTML(sb *strings.Builder, keepAttr func(tag string, attr htmlAttr) bool) {
+	switch n.Tag {
+	case "":
+		sb.WriteString(n.Text)
+		return
+	case "!--":
+		sb.WriteString("<!--" + n.Text + "-->")
+		return
+	case "#fragment":
+		for _, child := range n.Children {
+			child.writeHTML(sb, keepAttr)
+		}
+		return
+	}
+
+	sb.WriteString("<" + n.Tag)
+	for _, attr := range n.Attrs {
+		if keepAttr != nil && !keepAttr(n.Tag, attr) {
+			continue
+		}
+		sb.WriteString(" " + attr.Name)
+		if attr.Value != "" {
+			sb.WriteString(`="` + html.EscapeString(attr.Value) + `"`)
+		}
+	}
+	sb.WriteString(">")
+	if htmlVoidElements[n.Tag] {
+		return
+	}
+	for _, child := range n.Children {
+		child.writeHTML(sb, keepAttr)
+	}
+	sb.WriteString("</" + n.Tag + ">")
+}
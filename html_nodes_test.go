@@ -0,0 +1,41 @@
+package wpimport
+
+import "testing"
+
+// TestParseHTML tests the lenient HTML tree parser
+func TestParseHTML(t *testing.T) {
+	content := `<p>One<p>Two <img alt='a "b"' src=x.png /><ul><li>A<li>B</ul></div><table><tr><td>1<td>2<tr><td>3</table><!-- note -->`
+
+	root := parseHTML(content)
+	elements := root.Elements()
+	tags := ""
+	for _, el := range elements {
+		tags += el.Tag + " "
+	}
+	if tags != "p p ul table " {
+		t.Fatalf("Expected implied end tags to close p, got top-level %q", tags)
+	}
+
+	img := root.Find("img")[0]
+	if img.Attr("alt") != `a "b"` || img.Attr("src") != "x.png" {
+		t.Errorf("Unexpected img attributes: %+v", img.Attrs)
+	}
+
+	if items := elements[2].Find("li"); len(items) != 2 || items[1].TextContent() != "B" {
+		t.Errorf("Expected two list items, got %d", len(items))
+	}
+
+	rows := elements[3].Find("tr")
+	if len(rows) != 2 || len(rows[0].Elements()) != 2 {
+		t.Errorf("Expected two rows with implied cell ends, got %d", len(rows))
+	}
+
+	table := elements[3]
+	if got := content[table.Start:table.End]; got != "<table><tr><td>1<td>2<tr><td>3</table>" {
+		t.Errorf("Unexpected table source range %q", got)
+	}
+
+	if got := elements[0].OuterHTML(); got != "<p>One</p>" {
+		t.Errorf("Expected serialized paragraph, got %q", got)
+	}
+}
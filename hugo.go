@@ -0,0 +1,183 @@
+package wpimport
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HugoExporter writes a site as a Hugo content tree. Each item becomes a
+// page bundle, content/posts/<slug>/index.md for posts,
+// content/<page path>/index.md for pages (following their parents) and
+// content/<post type>/<slug>/index.md for custom post types. Pages with
+// child pages are branch bundles (_index.md).
+type HugoExporter struct {
+	// FrontMatter is the front matter format, YAML by default
+	FrontMatter FrontMatterFormat
+
+	// Mapping renames front matter fields, adds fields from post meta and
+	// keeps empty fields
+	Mapping FrontMatterMapping
+
+	// Converter converts the content to Markdown. When nil, a converter
+	// with the default settings for the site is used. Unless it has its
+	// own Links, links between exported items are rewritten to their new
+	// URLs.
+	Converter *Converter
+
+	// UploadsDir is a local copy of wp-content/uploads, such as the
+	// uploads directory of a MediaDownloader. When set, the files an item
+	// uses are copied into its bundle and linked by name. Files that are
+	// not there keep their URL.
+	UploadsDir string
+
+	// Drafts exports draft, pending and private items with draft set to
+	// true
+	Drafts bool
+
+	// Redirects, when set, lists every old path redirected to an item as
+	// its aliases, such as permalinks of old slugs. Otherwise the old
+	// permalink is the only alias.
+	Redirects *RedirectMap
+
+	// Taxonomies maps WordPress taxonomies to Hugo taxonomy names.
+	// "category" and "post_tag" map to "categories" and "tags" unless set
+	// here; other taxonomies keep their name.
+	Taxonomies map[string]string
+}
+
+// NewHugoExporter creates a Hugo exporter writing YAML front matter
+func NewHugoExporter() *HugoExporter {
+	return &HugoExporter{}
+}
+
+// Export writes the site's content tree under dir, creating dir/content
+func (e *HugoExporter) Export(site *WordPressSite, dir string) error {
+	entries := e.entries(site)
+	base := exportConverter(site, e.Converter, entries)
+	media := NewMediaMapper(site, nil)
+
+	for _, entry := range entries {
+		converter := base.ForItem(entry.item)
+		var bundle *exportBundle
+		if e.UploadsDir != "" {
+			bundle = newExportBundle(e.UploadsDir)
+			converter.Media = media.withNewURL(bundle.add)
+		}
+
+		content := strings.TrimSpace(converter.ToMarkdown(entry.item.Content))
+		fm := e.frontMatter(site, entry, media, bundle)
+		target := filepath.Join(dir, filepath.FromSlash(entry.file))
+		if err := writeExportFile(target, fm.String()+"\n"+content+"\n"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.file, err)
+		}
+		if bundle != nil {
+			if err := bundle.copyTo(filepath.Dir(target)); err != nil {
+				return fmt.Errorf("failed to copy media for %s: %w", entry.file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Helper function to place the exported items. Items that would share a
+// bundle get their ID appended.
+func (e *HugoExporter) entries(site *WordPressSite) []exportEntry {
+	tree := site.PageTree()
+	taken := make(map[string]bool)
+	var entries []exportEntry
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if !exportable(item, e.Drafts) {
+			continue
+		}
+		bundle := tree.Path(item)
+		switch item.PostType {
+		case "page":
+		case "post":
+			bundle = "posts/" + bundle
+		default:
+			bundle = item.PostType + "/" + bundle
+		}
+		if taken[bundle] {
+			bundle += "-" + strconv.Itoa(item.PostID)
+		}
+		taken[bundle] = true
+
+		file := "index.md"
+		for _, child := range tree.Children(item) {
+			if exportable(child, e.Drafts) {
+				file = "_index.md"
+				break
+			}
+		}
+		entries = append(entries, exportEntry{
+			item: item,
+			file: "content/" + bundle + "/" + file,
+			url:  "/" + bundle + "/",
+		})
+	}
+	return entries
+}
+
+// Helper function to get the old paths of an item that differ from its
+// new one
+func (e *HugoExporter) aliases(entry exportEntry) []string {
+	old := []string{permalinkPath(entry.item)}
+	if e.Redirects != nil {
+		old = e.Redirects.Aliases(entry.item)
+	}
+	var aliases []string
+	for _, path := range old {
+		if path != "" && path != entry.url {
+			aliases = append(aliases, path)
+		}
+	}
+	return aliases
+}
+
+// Helper function to build the front matter of an item: the common
+// fields with Hugo's taxonomy names and summary, then aliases and the
+// featured image
+func (e *HugoExporter) frontMatter(site *WordPressSite, entry exportEntry, media *MediaMapper, bundle *exportBundle) *FrontMatter {
+	item := entry.item
+	renames := map[string]string{
+		"description": "summary",
+		"categories":  e.taxonomy("category"),
+		"tags":        e.taxonomy("post_tag"),
+	}
+	for taxonomy, name := range e.Taxonomies {
+		if taxonomy != "category" && taxonomy != "post_tag" {
+			renames[taxonomy] = name
+		}
+	}
+	fm := &FrontMatter{Format: e.FrontMatter, Mapping: exportMapping(e.Mapping, renames)}
+	exportSetItem(fm, site, item)
+	fm.Set("aliases", e.aliases(entry))
+	if image := featuredImage(site, item); image != nil {
+		src := image.GetAttachmentURL()
+		if file, ok := media.Match(src); ok && bundle != nil {
+			if name := bundle.add(file); name != "" {
+				src = name
+			}
+		}
+		fm.Set("featured_image", src)
+	}
+	fm.SetMeta(item)
+	return fm
+}
+
+// Helper function to get the Hugo name of a WordPress taxonomy
+func (e *HugoExporter) taxonomy(name string) string {
+	if mapped, ok := e.Taxonomies[name]; ok {
+		return mapped
+	}
+	switch name {
+	case "category":
+		return "categories"
+	case "post_tag":
+		return "tags"
+	}
+	return name
+}
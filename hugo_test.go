@@ -0,0 +1,135 @@
+package wpimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a site for the exporters
+func exportTestSite() *WordPressSite {
+	site := &WordPressSite{}
+	site.Channel.BaseSiteURL = "https://oldsite.com"
+	site.Channel.Authors = []Author{{ID: 1, Login: "jane", DisplayName: "Jane Doe"}}
+	site.Channel.Items = []Item{
+		{PostID: 10, PostType: "post", Status: "publish", PostName: "hello-world", Title: "Hello &amp; \"World\"",
+			Link: "https://oldsite.com/2019/05/hello-world/", Creator: "jane",
+			PostDate: "2019-05-01 10:30:00", PostDateGMT: "2019-05-01 08:30:00",
+			PostModified: "2019-06-01 12:00:00", PostModifiedGMT: "2019-06-01 10:00:00",
+			Excerpt: "A short <em>summary</em>.",
+			Content: `<p>See <a href="https://oldsite.com/about/team/">the team</a>.</p>` +
+				`<p><img src="https://oldsite.com/wp-content/uploads/2019/05/photo-300x200.jpg" alt="Photo"></p>`,
+			Categories: []ItemCategory{
+				{Domain: "category", NiceName: "news", Name: "News"},
+				{Domain: "post_tag", NiceName: "go", Name: "Go"},
+				{Domain: "post_tag", NiceName: "wp", Name: "WordPress"},
+				{Domain: "genre", NiceName: "sci-fi", Name: "Sci-Fi"},
+			},
+			PostMeta: []PostMeta{{Key: "_thumbnail_id", Value: "30"}}},
+		{PostID: 11, PostType: "post", Status: "draft", Title: "Work in progress", Link: "https://oldsite.com/?p=11",
+			PostDate: "2020-01-01 00:00:00", PostDateGMT: "0000-00-00 00:00:00", Content: "Not yet."},
+		{PostID: 20, PostType: "page", Status: "publish", PostName: "about", Title: "About",
+			Link: "https://oldsite.com/about/", PostDate: "2019-01-01 00:00:00", Content: "About us."},
+		{PostID: 21, PostType: "page", Status: "publish", PostName: "team", Title: "Team", PostParent: 20,
+			Link: "https://oldsite.com/about/team/", PostDate: "2019-01-02 00:00:00", Content: "The team."},
+		{PostID: 22, PostType: "book", Status: "publish", PostName: "dune", Title: "Dune",
+			Link: "https://oldsite.com/book/dune/", PostDate: "2019-02-01 00:00:00", Content: "A book.",
+			Categories: []ItemCategory{{Domain: "genre", NiceName: "sci-fi", Name: "Sci-Fi"}}},
+		{PostID: 23, PostType: "post", Status: "trash", PostName: "gone", Title: "Gone"},
+		{PostID: 30, PostType: "attachment", PostName: "cover", AttachmentURL: "https://oldsite.com/wp-content/uploads/2019/05/cover.jpg"},
+		{PostID: 31, PostType: "attachment", PostName: "photo", AttachmentURL: "https://oldsite.com/wp-content/uploads/2019/05/photo.jpg"},
+	}
+	return site
+}
+
+// Helper function to create an uploads directory with the test site's files
+func exportTestUploads(t *testing.T) string {
+	uploads := t.TempDir()
+	os.MkdirAll(filepath.Join(uploads, "2019", "05"), 0o755)
+	os.WriteFile(filepath.Join(uploads, "2019", "05", "photo.jpg"), []byte("photo"), 0o644)
+	os.WriteFile(filepath.Join(uploads, "2019", "05", "cover.jpg"), []byte("cover"), 0o644)
+	return uploads
+}
+
+// Helper function to read an exported file
+func readExport(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", name, err)
+	}
+	return string(data)
+}
+
+// TestHugoExport tests the content tree and front matter of a Hugo export
+func TestHugoExport(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewHugoExporter()
+	exporter.UploadsDir = exportTestUploads(t)
+	if err := exporter.Export(exportTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	expected := `---
+title: "Hello & \"World\""
+date: 2019-05-01T10:30:00+02:00
+lastmod: 2019-06-01T12:00:00+02:00
+draft: false
+slug: "hello-world"
+author: "Jane Doe"
+categories: ["News"]
+tags: ["Go", "WordPress"]
+genre: ["Sci-Fi"]
+summary: "A short summary."
+aliases: ["/2019/05/hello-world/"]
+featured_image: "cover.jpg"
+---
+
+See [the team](/about/team/).
+
+![Photo](photo.jpg)
+`
+	if got := readExport(t, dir, "content/posts/hello-world/index.md"); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+	for _, name := range []string{"content/posts/hello-world/photo.jpg", "content/posts/hello-world/cover.jpg"} {
+		readExport(t, dir, name)
+	}
+
+	// Pages follow their parents; a page with children is a branch bundle
+	readExport(t, dir, "content/about/_index.md")
+	readExport(t, dir, "content/about/team/index.md")
+	if book := readExport(t, dir, "content/book/dune/index.md"); strings.Contains(book, "aliases") {
+		t.Errorf("Expected no alias when the URL is unchanged, got %s", book)
+	}
+
+	for _, name := range []string{"content/posts/work-in-progress/index.md", "content/posts/gone/index.md"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("Expected %s not to be exported", name)
+		}
+	}
+}
+
+// TestHugoExportTOML tests TOML front matter and drafts
+func TestHugoExportTOML(t *testing.T) {
+	dir := t.TempDir()
+	exporter := &HugoExporter{FrontMatter: FrontMatterTOML, Drafts: true, Taxonomies: map[string]string{"genre": "genres"}}
+	if err := exporter.Export(exportTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	post := readExport(t, dir, "content/posts/hello-world/index.md")
+	for _, want := range []string{"+++\ntitle = \"Hello & \\\"World\\\"\"\n", "genres = [\"Sci-Fi\"]\n",
+		"featured_image = \"https://oldsite.com/wp-content/uploads/2019/05/cover.jpg\"\n",
+		"![Photo](https://oldsite.com/wp-content/uploads/2019/05/photo-300x200.jpg)"} {
+		if !strings.Contains(post, want) {
+			t.Errorf("Expected the post to contain %q, got:\n%s", want, post)
+		}
+	}
+
+	draft := readExport(t, dir, "content/posts/work-in-progress/index.md")
+	if !strings.Contains(draft, "draft = true\n") || strings.Contains(draft, "aliases") {
+		t.Errorf("Expected a draft without aliases, got:\n%s", draft)
+	}
+}
@@ -0,0 +1,155 @@
+package wpimport
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JekyllExporter writes a site as a Jekyll source tree. Posts go to
+// _posts/YYYY-MM-DD-slug.md, drafts to _drafts/slug.md, pages to their
+// path (e.g. about/team.md) and custom post types to a collection,
+// _<type>/slug.md, which is declared in a generated _config.yml. Every
+// item keeps its old URL through a permalink in its front matter.
+type JekyllExporter struct {
+	// Converter converts the content to Markdown. When nil, a converter
+	// with the default settings for the site is used. Unless it has its
+	// own Links, links between exported items are made site-relative.
+	Converter *Converter
+
+	// UploadsDir is a local copy of wp-content/uploads, such as the
+	// uploads directory of a MediaDownloader. When set, the files the
+	// items use are copied to uploads/ in the site and linked there. Files
+	// that are not there keep their URL.
+	UploadsDir string
+
+	// Drafts exports draft, pending and private items: posts to _drafts,
+	// other items with published set to false
+	Drafts bool
+
+	// Mapping renames front matter fields, adds fields from post meta and
+	// keeps empty fields
+	Mapping FrontMatterMapping
+}
+
+// NewJekyllExporter creates a Jekyll exporter
+func NewJekyllExporter() *JekyllExporter {
+	return &JekyllExporter{}
+}
+
+// Export writes the site's source tree under dir
+func (e *JekyllExporter) Export(site *WordPressSite, dir string) error {
+	entries := e.entries(site)
+	converter := exportConverter(site, e.Converter, entries)
+	var uploads *exportBundle
+	if e.UploadsDir != "" {
+		uploads = newExportBundle(e.UploadsDir)
+		uploads.keepPaths = true
+		converter.Media = NewMediaMapper(site, func(file MediaFile) string {
+			if name := uploads.add(file); name != "" {
+				return "/" + name
+			}
+			return ""
+		})
+	}
+
+	var collections []string
+	for _, entry := range entries {
+		content := strings.TrimSpace(converter.ForItem(entry.item).ToMarkdown(entry.item.Content))
+		fm := e.frontMatter(site, entry, converter.Media)
+		if err := writeExportFile(filepath.Join(dir, filepath.FromSlash(entry.file)), fm.String()+"\n"+content+"\n"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.file, err)
+		}
+		if t := entry.item.PostType; t != "post" && t != "page" {
+			collections = append(collections, t)
+		}
+	}
+
+	if collections = uniqueStrings(collections); len(collections) > 0 {
+		sort.Strings(collections)
+		if err := writeExportFile(filepath.Join(dir, "_config.yml"), jekyllConfig(collections)); err != nil {
+			return fmt.Errorf("failed to write _config.yml: %w", err)
+		}
+	}
+	if uploads != nil {
+		if err := uploads.copyTo(dir); err != nil {
+			return fmt.Errorf("failed to copy media: %w", err)
+		}
+	}
+	return nil
+}
+
+// Helper function to place the exported items. Items that would share a
+// file get their ID appended.
+func (e *JekyllExporter) entries(site *WordPressSite) []exportEntry {
+	tree := site.PageTree()
+	taken := make(map[string]bool)
+	var entries []exportEntry
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if !exportable(item, e.Drafts) {
+			continue
+		}
+		var name string
+		switch {
+		case item.PostType == "post" && isDraft(item):
+			name = "_drafts/" + itemSlug(item)
+		case item.PostType == "post":
+			date, _ := item.PublishedTime()
+			name = "_posts/" + date.Format("2006-01-02") + "-" + itemSlug(item)
+		case item.PostType == "page":
+			name = tree.Path(item)
+		default:
+			name = "_" + item.PostType + "/" + tree.Path(item)
+		}
+		if taken[name] {
+			name += "-" + strconv.Itoa(item.PostID)
+		}
+		taken[name] = true
+
+		url := ""
+		if !isDraft(item) {
+			url = permalinkPath(item)
+		}
+		entries = append(entries, exportEntry{item: item, file: name + ".md", url: url})
+	}
+	return entries
+}
+
+// Helper function to build the front matter of an item: the common
+// fields with Jekyll's names, without draft and slug, which Jekyll takes
+// from the file, then published, permalink and the featured image, which
+// is mapped like the content's media
+func (e *JekyllExporter) frontMatter(site *WordPressSite, entry exportEntry, media *MediaMapper) *FrontMatter {
+	item := entry.item
+	fm := &FrontMatter{Mapping: exportMapping(e.Mapping, map[string]string{
+		"lastmod": "last_modified_at", "description": "excerpt", "draft": "-", "slug": "-",
+	})}
+	switch item.PostType {
+	case "post", "page":
+		fm.Set("layout", item.PostType)
+	}
+	exportSetItem(fm, site, item)
+	if isDraft(item) && item.PostType != "post" {
+		fm.Set("published", false)
+	}
+	fm.Set("permalink", entry.url)
+	fm.Set("image", exportFeaturedImage(site, item, media))
+	fm.SetMeta(item)
+	return fm
+}
+
+// Helper function to write the collections block of _config.yml
+func jekyllConfig(collections []string) string {
+	var sb strings.Builder
+	sb.WriteString("collections:\n")
+	for _, collection := range collections {
+		if !frontMatterBareKey.MatchString(collection) {
+			collection = quoteFrontMatter(collection)
+		}
+		sb.WriteString("  " + collection + ":\n    output: true\n")
+	}
+	return sb.String()
+}
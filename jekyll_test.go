@@ -0,0 +1,78 @@
+package wpimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestJekyllExport tests file names, front matter and collections of a
+// Jekyll export
+func TestJekyllExport(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewJekyllExporter()
+	exporter.UploadsDir = exportTestUploads(t)
+	if err := exporter.Export(exportTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	expected := `---
+layout: "post"
+title: "Hello & \"World\""
+date: 2019-05-01T10:30:00+02:00
+last_modified_at: 2019-06-01T12:00:00+02:00
+author: "Jane Doe"
+categories: ["News"]
+tags: ["Go", "WordPress"]
+genre: ["Sci-Fi"]
+excerpt: "A short summary."
+permalink: "/2019/05/hello-world/"
+image: "/uploads/2019/05/cover.jpg"
+---
+
+See [the team](/about/team/).
+
+![Photo](/uploads/2019/05/photo.jpg)
+`
+	if got := readExport(t, dir, "_posts/2019-05-01-hello-world.md"); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+	readExport(t, dir, "uploads/2019/05/photo.jpg")
+	readExport(t, dir, "uploads/2019/05/cover.jpg")
+
+	if page := readExport(t, dir, "about/team.md"); !strings.Contains(page, "layout: \"page\"\n") || !strings.Contains(page, "permalink: \"/about/team/\"\n") {
+		t.Errorf("Unexpected page front matter:\n%s", page)
+	}
+	if book := readExport(t, dir, "_book/dune.md"); strings.Contains(book, "layout") || !strings.Contains(book, "genre: [\"Sci-Fi\"]\n") {
+		t.Errorf("Unexpected collection document:\n%s", book)
+	}
+	if config := readExport(t, dir, "_config.yml"); config != "collections:\n  book:\n    output: true\n" {
+		t.Errorf("Unexpected _config.yml:\n%s", config)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "_drafts")); err == nil {
+		t.Error("Expected no drafts without Drafts set")
+	}
+}
+
+// TestJekyllExportDrafts tests exporting drafts
+func TestJekyllExportDrafts(t *testing.T) {
+	site := exportTestSite()
+	site.Channel.Items = append(site.Channel.Items, Item{PostID: 40, PostType: "page", Status: "private", PostName: "secret",
+		Title: "Secret", Link: "https://oldsite.com/secret/", PostDate: "2020-02-02 00:00:00"})
+	dir := t.TempDir()
+	if err := (&JekyllExporter{Drafts: true}).Export(site, dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	draft := readExport(t, dir, "_drafts/work-in-progress.md")
+	if strings.Contains(draft, "permalink") || !strings.Contains(draft, "Not yet.") {
+		t.Errorf("Unexpected draft:\n%s", draft)
+	}
+	if page := readExport(t, dir, "secret.md"); !strings.Contains(page, "published: false\n") {
+		t.Errorf("Expected an unpublished page, got:\n%s", page)
+	}
+	if post := readExport(t, dir, "_posts/2019-05-01-hello-world.md"); !strings.Contains(post, "![Photo](https://oldsite.com/wp-content/uploads/2019/05/photo-300x200.jpg)") {
+		t.Errorf("Expected media to keep its URL without UploadsDir, got:\n%s", post)
+	}
+}
@@ -0,0 +1,427 @@
+package wpimport
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// JSONSchema is the JSON Schema (draft 2020-12) of the document ExportJSON
+// writes. Its $defs/item also describes each line of ExportNDJSON.
+//
+//go:embed schema/wp-import.schema.json
+var JSONSchema []byte
+
+// JSONOptions controls what ExportJSON and ExportNDJSON include
+type JSONOptions struct {
+	// HTML, Markdown and Text add the content converted to that format
+	// next to the raw content
+	HTML     bool
+	Markdown bool
+	Text     bool
+
+	// Converter converts the content. When nil, a converter with the
+	// default settings for the site is used.
+	Converter *Converter
+
+	// Types limits the items to these post types; all items when empty
+	Types []string
+
+	// OmitPersonal leaves out e-mail and IP addresses of authors and
+	// commenters
+	OmitPersonal bool
+
+	// Indent indents the ExportJSON document with this string, such as
+	// "  ". NDJSON lines are never indented.
+	Indent string
+}
+
+// JSONSite is the normalized site ExportJSON writes
+type JSONSite struct {
+	Title       string `json:"title"`
+	Link        string `json:"link"`
+	Description string `json:"description,omitempty"`
+	Language    string `json:"language,omitempty"`
+	BaseSiteURL string `json:"base_site_url,omitempty"`
+	BaseBlogURL string `json:"base_blog_url,omitempty"`
+	WXRVersion  string `json:"wxr_version,omitempty"`
+	Generator   string `json:"generator,omitempty"`
+
+	Authors []JSONAuthor `json:"authors"`
+	Terms   []JSONTerm   `json:"terms"`
+	Items   []JSONItem   `json:"items"`
+}
+
+// JSONAuthor is a user
+type JSONAuthor struct {
+	ID          int    `json:"id,omitempty"`
+	Login       string `json:"login"`
+	Email       string `json:"email,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	FirstName   string `json:"first_name,omitempty"`
+	LastName    string `json:"last_name,omitempty"`
+}
+
+// JSONTerm is a category, tag or term of a custom taxonomy
+type JSONTerm struct {
+	ID          int    `json:"id,omitempty"`
+	Taxonomy    string `json:"taxonomy"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// Parent is the slug of the parent term
+	Parent string `json:"parent,omitempty"`
+}
+
+// JSONMeta is a post or comment meta entry. Value holds the decoded value
+// of PHP serialized meta, so it can be any JSON value.
+type JSONMeta struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// JSONContent is an item's content, raw as exported and optionally
+// converted
+type JSONContent struct {
+	Raw      string `json:"raw"`
+	HTML     string `json:"html,omitempty"`
+	Markdown string `json:"markdown,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// JSONComment is a comment with its replies
+type JSONComment struct {
+	ID          int           `json:"id"`
+	Author      string        `json:"author"`
+	AuthorEmail string        `json:"author_email,omitempty"`
+	AuthorURL   string        `json:"author_url,omitempty"`
+	AuthorIP    string        `json:"author_ip,omitempty"`
+	UserID      int           `json:"user_id,omitempty"`
+	Date        *time.Time    `json:"date,omitempty"`
+	Content     string        `json:"content"`
+	Status      string        `json:"status"`
+	Type        string        `json:"type,omitempty"`
+	Meta        []JSONMeta    `json:"meta,omitempty"`
+	Replies     []JSONComment `json:"replies,omitempty"`
+}
+
+// JSONItem is a post, page, attachment or other item
+type JSONItem struct {
+	ID            int           `json:"id"`
+	Type          string        `json:"type"`
+	Status        string        `json:"status"`
+	Title         string        `json:"title"`
+	Slug          string        `json:"slug,omitempty"`
+	Link          string        `json:"link,omitempty"`
+	GUID          string        `json:"guid,omitempty"`
+	Author        *JSONAuthor   `json:"author,omitempty"`
+	Date          *time.Time    `json:"date,omitempty"`
+	Modified      *time.Time    `json:"modified,omitempty"`
+	Parent        int           `json:"parent,omitempty"`
+	MenuOrder     int           `json:"menu_order,omitempty"`
+	Password      string        `json:"password,omitempty"`
+	Sticky        bool          `json:"sticky,omitempty"`
+	Excerpt       string        `json:"excerpt,omitempty"`
+	Content       JSONContent   `json:"content"`
+	AttachmentURL string        `json:"attachment_url,omitempty"`
+	Terms         []JSONTerm    `json:"terms,omitempty"`
+	Meta          []JSONMeta    `json:"meta,omitempty"`
+	Comments      []JSONComment `json:"comments,omitempty"`
+}
+
+// ExportJSON writes the site as one normalized JSON document: authors
+// resolved from logins, term objects instead of the export's category
+// elements, decoded PHP serialized meta, parsed dates and comments
+// threaded by their parents
+func ExportJSON(w io.Writer, site *WordPressSite, opts JSONOptions) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", opts.Indent)
+	return encoder.Encode(NormalizeSite(site, opts))
+}
+
+// ExportNDJSON writes the site's items as newline-delimited JSON, one
+// normalized item per line, without building the whole document in memory
+func ExportNDJSON(w io.Writer, site *WordPressSite, opts JSONOptions) error {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetEscapeHTML(false)
+	n := newJSONNormalizer(site, opts)
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if !n.included(item) {
+			continue
+		}
+		if err := encoder.Encode(n.item(item)); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// NormalizeSite builds the document ExportJSON writes
+func NormalizeSite(site *WordPressSite, opts JSONOptions) *JSONSite {
+	n := newJSONNormalizer(site, opts)
+	channel := &site.Channel
+	doc := &JSONSite{
+		Title:       html.UnescapeString(channel.Title),
+		Link:        channel.Link,
+		Description: html.UnescapeString(channel.Description),
+		Language:    channel.Language,
+		BaseSiteURL: channel.BaseSiteURL,
+		BaseBlogURL: channel.BaseBlogURL,
+		WXRVersion:  channel.WXRVersion,
+		Generator:   channel.Generator,
+		Authors:     []JSONAuthor{},
+		Terms:       n.terms.list,
+		Items:       []JSONItem{},
+	}
+	for _, author := range channel.Authors {
+		doc.Authors = append(doc.Authors, n.author(author))
+	}
+	for i := range channel.Items {
+		if item := &channel.Items[i]; n.included(item) {
+			doc.Items = append(doc.Items, n.item(item))
+		}
+	}
+	return doc
+}
+
+// jsonNormalizer holds the indexes used to normalize items
+type jsonNormalizer struct {
+	opts      JSONOptions
+	converter *Converter
+	types     map[string]bool
+	authors   map[string]JSONAuthor
+	terms     *siteTerms
+}
+
+// Helper function to index the site's authors and terms
+func newJSONNormalizer(site *WordPressSite, opts JSONOptions) *jsonNormalizer {
+	n := &jsonNormalizer{
+		opts:      opts,
+		converter: opts.Converter,
+		authors:   make(map[string]JSONAuthor),
+		terms:     newSiteTerms(site),
+	}
+	if n.converter == nil && (opts.HTML || opts.Markdown || opts.Text) {
+		n.converter = NewSiteConverter(site)
+	}
+	if len(opts.Types) > 0 {
+		n.types = make(map[string]bool)
+		for _, t := range opts.Types {
+			n.types[t] = true
+		}
+	}
+	for _, author := range site.Channel.Authors {
+		n.authors[author.Login] = n.author(author)
+	}
+	return n
+}
+
+// siteTerms holds the terms of a site by taxonomy and slug, in the order
+// they were added
+type siteTerms struct {
+	list  []JSONTerm
+	index map[string]int
+}
+
+// Helper function to collect the categories, tags and terms the export
+// declares
+func newSiteTerms(site *WordPressSite) *siteTerms {
+	terms := &siteTerms{list: []JSONTerm{}, index: make(map[string]int)}
+	for _, c := range site.Channel.Categories {
+		terms.add(JSONTerm{ID: c.TermID, Taxonomy: "category", Slug: c.NiceName, Name: c.Name, Parent: c.Parent})
+	}
+	for _, t := range site.Channel.Tags {
+		terms.add(JSONTerm{ID: t.TermID, Taxonomy: "post_tag", Slug: t.Slug, Name: t.Name})
+	}
+	for _, t := range site.Channel.Terms {
+		terms.add(JSONTerm{ID: t.TermID, Taxonomy: t.Taxonomy, Slug: t.Slug, Name: t.Name, Description: t.Description, Parent: t.Parent})
+	}
+	return terms
+}
+
+// Helper function to add a term unless one with the same taxonomy and slug
+// is known, returning the known or added term
+func (t *siteTerms) add(term JSONTerm) JSONTerm {
+	key := term.Taxonomy + "\x00" + term.Slug
+	if i, ok := t.index[key]; ok {
+		return t.list[i]
+	}
+	term.Name = strings.TrimSpace(html.UnescapeString(term.Name))
+	term.Description = html.UnescapeString(term.Description)
+	t.index[key] = len(t.list)
+	t.list = append(t.list, term)
+	return term
+}
+
+// Helper function to get the term an item's category element refers to.
+// Terms the export does not declare are built from the element alone.
+func (t *siteTerms) find(category ItemCategory) JSONTerm {
+	if i, ok := t.index[category.Domain+"\x00"+category.NiceName]; ok {
+		return t.list[i]
+	}
+	return JSONTerm{
+		Taxonomy: category.Domain,
+		Slug:     category.NiceName,
+		Name:     strings.TrimSpace(html.UnescapeString(category.Name)),
+	}
+}
+
+// Helper function to tell whether an item is exported
+func (n *jsonNormalizer) included(item *Item) bool {
+	return n.types == nil || n.types[item.PostType]
+}
+
+// Helper function to normalize an author
+func (n *jsonNormalizer) author(author Author) JSONAuthor {
+	normalized := JSONAuthor{
+		ID:          author.ID,
+		Login:       author.Login,
+		Email:       author.Email,
+		DisplayName: html.UnescapeString(author.DisplayName),
+		FirstName:   html.UnescapeString(author.FirstName),
+		LastName:    html.UnescapeString(author.LastName),
+	}
+	if n.opts.OmitPersonal {
+		normalized.Email = ""
+	}
+	return normalized
+}
+
+// Helper function to normalize an item
+func (n *jsonNormalizer) item(item *Item) JSONItem {
+	normalized := JSONItem{
+		ID:            item.PostID,
+		Type:          item.PostType,
+		Status:        item.Status,
+		Title:         html.UnescapeString(item.Title),
+		Slug:          item.PostName,
+		Link:          item.Link,
+		GUID:          item.GUID,
+		Parent:        item.PostParent,
+		MenuOrder:     item.MenuOrder,
+		Password:      item.PostPassword,
+		Sticky:        item.IsSticky != 0,
+		Excerpt:       item.Excerpt,
+		Content:       JSONContent{Raw: item.Content},
+		AttachmentURL: item.AttachmentURL,
+	}
+	if item.Creator != "" {
+		author, ok := n.authors[item.Creator]
+		if !ok {
+			author = JSONAuthor{Login: item.Creator}
+		}
+		normalized.Author = &author
+	}
+	if date, err := item.PublishedTime(); err == nil {
+		normalized.Date = &date
+	}
+	if modified, err := item.ModifiedTime(); err == nil {
+		normalized.Modified = &modified
+	}
+
+	if n.converter != nil && item.Content != "" {
+		converter := n.converter.ForItem(item)
+		if n.opts.HTML {
+			normalized.Content.HTML = converter.ToHTML(item.Content)
+		}
+		if n.opts.Markdown {
+			normalized.Content.Markdown = converter.ToMarkdown(item.Content)
+		}
+		if n.opts.Text {
+			normalized.Content.Text = converter.ToPlainText(item.Content)
+		}
+	}
+
+	for _, category := range item.Categories {
+		normalized.Terms = append(normalized.Terms, n.terms.find(category))
+	}
+	for _, meta := range item.PostMeta {
+		normalized.Meta = append(normalized.Meta, JSONMeta{Key: meta.Key, Value: meta.DecodedValue()})
+	}
+	normalized.Comments = n.commentThreads(item.Comments)
+	return normalized
+}
+
+// Helper function to nest comments under their parents. Comments whose
+// parent is not in the export start a thread of their own, as does the
+// first comment of a cycle of parents.
+func (n *jsonNormalizer) commentThreads(comments []Comment) []JSONComment {
+	known := make(map[int]bool, len(comments))
+	children := make(map[int][]int)
+	for i, comment := range comments {
+		known[comment.ID] = true
+		children[comment.Parent] = append(children[comment.Parent], i)
+	}
+
+	var build func(i int, seen map[int]bool) JSONComment
+	build = func(i int, seen map[int]bool) JSONComment {
+		comment := n.comment(comments[i])
+		seen[comments[i].ID] = true
+		for _, child := range children[comments[i].ID] {
+			if !seen[comments[child].ID] {
+				comment.Replies = append(comment.Replies, build(child, seen))
+			}
+		}
+		return comment
+	}
+
+	var threads []JSONComment
+	seen := make(map[int]bool)
+	for i, comment := range comments {
+		if (comment.Parent == 0 || !known[comment.Parent] || comment.Parent == comment.ID) && !seen[comment.ID] {
+			threads = append(threads, build(i, seen))
+		}
+	}
+	// Whatever is left hangs off a cycle, e.g. 7 replying to 8 and 8 to 7
+	for i, comment := range comments {
+		if !seen[comment.ID] {
+			threads = append(threads, build(i, seen))
+		}
+	}
+	return threads
+}
+
+// Helper function to normalize a single comment
+func (n *jsonNormalizer) comment(comment Comment) JSONComment {
+	normalized := JSONComment{
+		ID:          comment.ID,
+		Author:      html.UnescapeString(comment.Author),
+		AuthorEmail: comment.AuthorEmail,
+		AuthorURL:   comment.AuthorURL,
+		AuthorIP:    comment.AuthorIP,
+		UserID:      comment.UserID,
+		Content:     comment.Content,
+		Status:      commentStatus(comment.Approved),
+		Type:        comment.Type,
+	}
+	if n.opts.OmitPersonal {
+		normalized.AuthorEmail, normalized.AuthorIP = "", ""
+	}
+	if date, err := parseLocalDate(comment.Date, comment.DateGMT); err == nil {
+		normalized.Date = &date
+	}
+	for _, meta := range comment.CommentMeta {
+		normalized.Meta = append(normalized.Meta, JSONMeta{Key: meta.Key, Value: decodeMetaValue(meta.Value)})
+	}
+	return normalized
+}
+
+// Helper function to name the comment_approved value of a comment; "spam"
+// and "trash" are names already
+func commentStatus(approved string) string {
+	switch approved {
+	case "1":
+		return "approved"
+	case "0":
+		return "pending"
+	}
+	return approved
+}
@@ -0,0 +1,180 @@
+package wpimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a site with comments, terms and serialized meta
+func jsonTestSite() *WordPressSite {
+	site := exportTestSite()
+	site.Channel.Title = "Old &amp; Gold"
+	site.Channel.Authors[0].Email = "jane@example.com"
+	site.Channel.Categories = []Category{{TermID: 3, NiceName: "news", Name: "News"}}
+	site.Channel.Terms = []Term{{TermID: 7, Taxonomy: "genre", Slug: "sci-fi", Name: "Sci-Fi", Description: "Spaceships"}}
+	post := &site.Channel.Items[0]
+	post.GUID = "https://oldsite.com/?p=10"
+	post.PostMeta = append(post.PostMeta, PostMeta{Key: "_sizes", Value: `a:2:{s:5:"width";i:300;s:4:"tags";a:2:{i:0;s:1:"a";i:1;s:1:"b";}}`})
+	post.Comments = []Comment{
+		{ID: 1, Author: "Ann", AuthorEmail: "ann@example.com", AuthorIP: "10.0.0.1", Date: "2019-05-02 12:00:00", DateGMT: "2019-05-02 10:00:00", Content: "First!", Approved: "1"},
+		{ID: 2, Author: "Jane", Content: "Thanks", Approved: "1", Parent: 1, Date: "2019-05-02 13:00:00", DateGMT: "2019-05-02 11:00:00"},
+		{ID: 3, Author: "Bob", Content: "Me too", Approved: "0", Parent: 2},
+		{ID: 4, Author: "Spammer", Content: "Buy", Approved: "spam"},
+		{ID: 5, Author: "Orphan", Content: "Lost", Approved: "1", Parent: 99},
+	}
+	return site
+}
+
+// TestExportJSON tests the normalized document
+func TestExportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportJSON(&buf, jsonTestSite(), JSONOptions{Markdown: true, Text: true, Indent: "  "}); err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	var doc JSONSite
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if doc.Title != "Old & Gold" || len(doc.Authors) != 1 || len(doc.Items) != 8 {
+		t.Fatalf("Unexpected document: title %q, %d authors, %d items", doc.Title, len(doc.Authors), len(doc.Items))
+	}
+	if len(doc.Terms) != 2 || doc.Terms[1].Description != "Spaceships" {
+		t.Errorf("Unexpected terms: %+v", doc.Terms)
+	}
+
+	post := doc.Items[0]
+	if post.Author == nil || post.Author.DisplayName != "Jane Doe" || post.Author.Email != "jane@example.com" {
+		t.Errorf("Expected the resolved author, got %+v", post.Author)
+	}
+	if post.Date == nil || post.Date.Format("2006-01-02T15:04:05Z07:00") != "2019-05-01T10:30:00+02:00" {
+		t.Errorf("Unexpected date: %v", post.Date)
+	}
+	if len(post.Terms) != 4 || post.Terms[0].ID != 3 || post.Terms[3].ID != 7 || post.Terms[1].Name != "Go" {
+		t.Errorf("Unexpected item terms: %+v", post.Terms)
+	}
+	sizes, ok := post.Meta[1].Value.(map[string]interface{})
+	if !ok || sizes["width"] != float64(300) || len(sizes["tags"].([]interface{})) != 2 {
+		t.Errorf("Expected decoded meta, got %#v", post.Meta[1].Value)
+	}
+	if !strings.Contains(post.Content.Markdown, "![Photo](") || !strings.Contains(post.Content.Text, "See the team") || post.Content.HTML != "" {
+		t.Errorf("Unexpected content: %+v", post.Content)
+	}
+
+	// Replies are nested; comments with unknown parents start a thread
+	if len(post.Comments) != 3 {
+		t.Fatalf("Expected 3 threads, got %d", len(post.Comments))
+	}
+	first := post.Comments[0]
+	if first.Status != "approved" || first.AuthorIP != "10.0.0.1" || len(first.Replies) != 1 || len(first.Replies[0].Replies) != 1 {
+		t.Errorf("Unexpected thread: %+v", first)
+	}
+	if reply := first.Replies[0].Replies[0]; reply.Status != "pending" || reply.Date != nil {
+		t.Errorf("Unexpected nested reply: %+v", reply)
+	}
+	if post.Comments[1].Status != "spam" || post.Comments[2].ID != 5 {
+		t.Errorf("Unexpected threads: %+v", post.Comments[1:])
+	}
+}
+
+// TestJSONCommentCycles tests that comments whose parents form a cycle
+// are kept
+func TestJSONCommentCycles(t *testing.T) {
+	n := newJSONNormalizer(jsonTestSite(), JSONOptions{})
+	threads := n.commentThreads([]Comment{{ID: 1}, {ID: 7, Parent: 8}, {ID: 8, Parent: 7}, {ID: 9, Parent: 8}})
+	if len(threads) != 2 || threads[1].ID != 7 || len(threads[1].Replies) != 1 {
+		t.Fatalf("Expected the cycle as a thread of its own, got %+v", threads)
+	}
+	if reply := threads[1].Replies[0]; reply.ID != 8 || len(reply.Replies) != 1 || reply.Replies[0].ID != 9 {
+		t.Errorf("Unexpected replies in the cycle: %+v", reply)
+	}
+}
+
+// TestExportNDJSON tests one item per line, type filtering and leaving out
+// personal data
+func TestExportNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	opts := JSONOptions{Types: []string{"post"}, OmitPersonal: true}
+	if err := ExportNDJSON(&buf, jsonTestSite(), opts); err != nil {
+		t.Fatalf("ExportNDJSON failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "example.com") || strings.Contains(buf.String(), "10.0.0.1") {
+		t.Errorf("Expected no e-mail or IP addresses, got %s", buf.String())
+	}
+
+	var ids []int
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var item JSONItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("Expected a JSON item per line: %v", err)
+		}
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 3 || ids[0] != 10 || ids[1] != 11 || ids[2] != 23 {
+		t.Errorf("Expected posts 10, 11 and 23, got %v", ids)
+	}
+}
+
+// TestJSONSchema tests that the schema describes every property of the
+// output
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		t.Fatalf("Expected a valid schema: %v", err)
+	}
+	defs := schema["$defs"].(map[string]interface{})
+	properties := func(def map[string]interface{}) map[string]interface{} {
+		return def["properties"].(map[string]interface{})
+	}
+
+	var buf bytes.Buffer
+	ExportJSON(&buf, jsonTestSite(), JSONOptions{HTML: true, Markdown: true, Text: true})
+	var doc map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &doc)
+
+	check := func(name string, object map[string]interface{}, def map[string]interface{}) {
+		for key := range object {
+			if _, ok := properties(def)[key]; !ok {
+				t.Errorf("Expected the schema of %s to describe %q", name, key)
+			}
+		}
+		for _, required := range def["required"].([]interface{}) {
+			if _, ok := object[required.(string)]; !ok {
+				t.Errorf("Expected %s to have the required %q", name, required)
+			}
+		}
+	}
+	check("the site", doc, schema)
+	for _, item := range doc["items"].([]interface{}) {
+		item := item.(map[string]interface{})
+		check("an item", item, defs["item"].(map[string]interface{}))
+		check("content", item["content"].(map[string]interface{}), defs["content"].(map[string]interface{}))
+		if author, ok := item["author"].(map[string]interface{}); ok {
+			check("an author", author, defs["author"].(map[string]interface{}))
+		}
+		for _, term := range asObjects(item["terms"]) {
+			check("a term", term, defs["term"].(map[string]interface{}))
+		}
+		for _, meta := range asObjects(item["meta"]) {
+			check("meta", meta, defs["meta"].(map[string]interface{}))
+		}
+		for _, comment := range asObjects(item["comments"]) {
+			check("a comment", comment, defs["comment"].(map[string]interface{}))
+		}
+	}
+}
+
+// Helper function to get the objects in a decoded JSON array
+func asObjects(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, v := range list {
+		if object, ok := v.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
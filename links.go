@@ -0,0 +1,360 @@
+package wpimport
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LinkResolver maps URLs on the exported site to the items they point at,
+// so links between posts can be rewritten for the site's new home. It
+// knows the site by Channel.BaseSiteURL, Channel.BaseBlogURL and
+// Channel.Link, and items by their Link, GUID, PostName, attachment URL
+// and ID, which covers permalinks as well as ?p=, ?page_id= and
+// ?attachment_id= links.
+type LinkResolver struct {
+	// NewURL returns the new URL for an item. Returning "" keeps the
+	// original link. When nil, links are resolved but never rewritten.
+	// Attachments are linked from src as well as href, so for them NewURL
+	// should return the new file URL.
+	NewURL func(*Item) string
+
+	// ArchiveBases are the path segments archive pages are served below,
+	// such as "category" in /category/news/. Links below them are never
+	// resolved by slug, so an archive is not mistaken for a post with the
+	// same slug. NewLinkResolver sets WordPress' defaults and the site's
+	// taxonomies; add the category and tag bases if the site changed them
+	// under Settings > Permalinks.
+	ArchiveBases []string
+
+	hosts  map[string]bool
+	byID   map[int]*Item
+	byPath map[string]*Item
+	byName map[string]*Item
+
+	mu         sync.Mutex
+	unresolved map[string]bool
+}
+
+var (
+	linkAttribute  = regexp.MustCompile(`(?i)(\s(?:href|src)\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+	linkMarkdown   = regexp.MustCompile(`(\]\()(<[^>\n]*>|[^)\s]+)`)
+	linkPagination = regexp.MustCompile(`^(.*?)/(?:page/\d+|\d+|amp|feed|embed|comment-page-\d+)$`)
+)
+
+// linkArchiveQueries are the query parameters of archive pages, e.g.
+// /?cat=3 or /?author_name=jane
+var linkArchiveQueries = []string{"cat", "category_name", "tag", "tag_id", "author", "author_name", "taxonomy", "term", "post_type", "s", "m", "year"}
+
+// linkAssetPaths are site paths for files rather than items, which are
+// never reported as unresolved
+var linkAssetPaths = []string{"wp-content/", "wp-includes/", "wp-json/", "wp-admin/"}
+
+// NewLinkResolver indexes the items of a site. newURL may be nil.
+func NewLinkResolver(site *WordPressSite, newURL func(*Item) string) *LinkResolver {
+	r := &LinkResolver{
+		NewURL:       newURL,
+		ArchiveBases: []string{"category", "tag", "author", "type", "search"},
+		hosts:        siteHosts(site),
+		byID:         make(map[int]*Item),
+		byPath:       make(map[string]*Item),
+		byName:       make(map[string]*Item),
+		unresolved:   make(map[string]bool),
+	}
+
+	taxonomies := make(map[string]bool)
+	for _, term := range site.Channel.Terms {
+		taxonomies[term.Taxonomy] = true
+	}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostID != 0 {
+			r.byID[item.PostID] = item
+		}
+		for _, category := range item.Categories {
+			taxonomies[category.Domain] = true
+		}
+		for _, link := range []string{item.Link, item.GUID, item.AttachmentURL} {
+			u, err := url.Parse(strings.TrimSpace(link))
+			if err != nil || u.RawQuery != "" || (u.Host != "" && !r.hosts[linkHost(u)]) {
+				continue
+			}
+			if path := linkPath(u); path != "" {
+				if _, taken := r.byPath[path]; !taken {
+					r.byPath[path] = item
+				}
+			}
+		}
+
+		// Slugs resolve links to old permalink structures, but only when
+		// no other item shares them
+		if item.PostName != "" && item.PostType != "attachment" && item.PostType != "revision" {
+			name := strings.ToLower(item.PostName)
+			if _, taken := r.byName[name]; taken {
+				r.byName[name] = nil
+			} else {
+				r.byName[name] = item
+			}
+		}
+	}
+
+	// Custom taxonomies are served below their name by default
+	var custom []string
+	for taxonomy := range taxonomies {
+		switch taxonomy {
+		case "", "category", "post_tag", "post_format", "nav_menu":
+		default:
+			custom = append(custom, strings.ToLower(taxonomy))
+		}
+	}
+	sort.Strings(custom)
+	r.ArchiveBases = append(r.ArchiveBases, custom...)
+	return r
+}
+
+// Helper function to collect the hosts of a site from the channel, or
+// from the item links when the channel has none
+func siteHosts(site *WordPressSite) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, base := range []string{site.Channel.BaseSiteURL, site.Channel.BaseBlogURL, site.Channel.Link} {
+		if u, err := url.Parse(strings.TrimSpace(base)); err == nil && u.Host != "" {
+			hosts[linkHost(u)] = true
+		}
+	}
+	if len(hosts) == 0 {
+		for _, item := range site.Channel.Items {
+			if u, err := url.Parse(strings.TrimSpace(item.Link)); err == nil && u.Host != "" {
+				hosts[linkHost(u)] = true
+			}
+		}
+	}
+	return hosts
+}
+
+// Helper function to get a URL's host without "www." and port
+func linkHost(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Helper function to normalize a URL path for lookups
+func linkPath(u *url.URL) string {
+	path := u.Path
+	if unescaped, err := url.PathUnescape(u.EscapedPath()); err == nil {
+		path = unescaped
+	}
+	return strings.Trim(strings.ToLower(path), "/")
+}
+
+// IsInternal reports whether a URL points at the exported site. Relative
+// URLs are internal.
+func (r *LinkResolver) IsInternal(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return u.Host == "" || r.hosts[linkHost(u)]
+}
+
+// Resolve returns the item an internal URL points at, or nil
+func (r *LinkResolver) Resolve(rawURL string) *Item {
+	if !r.IsInternal(rawURL) {
+		return nil
+	}
+	u, _ := url.Parse(strings.TrimSpace(rawURL))
+	if u.Host == "" && u.Path == "" && u.RawQuery == "" {
+		return nil
+	}
+
+	query := u.Query()
+	for _, key := range []string{"p", "page_id", "attachment_id"} {
+		if id, err := strconv.Atoi(query.Get(key)); err == nil {
+			return r.byID[id]
+		}
+	}
+	for _, key := range linkArchiveQueries {
+		if query.Has(key) {
+			return nil
+		}
+	}
+	for _, key := range []string{"pagename", "name", "attachment"} {
+		if value := strings.Trim(strings.ToLower(query.Get(key)), "/"); value != "" {
+			if item := r.byPath[value]; item != nil {
+				return item
+			}
+			return r.byName[value[strings.LastIndex(value, "/")+1:]]
+		}
+	}
+
+	path := linkPath(u)
+	if path == "" {
+		return nil
+	}
+	if item := r.byPath[path]; item != nil {
+		return item
+	}
+	if m := linkPagination.FindStringSubmatch(path); m != nil {
+		if item := r.byPath[m[1]]; item != nil {
+			return item
+		}
+		path = m[1]
+	}
+
+	// Slugs are only tried outside archives: /category/news/ is not the
+	// post "news"
+	segments := strings.Split(path, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		for _, base := range r.ArchiveBases {
+			if segment == strings.ToLower(base) {
+				return nil
+			}
+		}
+	}
+	return r.byName[segments[len(segments)-1]]
+}
+
+// Rewrite returns the new URL for a link and whether it was resolved.
+// Links that are external, point at the site root or at files under
+// wp-content count as resolved and are returned unchanged. The fragment
+// of a rewritten link is kept.
+func (r *LinkResolver) Rewrite(rawURL string) (string, bool) {
+	if !r.IsInternal(rawURL) {
+		return rawURL, true
+	}
+	item := r.Resolve(rawURL)
+	if item == nil {
+		u, _ := url.Parse(strings.TrimSpace(rawURL))
+		path := strings.TrimPrefix(u.Path, "/")
+		if path == "" && u.RawQuery == "" {
+			return rawURL, true
+		}
+		for _, asset := range linkAssetPaths {
+			if strings.Contains(path, asset) {
+				return rawURL, true
+			}
+		}
+		return rawURL, false
+	}
+	if r.NewURL == nil {
+		return rawURL, true
+	}
+	newURL := r.NewURL(item)
+	if newURL == "" {
+		return rawURL, true
+	}
+	if i := strings.Index(rawURL, "#"); i != -1 && !strings.Contains(newURL, "#") {
+		newURL += rawURL[i:]
+	}
+	return newURL, true
+}
+
+// RewriteHTML rewrites the href and src attributes in HTML and returns
+// the internal links that could not be resolved
+func (r *LinkResolver) RewriteHTML(content string) (string, []string) {
+	return r.rewriteHTML(content, nil)
+}
+
+// Helper function to rewrite links in HTML, leaving the URLs for which
+// skip returns true alone
+func (r *LinkResolver) rewriteHTML(content string, skip func(string) bool) (string, []string) {
+	var unresolved []string
+	content = linkAttribute.ReplaceAllStringFunc(content, func(match string) string {
+		m := linkAttribute.FindStringSubmatch(match)
+		value, quote := m[2], `"`
+		if strings.HasPrefix(match[len(m[1]):], "'") {
+			value, quote = m[3], "'"
+		}
+		oldURL := html.UnescapeString(value)
+		if skip != nil && skip(oldURL) {
+			return match
+		}
+		newURL, ok := r.Rewrite(oldURL)
+		if !ok {
+			unresolved = append(unresolved, newURL)
+		}
+		if newURL == oldURL {
+			return match
+		}
+		return m[1] + quote + html.EscapeString(newURL) + quote
+	})
+	r.report(unresolved)
+	return content, uniqueStrings(unresolved)
+}
+
+// RewriteMarkdown rewrites the targets of Markdown links and images, and
+// of any HTML left in the Markdown, and returns the internal links that
+// could not be resolved
+func (r *LinkResolver) RewriteMarkdown(content string) (string, []string) {
+	content, unresolved := r.RewriteHTML(content)
+	var missing []string
+	content = linkMarkdown.ReplaceAllStringFunc(content, func(match string) string {
+		m := linkMarkdown.FindStringSubmatch(match)
+		target := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+		newURL, ok := r.Rewrite(target)
+		if !ok {
+			missing = append(missing, newURL)
+		}
+		if newURL == target {
+			return match
+		}
+		if strings.HasPrefix(m[2], "<") || strings.ContainsAny(newURL, " ()") {
+			newURL = "<" + newURL + ">"
+		}
+		return m[1] + newURL
+	})
+	r.report(missing)
+	return content, uniqueStrings(append(unresolved, missing...))
+}
+
+// Unresolved returns every internal link that could not be resolved so
+// far, sorted
+func (r *LinkResolver) Unresolved() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	links := make([]string, 0, len(r.unresolved))
+	for link := range r.unresolved {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	return links
+}
+
+// FindUnresolvedLinks reports, per item ID, the internal links in the
+// item's content that do not point at an item in the export
+func (r *LinkResolver) FindUnresolvedLinks(site *WordPressSite) map[int][]string {
+	report := make(map[int][]string)
+	for _, item := range site.Channel.Items {
+		if _, unresolved := r.RewriteHTML(item.Content); len(unresolved) > 0 {
+			report[item.PostID] = unresolved
+		}
+	}
+	return report
+}
+
+// Helper function to record unresolved links for Unresolved
+func (r *LinkResolver) report(links []string) {
+	if len(links) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, link := range links {
+		r.unresolved[link] = true
+	}
+}
+
+// Helper function to drop repeated strings, keeping the first of each
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,129 @@
+package wpimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a small site for link tests
+func linkTestSite() *WordPressSite {
+	site := &WordPressSite{}
+	site.Channel.BaseSiteURL = "https://oldsite.com"
+	site.Channel.Items = []Item{
+		{PostID: 10, PostName: "my-post", PostType: "post", Link: "https://oldsite.com/2019/05/my-post/", GUID: "https://oldsite.com/?p=10"},
+		{PostID: 20, PostName: "about", PostType: "page", Link: "https://oldsite.com/about/", GUID: "https://oldsite.com/?page_id=20"},
+		{PostID: 30, PostName: "team", PostType: "page", PostParent: 20, Link: "https://oldsite.com/about/team/"},
+		{PostID: 40, PostName: "photo", PostType: "attachment", Link: "https://oldsite.com/my-post/photo/", AttachmentURL: "https://oldsite.com/wp-content/uploads/2019/05/photo.jpg"},
+	}
+	return site
+}
+
+// TestLinkResolver tests resolving permalinks, query links and slugs
+func TestLinkResolver(t *testing.T) {
+	r := NewLinkResolver(linkTestSite(), nil)
+
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://oldsite.com/2019/05/my-post/", 10},
+		{"http://www.oldsite.com/2019/05/my-post", 10},
+		{"/2019/05/my-post/2/", 10},
+		{"https://oldsite.com/?p=10", 10},
+		{"https://oldsite.com/?page_id=20", 20},
+		{"https://oldsite.com/index.php?p=30", 30},
+		{"https://oldsite.com/about/team/#contact", 30},
+		{"https://oldsite.com/old/structure/my-post/", 10},
+		{"https://oldsite.com/wp-content/uploads/2019/05/photo.jpg", 40},
+		{"https://oldsite.com/?pagename=about/team", 30},
+		{"https://oldsite.com/?p=999", 0},
+		{"https://othersite.com/2019/05/my-post/", 0},
+		{"https://oldsite.com/", 0},
+	}
+	for _, test := range tests {
+		got := 0
+		if item := r.Resolve(test.url); item != nil {
+			got = item.PostID
+		}
+		if got != test.want {
+			t.Errorf("Expected %s to resolve to %d, got %d", test.url, test.want, got)
+		}
+	}
+}
+
+// TestLinkResolverArchives tests that archive links are not resolved to
+// an item sharing the archive's slug
+func TestLinkResolverArchives(t *testing.T) {
+	site := linkTestSite()
+	site.Channel.Items = append(site.Channel.Items,
+		Item{PostID: 50, PostName: "news", PostType: "post", Link: "https://oldsite.com/2020/01/news/",
+			Categories: []ItemCategory{{Domain: "genre", NiceName: "news"}}})
+	r := NewLinkResolver(site, func(item *Item) string { return "/" + item.PostName + "/" })
+
+	if item := r.Resolve("https://oldsite.com/old/news/"); item == nil || item.PostID != 50 {
+		t.Errorf("Expected the slug to resolve outside archives, got %v", item)
+	}
+	for _, link := range []string{
+		"https://oldsite.com/category/news/", "/category/world/news/", "/tag/news/", "/author/news/",
+		"/genre/news/", "/category/news/page/2/", "/?cat=news", "/?tag=news&name=news", "/?author_name=news",
+	} {
+		if item := r.Resolve(link); item != nil {
+			t.Errorf("Expected %s to stay unresolved, got %d", link, item.PostID)
+		}
+		if _, ok := r.Rewrite(link); ok {
+			t.Errorf("Expected %s to be reported as unresolved", link)
+		}
+	}
+
+	r.ArchiveBases = append(r.ArchiveBases, "topics")
+	if item := r.Resolve("/topics/news/"); item != nil {
+		t.Errorf("Expected a custom category base to be an archive, got %d", item.PostID)
+	}
+}
+
+// TestRewriteLinks tests rewriting converted HTML and Markdown and the
+// unresolved link report
+func TestRewriteLinks(t *testing.T) {
+	site := linkTestSite()
+	r := NewLinkResolver(site, func(item *Item) string {
+		if item.PostType == "attachment" {
+			return "/media/" + item.PostName + ".jpg"
+		}
+		return "/" + item.PostType + "s/" + item.PostName + "/"
+	})
+
+	content := `<p>See <a href="https://oldsite.com/?p=10">my post</a>, <a href='/about/team/#contact'>the team</a>, ` +
+		`<a href="https://oldsite.com/gone/">a gone page</a> and <a href="https://example.com/">elsewhere</a>.</p>` +
+		`<img src="https://oldsite.com/wp-content/uploads/2019/05/photo.jpg" alt="Photo">`
+
+	c := NewConverter()
+	c.Links = r
+	html := c.ToHTML(content)
+	for _, want := range []string{`href="/posts/my-post/"`, `href='/pages/team/#contact'`, `src="/media/photo.jpg"`, `href="https://oldsite.com/gone/"`, `href="https://example.com/"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected HTML to contain %s, got %s", want, html)
+		}
+	}
+
+	markdown := c.ToMarkdown(content)
+	for _, want := range []string{"[my post](/posts/my-post/)", "[the team](/pages/team/#contact)", "![Photo](/media/photo.jpg)"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected Markdown to contain %s, got %s", want, markdown)
+		}
+	}
+	if got := r.Unresolved(); !reflect.DeepEqual(got, []string{"https://oldsite.com/gone/"}) {
+		t.Errorf("Expected one unresolved link, got %v", got)
+	}
+
+	// Markdown that was converted earlier can be rewritten too
+	rewritten, unresolved := r.RewriteMarkdown("[Post](https://oldsite.com/2019/05/my-post/) and [missing](https://oldsite.com/?p=5)")
+	if rewritten != "[Post](/posts/my-post/) and [missing](https://oldsite.com/?p=5)" || len(unresolved) != 1 {
+		t.Errorf("Unexpected Markdown rewrite %q with unresolved %v", rewritten, unresolved)
+	}
+
+	site.Channel.Items[0].Content = content
+	if report := r.FindUnresolvedLinks(site); !reflect.DeepEqual(report, map[int][]string{10: {"https://oldsite.com/gone/"}}) {
+		t.Errorf("Unexpected unresolved link report %v", report)
+	}
+}
@@ -0,0 +1,194 @@
+package wpimport
+
+import (
+	"strconv"
+	"strings"
+)
+
+// listBlockTags are the elements that start a new block inside a list
+// item; everything else is inline content of the item
+var listBlockTags = map[string]bool{
+	"p": true, "pre": true, "blockquote": true, "ul": true, "ol": true, "dl": true,
+	"table": true, "figure": true, "div": true, "hr": true, "details": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// listItem is an <li> with the nodes that belong to it. Nested lists
+// placed directly inside a list (a common WordPress mistake) are added to
+// the item before them.
+type listItem struct {
+	Node     *htmlNode
+	Number   int
+	Children []*htmlNode
+}
+
+// Helper function to replace the outermost lists in content with their
+// rendering in the given format. Lists inside tables and <pre> are left
+// to those elements.
+func (c *Converter) replaceLists(content string, format OutputFormat, verbatim *verbatimStore) string {
+	lower := strings.ToLower(content)
+	if !strings.Contains(lower, "<ul") && !strings.Contains(lower, "<ol") {
+		return content
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for _, list := range findLists(parseHTML(content)) {
+		sb.WriteString(content[pos:list.Start])
+		sb.WriteString("\n\n" + verbatim.protect(c.renderList(list, format)) + "\n\n")
+		pos = list.End
+	}
+	sb.WriteString(content[pos:])
+	return sb.String()
+}
+
+// Helper function to find the outermost lists, skipping tables and <pre>
+func findLists(n *htmlNode) []*htmlNode {
+	var found []*htmlNode
+	for _, child := range n.Children {
+		switch child.Tag {
+		case "ul", "ol":
+			found = append(found, child)
+		case "table", "pre":
+		default:
+			found = append(found, findLists(child)...)
+		}
+	}
+	return found
+}
+
+// Helper function to collect the items of a list and number them. Ordered
+// lists honour start, reversed and the value of each item.
+func listItems(list *htmlNode) []*listItem {
+	var items []*listItem
+	for _, child := range list.Children {
+		switch {
+		case child.Tag == "li":
+			items = append(items, &listItem{Node: child, Children: child.Children})
+		case (child.Tag == "ul" || child.Tag == "ol") && len(items) > 0:
+			last := items[len(items)-1]
+			last.Children = append(last.Children, child)
+		case child.Tag == "!--", child.Tag == "" && strings.TrimSpace(child.Text) == "":
+		default:
+			// Stray content becomes an item of its own
+			items = append(items, &listItem{Node: child, Children: []*htmlNode{child}})
+		}
+	}
+
+	reversed := list.HasAttr("reversed")
+	number := 1
+	if reversed {
+		number = len(items)
+	}
+	if start, err := strconv.Atoi(strings.TrimSpace(list.Attr("start"))); err == nil {
+		number = start
+	}
+	for _, item := range items {
+		if item.Node.Tag == "li" {
+			if value, err := strconv.Atoi(strings.TrimSpace(item.Node.Attr("value"))); err == nil {
+				number = value
+			}
+		}
+		item.Number = number
+		if reversed {
+			number--
+		} else {
+			number++
+		}
+	}
+	return items
+}
+
+// Helper function to render a list and its nested lists. Markdown uses
+// "- " and "1. " markers, plain text "• " and "1. ". Later blocks of an
+// item are indented to line up with the text after the marker, and items
+// holding several blocks are separated by blank lines.
+func (c *Converter) renderList(list *htmlNode, format OutputFormat) string {
+	ordered := list.Tag == "ol"
+	bullet := "- "
+	if format == FormatPlainText {
+		bullet = "• "
+	}
+
+	items := listItems(list)
+	rendered := make([]string, len(items))
+	loose := false
+	for i, item := range items {
+		marker := bullet
+		if ordered {
+			marker = strconv.Itoa(item.Number) + ". "
+		}
+
+		// Item content wraps within the indentation in plain text
+		blocks, separators := c.indented(displayWidth(marker)).listItemBlocks(item, format)
+		if len(blocks) == 0 {
+			rendered[i] = strings.TrimSpace(marker)
+			continue
+		}
+		loose = loose || strings.Contains(strings.Join(separators[1:], ""), "\n\n")
+
+		indent := strings.Repeat(" ", displayWidth(marker))
+		var sb strings.Builder
+		sb.WriteString(marker)
+		for j, block := range blocks {
+			if j > 0 {
+				sb.WriteString(separators[j])
+			}
+			for k, line := range strings.Split(block, "\n") {
+				if k > 0 {
+					sb.WriteString("\n")
+				}
+				if (j > 0 || k > 0) && line != "" {
+					sb.WriteString(indent)
+				}
+				sb.WriteString(line)
+			}
+		}
+		rendered[i] = sb.String()
+	}
+
+	if loose {
+		return strings.Join(rendered, "\n\n")
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// Helper function to render the blocks of a list item. Runs of inline
+// content form one block; a nested list follows the text before it
+// directly while other blocks are set off by a blank line. separators[i]
+// goes before blocks[i].
+func (c *Converter) listItemBlocks(item *listItem, format OutputFormat) (blocks, separators []string) {
+	convert := c.HTMLToMarkdown
+	if format == FormatPlainText {
+		convert = c.fragmentToPlainText
+	}
+
+	var inline strings.Builder
+	add := func(block, separator string) {
+		// Leading spaces may be code indentation
+		block = strings.TrimLeft(strings.TrimRight(block, " \t\n"), "\n")
+		if strings.TrimSpace(block) != "" {
+			blocks = append(blocks, block)
+			separators = append(separators, separator)
+		}
+	}
+	flush := func() {
+		add(convert(inline.String()), "\n\n")
+		inline.Reset()
+	}
+
+	for _, child := range item.Children {
+		if !listBlockTags[child.Tag] {
+			child.writeHTML(&inline, nil)
+			continue
+		}
+		flush()
+		if child.Tag == "ul" || child.Tag == "ol" {
+			add(c.renderList(child, format), "\n")
+		} else {
+			add(convert(child.OuterHTML()), "\n\n")
+		}
+	}
+	flush()
+	return blocks, separators
+}
@@ -0,0 +1,100 @@
+package wpimport
+
+import "testing"
+
+// mixedList nests ordered and unordered lists three levels deep and has
+// an item with several paragraphs and a code block
+const mixedList = `<ol start="3"><li>Intro<ul><li>Sub <b>a</b><ol reversed><li>x</li><li>y</li><li value="10">z</li></ol></li><li>Sub b</li></ul></li>
+<li><p>First para</p><p>Second para</p><pre><code>go run .
+  indented</code></pre></li><li>Last</li></ol><p>After</p>`
+
+// TestListNumbering tests start, reversed and value on ordered lists
+func TestListNumbering(t *testing.T) {
+	tests := []struct {
+		html string
+		want []int
+	}{
+		{`<ol><li>a<li>b<li>c</ol>`, []int{1, 2, 3}},
+		{`<ol start="5"><li>a<li>b</ol>`, []int{5, 6}},
+		{`<ol reversed><li>a<li>b<li>c</ol>`, []int{3, 2, 1}},
+		{`<ol reversed start="10"><li>a<li value="4">b<li>c</ol>`, []int{10, 4, 3}},
+	}
+	for _, test := range tests {
+		items := listItems(parseHTML(test.html).Find("ol")[0])
+		if len(items) != len(test.want) {
+			t.Fatalf("Expected %d items for %s, got %d", len(test.want), test.html, len(items))
+		}
+		for i, item := range items {
+			if item.Number != test.want[i] {
+				t.Errorf("Expected item %d of %s to be numbered %d, got %d", i, test.html, test.want[i], item.Number)
+			}
+		}
+	}
+}
+
+// TestListMarkdown tests nested mixed lists and multi-block items
+func TestListMarkdown(t *testing.T) {
+	want := "3. Intro\n" +
+		"   - Sub **a**\n" +
+		"     3. x\n" +
+		"     2. y\n" +
+		"     10. z\n" +
+		"   - Sub b\n\n" +
+		"4. First para\n\n" +
+		"   Second para\n\n" +
+		"   ```\n" +
+		"   go run .\n" +
+		"     indented\n" +
+		"   ```\n\n" +
+		"5. Last\n\n" +
+		"After"
+	if got := ConvertToMarkdown(mixedList); got != want {
+		t.Errorf("Expected nested list:\n%s\ngot:\n%s", want, got)
+	}
+
+	// A list nested directly in a list belongs to the item before it
+	got := ConvertToMarkdown(`<ul><li>One</li><ul><li>Two</li></ul><li>Three</li></ul>`)
+	if want := "- One\n  - Two\n- Three"; got != want {
+		t.Errorf("Expected stray nested list under its item:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestListPlainText tests indented bullets and numbers in plain text
+func TestListPlainText(t *testing.T) {
+	want := "3. Intro\n" +
+		"   • Sub a\n" +
+		"     3. x\n" +
+		"     2. y\n" +
+		"     10. z\n" +
+		"   • Sub b\n\n" +
+		"4. First para\n\n" +
+		"   Second para\n\n" +
+		"   go run .\n" +
+		"     indented\n\n" +
+		"5. Last\n\n" +
+		"After"
+	if got := ConvertToPlainText(mixedList); got != want {
+		t.Errorf("Expected nested list:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestListBlock tests core/list blocks made of core/list-item blocks
+func TestListBlock(t *testing.T) {
+	content := `<!-- wp:list {"ordered":true,"start":2} -->
+<ol start="2"><!-- wp:list-item -->
+<li>One<!-- wp:list -->
+<ul><!-- wp:list-item -->
+<li>Nested</li>
+<!-- /wp:list-item --></ul>
+<!-- /wp:list --></li>
+<!-- /wp:list-item -->
+
+<!-- wp:list-item -->
+<li>Two</li>
+<!-- /wp:list-item --></ol>
+<!-- /wp:list -->`
+
+	if got, want := ConvertToMarkdown(content), "2. One\n   - Nested\n3. Two"; got != want {
+		t.Errorf("Expected list block:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,307 @@
+package wpimport
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MediaFile is a file in the site's uploads directory that content refers
+// to
+type MediaFile struct {
+	// URL is the URL as it first appeared in content
+	URL string
+
+	// Path is the file's path below wp-content/uploads, e.g.
+	// "2019/05/photo-300x200.jpg"
+	Path string
+
+	// Original is the path of the uploaded file the variant was made from;
+	// it equals Path for the uploaded file itself
+	Original string
+
+	// Width and Height are the size in the name of a resized variant, or 0
+	Width, Height int
+
+	// Attachment is the attachment item for the file, or nil when the
+	// export has none
+	Attachment *Item
+}
+
+// MediaMapper recognises URLs of uploaded files, including the resized
+// (-300x200), scaled (-scaled) and edited (-e1234567890123) variants
+// WordPress generates, and maps them to their attachments so content can
+// be pointed at a new location for the files.
+type MediaMapper struct {
+	// NewURL returns the new URL for a file. Returning "" keeps the
+	// original URL. When nil, files are only recorded in the manifest.
+	NewURL func(MediaFile) string
+
+	hosts  map[string]bool
+	byFile map[string]*Item
+
+	mu       sync.Mutex
+	manifest map[string]MediaFile
+}
+
+var (
+	mediaAttribute = regexp.MustCompile(`(?i)(\s(?:src|href|srcset|poster|data-src|data-srcset|data-orig-file|data-medium-file|data-large-file)\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+	mediaResized   = regexp.MustCompile(`^(.*)-(\d+)x(\d+)(\.[A-Za-z0-9]+)$`)
+	mediaEdited    = regexp.MustCompile(`^(.*)-e\d{13}(\.[A-Za-z0-9]+)$`)
+	mediaScaled    = regexp.MustCompile(`^(.*)-scaled(\.[A-Za-z0-9]+)$`)
+	mediaPhotonCDN = regexp.MustCompile(`^i[0-3]\.wp\.com$`)
+)
+
+// mediaUploadsDir is the part of an upload URL before the file's path
+const mediaUploadsDir = "/wp-content/uploads/"
+
+// NewMediaMapper indexes the attachments of a site by their files. newURL
+// may be nil.
+func NewMediaMapper(site *WordPressSite, newURL func(MediaFile) string) *MediaMapper {
+	m := &MediaMapper{
+		NewURL:   newURL,
+		hosts:    siteHosts(site),
+		byFile:   make(map[string]*Item),
+		manifest: make(map[string]MediaFile),
+	}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostType != "attachment" {
+			continue
+		}
+		if file := item.GetMetaValue("_wp_attached_file"); file != "" {
+			m.byFile[strings.TrimPrefix(file, "/")] = item
+		}
+		if path, ok := m.uploadPath(item.AttachmentURL); ok {
+			if _, taken := m.byFile[path]; !taken {
+				m.byFile[path] = item
+			}
+		}
+	}
+	return m
+}
+
+// MediaPrefix returns a NewURL function that puts files under prefix,
+// keeping their uploads path, e.g. MediaPrefix("/static/uploads") or
+// MediaPrefix("https://cdn.example.com/media")
+func MediaPrefix(prefix string) func(MediaFile) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(file MediaFile) string {
+		return prefix + "/" + file.Path
+	}
+}
+
+// Helper function to get a mapper that shares the index of m but has its
+// own NewURL and manifest
+func (m *MediaMapper) withNewURL(newURL func(MediaFile) string) *MediaMapper {
+	return &MediaMapper{
+		NewURL:   newURL,
+		hosts:    m.hosts,
+		byFile:   m.byFile,
+		manifest: make(map[string]MediaFile),
+	}
+}
+
+// Helper function to get the path below wp-content/uploads of a URL on
+// the site, including URLs served through the Jetpack image CDN
+func (m *MediaMapper) uploadPath(rawURL string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	path := u.Path
+	if u.Host != "" && !m.hosts[linkHost(u)] {
+		if !mediaPhotonCDN.MatchString(u.Hostname()) {
+			return "", false
+		}
+		// i0.wp.com/oldsite.com/wp-content/uploads/...
+		host, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		if !m.hosts[strings.TrimPrefix(strings.ToLower(host), "www.")] {
+			return "", false
+		}
+		path = "/" + rest
+	}
+	i := strings.Index(path, mediaUploadsDir)
+	if i == -1 {
+		return "", false
+	}
+	file := path[i+len(mediaUploadsDir):]
+	return file, file != "" && !strings.HasSuffix(file, "/")
+}
+
+// IsUpload reports whether a URL points at a file in the site's uploads
+func (m *MediaMapper) IsUpload(rawURL string) bool {
+	_, ok := m.uploadPath(rawURL)
+	return ok
+}
+
+// Match recognises an upload URL and finds the attachment it belongs to
+func (m *MediaMapper) Match(rawURL string) (MediaFile, bool) {
+	path, ok := m.uploadPath(rawURL)
+	if !ok {
+		return MediaFile{}, false
+	}
+	file := MediaFile{URL: rawURL, Path: path, Original: path}
+
+	dir, name := "", path
+	if i := strings.LastIndex(path, "/"); i != -1 {
+		dir, name = path[:i+1], path[i+1:]
+	}
+	original := name
+	if r := mediaResized.FindStringSubmatch(name); r != nil {
+		file.Width, _ = strconv.Atoi(r[2])
+		file.Height, _ = strconv.Atoi(r[3])
+		original = r[1] + r[4]
+	}
+
+	// Try the file itself, then the file it was resized from, then the
+	// same file before editing or scaling
+	candidates := []string{name, original}
+	if e := mediaEdited.FindStringSubmatch(original); e != nil {
+		candidates = append(candidates, e[1]+e[2])
+	}
+	if s := mediaScaled.FindStringSubmatch(original); s != nil {
+		candidates = append(candidates, s[1]+s[2])
+	} else if dot := strings.LastIndex(original, "."); dot != -1 {
+		candidates = append(candidates, original[:dot]+"-scaled"+original[dot:])
+	}
+	for _, candidate := range candidates {
+		if item := m.byFile[dir+candidate]; item != nil {
+			file.Attachment = item
+			file.Original = dir + candidate
+			break
+		}
+	}
+	if file.Attachment == nil {
+		file.Original = dir + original
+	}
+	return file, true
+}
+
+// mediaRefs collects the files one piece of content refers to, in order
+// of first appearance
+type mediaRefs struct {
+	files []MediaFile
+	seen  map[string]bool
+}
+
+// Helper function to add a file unless it was seen before
+func (refs *mediaRefs) add(file MediaFile) {
+	if refs.seen == nil {
+		refs.seen = make(map[string]bool)
+	}
+	if !refs.seen[file.Path] {
+		refs.seen[file.Path] = true
+		refs.files = append(refs.files, file)
+	}
+}
+
+// Helper function to map one URL, adding it to refs
+func (m *MediaMapper) rewrite(rawURL string, refs *mediaRefs) string {
+	file, ok := m.Match(rawURL)
+	if !ok {
+		return rawURL
+	}
+	refs.add(file)
+	if m.NewURL == nil {
+		return rawURL
+	}
+	if newURL := m.NewURL(file); newURL != "" {
+		return newURL
+	}
+	return rawURL
+}
+
+// Helper function to rewrite each URL of a srcset candidate list
+func (m *MediaMapper) rewriteSrcset(srcset string, refs *mediaRefs) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = m.rewrite(fields[0], refs)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// RewriteHTML rewrites upload URLs in src, srcset, href and the lazy
+// loading and Jetpack data attributes, and returns the files the content
+// refers to in order of appearance
+func (m *MediaMapper) RewriteHTML(content string) (string, []MediaFile) {
+	refs := &mediaRefs{}
+	content = m.rewriteHTML(content, refs)
+	return content, m.record(refs)
+}
+
+// Helper function to rewrite upload URLs in HTML attributes
+func (m *MediaMapper) rewriteHTML(content string, refs *mediaRefs) string {
+	return mediaAttribute.ReplaceAllStringFunc(content, func(match string) string {
+		r := mediaAttribute.FindStringSubmatch(match)
+		value, quote := r[2], `"`
+		if strings.HasPrefix(match[len(r[1]):], "'") {
+			value, quote = r[3], "'"
+		}
+		oldValue := html.UnescapeString(value)
+		var newValue string
+		if strings.Contains(strings.ToLower(r[1]), "srcset") {
+			newValue = m.rewriteSrcset(oldValue, refs)
+		} else {
+			newValue = m.rewrite(oldValue, refs)
+		}
+		if newValue == oldValue {
+			return match
+		}
+		return r[1] + quote + html.EscapeString(newValue) + quote
+	})
+}
+
+// RewriteMarkdown rewrites upload URLs in Markdown links and images, and
+// in any HTML left in the Markdown, and returns the files the content
+// refers to in order of appearance
+func (m *MediaMapper) RewriteMarkdown(content string) (string, []MediaFile) {
+	refs := &mediaRefs{}
+	content = m.rewriteHTML(content, refs)
+	content = linkMarkdown.ReplaceAllStringFunc(content, func(match string) string {
+		r := linkMarkdown.FindStringSubmatch(match)
+		target := strings.TrimSuffix(strings.TrimPrefix(r[2], "<"), ">")
+		newURL := m.rewrite(target, refs)
+		if newURL == target {
+			return match
+		}
+		if strings.HasPrefix(r[2], "<") || strings.ContainsAny(newURL, " ()") {
+			newURL = "<" + newURL + ">"
+		}
+		return r[1] + newURL
+	})
+	return content, m.record(refs)
+}
+
+// Helper function to add the files of refs to the manifest
+func (m *MediaMapper) record(refs *mediaRefs) []MediaFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, file := range refs.files {
+		if _, ok := m.manifest[file.Path]; !ok {
+			m.manifest[file.Path] = file
+		}
+	}
+	return refs.files
+}
+
+// Manifest returns every file recorded so far, sorted by path
+func (m *MediaMapper) Manifest() []MediaFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make([]MediaFile, 0, len(m.manifest))
+	for _, file := range m.manifest {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
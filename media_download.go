@@ -0,0 +1,406 @@
+package wpimport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMediaManifest is the name of the manifest a MediaDownloader
+// created with NewMediaDownloader writes to its directory
+const DefaultMediaManifest = "media-manifest.json"
+
+// MediaDownloader fetches uploaded files into a local directory, keeping
+// the uploads/YYYY/MM layout of the site. Files already on disk are skipped
+// when they match the hash recorded in an earlier manifest or the size the
+// server reports, so an interrupted download resumes when run again.
+type MediaDownloader struct {
+	// Client makes the requests; http.DefaultClient is used when nil
+	Client *http.Client
+
+	// Dir is the directory files are saved under, e.g. a file from
+	// wp-content/uploads/2019/05 is saved as Dir/uploads/2019/05/photo.jpg
+	Dir string
+
+	// Concurrency is how many files are downloaded at once, at least 1
+	Concurrency int
+
+	// Retries is how often a failed download is retried. Network errors,
+	// 429 and 5xx responses are retried; other errors are not.
+	Retries int
+
+	// Backoff is the wait before the first retry, doubled for each
+	// further retry
+	Backoff time.Duration
+
+	// ManifestName is the file in Dir the manifest is read from and
+	// written to; when empty no manifest is written
+	ManifestName string
+}
+
+// DownloadStatus is the outcome of downloading one file
+type DownloadStatus string
+
+const (
+	// DownloadDone means the file was downloaded
+	DownloadDone DownloadStatus = "downloaded"
+	// DownloadSkipped means the file was already on disk
+	DownloadSkipped DownloadStatus = "skipped"
+	// DownloadFailed means the file could not be downloaded
+	DownloadFailed DownloadStatus = "failed"
+)
+
+// MediaDownload describes one file in a MediaManifest
+type MediaDownload struct {
+	URL          string         `json:"url"`
+	Path         string         `json:"path"`
+	AttachmentID int            `json:"attachment_id,omitempty"`
+	Size         int64          `json:"size,omitempty"`
+	SHA256       string         `json:"sha256,omitempty"`
+	Status       DownloadStatus `json:"status"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// MediaManifest lists the files of a download. Paths are relative to the
+// downloader's directory and use forward slashes.
+type MediaManifest struct {
+	Files []MediaDownload `json:"files"`
+}
+
+// mediaStatusError is a download that failed with an HTTP status
+type mediaStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *mediaStatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// NewMediaDownloader creates a downloader that saves files under dir,
+// downloading up to concurrency files at once. It retries three times,
+// starting with a half-second wait, and writes DefaultMediaManifest.
+func NewMediaDownloader(client *http.Client, dir string, concurrency int) *MediaDownloader {
+	return &MediaDownloader{
+		Client:       client,
+		Dir:          dir,
+		Concurrency:  concurrency,
+		Retries:      3,
+		Backoff:      500 * time.Millisecond,
+		ManifestName: DefaultMediaManifest,
+	}
+}
+
+// Failed returns the files that could not be downloaded
+func (m *MediaManifest) Failed() []MediaDownload {
+	var failed []MediaDownload
+	for _, file := range m.Files {
+		if file.Status == DownloadFailed {
+			failed = append(failed, file)
+		}
+	}
+	return failed
+}
+
+// DownloadSite downloads the files of every attachment in the site
+func (d *MediaDownloader) DownloadSite(ctx context.Context, site *WordPressSite) (*MediaManifest, error) {
+	var files []MediaDownload
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostType != "attachment" {
+			continue
+		}
+		if fileURL := item.GetAttachmentURL(); fileURL != "" {
+			files = append(files, MediaDownload{URL: fileURL, AttachmentID: item.PostID})
+		}
+	}
+	return d.download(ctx, files)
+}
+
+// Download downloads the files at the given URLs, such as those returned
+// by GetAttachmentURLs. Files that fail are marked in the manifest; the
+// error is only set when the context is done or the manifest cannot be
+// written.
+func (d *MediaDownloader) Download(ctx context.Context, urls []string) (*MediaManifest, error) {
+	files := make([]MediaDownload, len(urls))
+	for i, fileURL := range urls {
+		files[i].URL = fileURL
+	}
+	return d.download(ctx, files)
+}
+
+// Helper function to download a list of files with a pool of workers and
+// write the manifest
+func (d *MediaDownloader) download(ctx context.Context, jobs []MediaDownload) (*MediaManifest, error) {
+	previous := d.readManifest()
+
+	// Several URLs, such as a GUID and the attachment URL, may be the
+	// same file
+	var files []MediaDownload
+	seen := make(map[string]bool)
+	for _, file := range jobs {
+		filePath, err := mediaDownloadPath(file.URL)
+		if err != nil {
+			file.Status, file.Error = DownloadFailed, err.Error()
+		} else if seen[filePath] {
+			continue
+		} else {
+			seen[filePath] = true
+			file.Path = filePath
+		}
+		files = append(files, file)
+	}
+
+	slots := make(chan struct{}, max(d.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range files {
+		if files[i].Status != "" {
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(file *MediaDownload) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d.fetch(ctx, file, previous[file.Path])
+		}(&files[i])
+	}
+	wg.Wait()
+
+	for i := range files {
+		if files[i].Status == "" {
+			files[i].Status, files[i].Error = DownloadFailed, ctx.Err().Error()
+		}
+	}
+	manifest := &MediaManifest{Files: files}
+	if err := d.writeManifest(manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, ctx.Err()
+}
+
+// Helper function to get the path a file is saved under, relative to the
+// downloader's directory
+func mediaDownloadPath(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("not an absolute HTTP URL: %q", rawURL)
+	}
+	filePath := u.Path
+	if i := strings.Index(filePath, mediaUploadsDir); i != -1 {
+		filePath = filePath[i+len(mediaUploadsDir):]
+	}
+	if strings.HasSuffix(filePath, "/") {
+		return "", fmt.Errorf("not a file URL: %q", rawURL)
+	}
+	// Cleaning below the root drops ".." so files stay inside the directory
+	filePath = strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	if filePath == "" {
+		return "", fmt.Errorf("not a file URL: %q", rawURL)
+	}
+	return "uploads/" + filePath, nil
+}
+
+// Helper function to download one file unless it is already on disk
+func (d *MediaDownloader) fetch(ctx context.Context, file *MediaDownload, previous MediaDownload) {
+	target := filepath.Join(d.Dir, filepath.FromSlash(file.Path))
+	if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+		if d.upToDate(ctx, file, target, info.Size(), previous) {
+			file.Status = DownloadSkipped
+			return
+		}
+	}
+	if err := d.retry(ctx, func() error { return d.get(ctx, file, target) }); err != nil {
+		file.Status, file.Error = DownloadFailed, err.Error()
+		return
+	}
+	file.Status = DownloadDone
+}
+
+// Helper function to check a file on disk against the hash in the earlier
+// manifest or, without one, the size the server reports for a HEAD request
+func (d *MediaDownloader) upToDate(ctx context.Context, file *MediaDownload, target string, size int64, previous MediaDownload) bool {
+	sum, err := fileSHA256(target)
+	if err != nil {
+		return false
+	}
+	if previous.SHA256 == "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, file.URL, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := d.client().Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.ContentLength != size {
+			return false
+		}
+	} else if previous.SHA256 != sum {
+		return false
+	}
+	file.Size, file.SHA256 = size, sum
+	return true
+}
+
+// Helper function to download a file to target. The file is written next
+// to target first so an interrupted download never leaves a partial file
+// under the final name.
+func (d *MediaDownloader) get(ctx context.Context, file *MediaDownload, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &mediaStatusError{URL: file.URL, StatusCode: resp.StatusCode}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	partial := target + ".part"
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && size != resp.ContentLength {
+		return fmt.Errorf("GET %s: got %d of %d bytes", file.URL, size, resp.ContentLength)
+	}
+	if err := os.Rename(partial, target); err != nil {
+		return err
+	}
+	file.Size, file.SHA256 = size, hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Helper function to run attempt until it succeeds, fails for good or the
+// retries run out, waiting longer before each retry
+func (d *MediaDownloader) retry(ctx context.Context, attempt func() error) error {
+	wait := d.Backoff
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil || i >= d.Retries || ctx.Err() != nil || !retryableDownload(err) {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// Helper function to decide whether a failed download is worth retrying
+func retryableDownload(err error) bool {
+	var statusErr *mediaStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var pathErr *fs.PathError
+	return !errors.As(err, &pathErr)
+}
+
+// Helper function to get the client to make requests with
+func (d *MediaDownloader) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return http.DefaultClient
+}
+
+// Helper function to read the manifest of an earlier run, keyed by path
+func (d *MediaDownloader) readManifest() map[string]MediaDownload {
+	files := make(map[string]MediaDownload)
+	if d.ManifestName == "" {
+		return files
+	}
+	data, err := os.ReadFile(filepath.Join(d.Dir, d.ManifestName))
+	if err != nil {
+		return files
+	}
+	var manifest MediaManifest
+	if json.Unmarshal(data, &manifest) != nil {
+		return files
+	}
+	for _, file := range manifest.Files {
+		if file.Status != DownloadFailed {
+			files[file.Path] = file
+		}
+	}
+	return files
+}
+
+// Helper function to write the manifest to the downloader's directory
+func (d *MediaDownloader) writeManifest(manifest *MediaManifest) error {
+	if d.ManifestName == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.MkdirAll(d.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.Dir, d.ManifestName), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Helper function to hash a file with SHA-256
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
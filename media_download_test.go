@@ -0,0 +1,187 @@
+package wpimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Helper function to start a server for uploaded files that counts GET
+// requests and fails the first requests for flaky.jpg
+func mediaTestServer(t *testing.T, gets *atomic.Int32) *httptest.Server {
+	var flaky atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		switch r.URL.Path {
+		case "/wp-content/uploads/2019/05/photo.jpg":
+			w.Write([]byte("photo data"))
+		case "/wp-content/uploads/2020/01/doc.pdf":
+			w.Write([]byte("pdf data"))
+		case "/wp-content/uploads/2020/01/flaky.jpg":
+			if r.Method == http.MethodGet && flaky.Add(1) <= 2 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("flaky data"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestMediaDownload tests downloading files into the uploads layout,
+// retrying and writing the manifest
+func TestMediaDownload(t *testing.T) {
+	var gets atomic.Int32
+	server := mediaTestServer(t, &gets)
+	dir := t.TempDir()
+
+	d := NewMediaDownloader(server.Client(), dir, 2)
+	d.Backoff = time.Millisecond
+	urls := []string{
+		server.URL + "/wp-content/uploads/2019/05/photo.jpg",
+		server.URL + "/wp-content/uploads/2020/01/doc.pdf",
+		server.URL + "/wp-content/uploads/2020/01/flaky.jpg",
+		server.URL + "/wp-content/uploads/2020/01/missing.jpg",
+		server.URL + "/wp-content/uploads/2019/05/photo.jpg",
+		"not a url",
+	}
+	manifest, err := d.Download(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if len(manifest.Files) != 5 {
+		t.Fatalf("Expected 5 files, got %d", len(manifest.Files))
+	}
+	statuses := []DownloadStatus{DownloadDone, DownloadDone, DownloadDone, DownloadFailed, DownloadFailed}
+	for i, file := range manifest.Files {
+		if file.Status != statuses[i] {
+			t.Errorf("Expected %s to be %s, got %s (%s)", file.URL, statuses[i], file.Status, file.Error)
+		}
+	}
+	if photo := manifest.Files[0]; photo.Path != "uploads/2019/05/photo.jpg" || photo.Size != 10 || len(photo.SHA256) != 64 {
+		t.Errorf("Unexpected manifest entry: %+v", photo)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "uploads", "2020", "01", "flaky.jpg")); err != nil || string(data) != "flaky data" {
+		t.Errorf("Expected the retried file on disk, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "2020", "01", "missing.jpg")); err == nil {
+		t.Error("Expected no file for a failed download")
+	}
+	if len(manifest.Failed()) != 2 {
+		t.Errorf("Expected 2 failed files, got %d", len(manifest.Failed()))
+	}
+	// 2 files, 3 attempts for flaky.jpg and a single attempt for the 404
+	if n := gets.Load(); n != 6 {
+		t.Errorf("Expected 6 GET requests, got %d", n)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, DefaultMediaManifest))
+	if err != nil {
+		t.Fatalf("Expected a manifest: %v", err)
+	}
+	var written MediaManifest
+	if err := json.Unmarshal(data, &written); err != nil || len(written.Files) != 5 {
+		t.Errorf("Expected the manifest JSON to list 5 files, got %s", data)
+	}
+}
+
+// TestMediaDownloadResume tests skipping files that are already on disk
+func TestMediaDownloadResume(t *testing.T) {
+	var gets atomic.Int32
+	server := mediaTestServer(t, &gets)
+	dir := t.TempDir()
+	urls := []string{
+		server.URL + "/wp-content/uploads/2019/05/photo.jpg",
+		server.URL + "/wp-content/uploads/2020/01/doc.pdf",
+	}
+
+	d := NewMediaDownloader(server.Client(), dir, 1)
+	if _, err := d.Download(context.Background(), urls); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	// Resumed from the manifest's hashes; a changed file is fetched again
+	os.WriteFile(filepath.Join(dir, "uploads", "2020", "01", "doc.pdf"), []byte("PDF DATA"), 0o644)
+	manifest, _ := d.Download(context.Background(), urls)
+	if manifest.Files[0].Status != DownloadSkipped || manifest.Files[1].Status != DownloadDone {
+		t.Errorf("Expected photo skipped and doc downloaded, got %+v", manifest.Files)
+	}
+	if manifest.Files[0].SHA256 == "" {
+		t.Error("Expected skipped files to keep their hash")
+	}
+	if n := gets.Load(); n != 3 {
+		t.Errorf("Expected 3 GET requests, got %d", n)
+	}
+
+	// Without a manifest the size from a HEAD request decides
+	os.Remove(filepath.Join(dir, DefaultMediaManifest))
+	os.WriteFile(filepath.Join(dir, "uploads", "2019", "05", "photo.jpg"), []byte("short"), 0o644)
+	manifest, _ = d.Download(context.Background(), urls)
+	if manifest.Files[0].Status != DownloadDone || manifest.Files[1].Status != DownloadSkipped {
+		t.Errorf("Expected photo downloaded and doc skipped, got %+v", manifest.Files)
+	}
+	if n := gets.Load(); n != 4 {
+		t.Errorf("Expected 4 GET requests, got %d", n)
+	}
+}
+
+// TestMediaDownloadSite tests downloading attachments and cancelling
+func TestMediaDownloadSite(t *testing.T) {
+	var gets atomic.Int32
+	server := mediaTestServer(t, &gets)
+	site := &WordPressSite{}
+	site.Channel.Items = []Item{
+		{PostID: 7, PostType: "attachment", AttachmentURL: server.URL + "/wp-content/uploads/2019/05/photo.jpg",
+			GUID: server.URL + "/?attachment_id=7"},
+		{PostID: 8, PostType: "post", GUID: server.URL + "/?p=8"},
+	}
+
+	manifest, err := NewMediaDownloader(server.Client(), t.TempDir(), 4).DownloadSite(context.Background(), site)
+	if err != nil || len(manifest.Files) != 1 || manifest.Files[0].AttachmentID != 7 || manifest.Files[0].Status != DownloadDone {
+		t.Errorf("Expected the attachment to be downloaded, got %+v (%v)", manifest, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	manifest, err = NewMediaDownloader(server.Client(), t.TempDir(), 4).DownloadSite(ctx, site)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(manifest.Files) != 1 || !strings.Contains(manifest.Files[0].Error, "canceled") {
+		t.Errorf("Expected the file to be marked as cancelled, got %+v", manifest.Files)
+	}
+}
+
+// TestMediaDownloadPath tests where files are saved
+func TestMediaDownloadPath(t *testing.T) {
+	tests := map[string]string{
+		"https://oldsite.com/wp-content/uploads/2019/05/photo.jpg":         "uploads/2019/05/photo.jpg",
+		"https://oldsite.com/wp-content/uploads/sites/2/2019/05/photo.jpg": "uploads/sites/2/2019/05/photo.jpg",
+		"https://oldsite.com/files/2010/01/old.png":                        "uploads/files/2010/01/old.png",
+		"https://oldsite.com/wp-content/uploads/../../../etc/passwd":       "uploads/etc/passwd",
+	}
+	for url, want := range tests {
+		if got, err := mediaDownloadPath(url); err != nil || got != want {
+			t.Errorf("Expected %s for %s, got %s (%v)", want, url, got, err)
+		}
+	}
+	for _, url := range []string{"/wp-content/uploads/a.jpg", "https://oldsite.com/", "ftp://oldsite.com/a.jpg"} {
+		if _, err := mediaDownloadPath(url); err == nil {
+			t.Errorf("Expected an error for %s", url)
+		}
+	}
+}
@@ -0,0 +1,518 @@
+package wpimport
+
+import (
+	"html"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaInventory compares the attachments of a site with the media its
+// content, featured images, galleries and meta refer to
+type MediaInventory struct {
+	// Attachments lists every attachment with the items that use it,
+	// sorted by ID
+	Attachments []AttachmentUsage
+
+	// Orphans are the attachments nothing in the export refers to
+	Orphans []*Item
+
+	// Missing lists referenced media without an attachment item: upload
+	// URLs and IDs the export has no attachment for, and images loaded
+	// from other sites
+	Missing []MissingMedia
+
+	// Duplicates groups attachments that look like the same upload
+	Duplicates []DuplicateMedia
+
+	// CountByMIME counts the attachments per MIME type
+	CountByMIME map[string]int
+
+	// SizeByMIME totals the file size per MIME type. Attachments whose size
+	// is unknown only count in CountByMIME.
+	SizeByMIME map[string]int64
+}
+
+// AttachmentUsage describes one attachment and its use
+type AttachmentUsage struct {
+	Attachment *Item
+
+	// Path is the file's path below wp-content/uploads
+	Path string
+
+	// MIMEType is derived from the file extension
+	MIMEType string
+
+	// Size is the file size in bytes from the attachment metadata, or 0
+	// when the export does not record it
+	Size int64
+
+	// UsedBy holds the IDs of the items that refer to the attachment,
+	// sorted
+	UsedBy []int
+}
+
+// MissingMedia is media content refers to that has no attachment item
+type MissingMedia struct {
+	// URL is the media URL, or "" for a reference by attachment ID
+	URL string
+
+	// AttachmentID is the ID of a reference by ID, such as a featured
+	// image, that matches no attachment
+	AttachmentID int
+
+	// Hotlinked is true for media loaded from another site
+	Hotlinked bool
+
+	// UsedBy holds the IDs of the items that refer to the media, sorted
+	UsedBy []int
+}
+
+// DuplicateMedia is a group of attachments that share a file name or a
+// file size
+type DuplicateMedia struct {
+	// Reason is "filename" or "size"
+	Reason string
+
+	// Key is the shared file name or size
+	Key string
+
+	Attachments []*Item
+}
+
+var (
+	mediaImageClass   = regexp.MustCompile(`\bwp-image-(\d+)\b`)
+	mediaMetaURL      = regexp.MustCompile(`https?://[^\s"'<>\\]+`)
+	mediaFileSize     = regexp.MustCompile(`s:8:"filesize";i:(\d+);`)
+	mediaUploadSuffix = regexp.MustCompile(`-\d{1,2}$`)
+	mediaElement      = regexp.MustCompile(`(?i)<(img|source|video|audio|track|a)\b[^>]*>`)
+)
+
+// mediaIDBlocks maps the blocks that refer to an attachment by ID to the
+// attribute holding it
+var mediaIDBlocks = map[string]string{
+	"core/image": "id", "core/video": "id", "core/audio": "id", "core/file": "id",
+	"core/cover": "id", "core/media-text": "mediaId",
+}
+
+// mediaMIMETypes maps the extensions of common uploads to their MIME type,
+// so the result does not depend on the system's MIME tables
+var mediaMIMETypes = map[string]string{
+	".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".png": "image/png", ".gif": "image/gif",
+	".webp": "image/webp", ".avif": "image/avif", ".svg": "image/svg+xml", ".ico": "image/x-icon",
+	".heic": "image/heic", ".bmp": "image/bmp", ".tif": "image/tiff", ".tiff": "image/tiff",
+	".mp4": "video/mp4", ".m4v": "video/mp4", ".mov": "video/quicktime", ".webm": "video/webm",
+	".ogv": "video/ogg", ".avi": "video/x-msvideo", ".mp3": "audio/mpeg", ".m4a": "audio/mp4",
+	".ogg": "audio/ogg", ".wav": "audio/wav", ".flac": "audio/flac", ".pdf": "application/pdf",
+	".doc": "application/msword", ".zip": "application/zip", ".txt": "text/plain", ".csv": "text/csv",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// mediaRefsCollector gathers the media each item refers to
+type mediaRefsCollector struct {
+	mapper  *MediaMapper
+	byID    map[int]*Item
+	used    map[int]map[int]bool
+	missing map[string]*MissingMedia
+}
+
+// AnalyzeMedia builds an inventory of the site's media. Attachments are
+// found used through upload URLs (including resized variants) and
+// wp-image-N classes in content, image, gallery and media blocks,
+// [gallery] shortcodes, featured images, WooCommerce product galleries and
+// upload URLs in any other post meta.
+func (site *WordPressSite) AnalyzeMedia() *MediaInventory {
+	refs := &mediaRefsCollector{
+		mapper:  NewMediaMapper(site, nil),
+		byID:    make(map[int]*Item),
+		used:    make(map[int]map[int]bool),
+		missing: make(map[string]*MissingMedia),
+	}
+	for i := range site.Channel.Items {
+		if item := &site.Channel.Items[i]; item.PostType == "attachment" {
+			refs.byID[item.PostID] = item
+		}
+	}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostType != "attachment" && item.PostType != "revision" {
+			refs.collect(item)
+		}
+	}
+
+	inv := &MediaInventory{}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostType != "attachment" {
+			continue
+		}
+		usage := AttachmentUsage{Attachment: item, Path: attachmentPath(refs.mapper, item)}
+		usage.MIMEType = mediaMIMEType(usage.Path)
+		usage.Size = attachmentFileSize(item)
+		for id := range refs.used[item.PostID] {
+			usage.UsedBy = append(usage.UsedBy, id)
+		}
+		sort.Ints(usage.UsedBy)
+		if len(usage.UsedBy) == 0 {
+			inv.Orphans = append(inv.Orphans, item)
+		}
+		inv.Attachments = append(inv.Attachments, usage)
+	}
+	sort.SliceStable(inv.Attachments, func(i, j int) bool {
+		return inv.Attachments[i].Attachment.PostID < inv.Attachments[j].Attachment.PostID
+	})
+	sort.SliceStable(inv.Orphans, func(i, j int) bool { return inv.Orphans[i].PostID < inv.Orphans[j].PostID })
+
+	for _, missing := range refs.missing {
+		sort.Ints(missing.UsedBy)
+		inv.Missing = append(inv.Missing, *missing)
+	}
+	sort.Slice(inv.Missing, func(i, j int) bool {
+		a, b := inv.Missing[i], inv.Missing[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		return a.AttachmentID < b.AttachmentID
+	})
+
+	inv.summarize()
+	return inv
+}
+
+// AddSizes fills in the size of attachments from the manifest of a media
+// download, for exports that do not record file sizes, and updates the
+// totals and duplicates
+func (inv *MediaInventory) AddSizes(manifest *MediaManifest) {
+	byID := make(map[int]int64)
+	byPath := make(map[string]int64)
+	for _, file := range manifest.Files {
+		if file.Status == DownloadFailed || file.Size == 0 {
+			continue
+		}
+		if file.AttachmentID != 0 {
+			byID[file.AttachmentID] = file.Size
+		}
+		byPath[strings.TrimPrefix(file.Path, "uploads/")] = file.Size
+	}
+	for i := range inv.Attachments {
+		usage := &inv.Attachments[i]
+		if size, ok := byID[usage.Attachment.PostID]; ok {
+			usage.Size = size
+		} else if size, ok := byPath[usage.Path]; ok {
+			usage.Size = size
+		}
+	}
+	inv.summarize()
+}
+
+// Helper function to compute the totals and duplicates from Attachments
+func (inv *MediaInventory) summarize() {
+	inv.CountByMIME = make(map[string]int)
+	inv.SizeByMIME = make(map[string]int64)
+	byName := make(map[string][]*Item)
+	bySize := make(map[int64][]*Item)
+
+	// The sizes of the uploads by name, to tell a copy WordPress renamed
+	// ("photo-1.jpg") from a file that is named that way ("chapter-1.pdf")
+	sizes := make(map[string][]int64)
+	for _, usage := range inv.Attachments {
+		if name := uploadName(usage.Path); name != "" {
+			sizes[name] = append(sizes[name], usage.Size)
+		}
+	}
+
+	for _, usage := range inv.Attachments {
+		inv.CountByMIME[usage.MIMEType]++
+		if usage.Size > 0 {
+			inv.SizeByMIME[usage.MIMEType] += usage.Size
+			bySize[usage.Size] = append(bySize[usage.Size], usage.Attachment)
+		}
+		name := uploadName(usage.Path)
+		if name == "" {
+			continue
+		}
+		if original := uploadOriginalName(name); original != name && uploadSizeMatches(sizes[original], usage.Size) {
+			name = original
+		}
+		byName[name] = append(byName[name], usage.Attachment)
+	}
+
+	inv.Duplicates = nil
+	for name, items := range byName {
+		if len(items) > 1 {
+			inv.Duplicates = append(inv.Duplicates, DuplicateMedia{Reason: "filename", Key: name, Attachments: items})
+		}
+	}
+	for size, items := range bySize {
+		if len(items) > 1 {
+			inv.Duplicates = append(inv.Duplicates, DuplicateMedia{Reason: "size", Key: strconv.FormatInt(size, 10), Attachments: items})
+		}
+	}
+	sort.Slice(inv.Duplicates, func(i, j int) bool {
+		a, b := inv.Duplicates[i], inv.Duplicates[j]
+		if a.Reason != b.Reason {
+			return a.Reason < b.Reason
+		}
+		return a.Attachments[0].PostID < b.Attachments[0].PostID
+	})
+}
+
+// Helper function to record the media one item refers to
+func (refs *mediaRefsCollector) collect(item *Item) {
+	for _, file := range refs.contentFiles(item.Content) {
+		refs.useFile(item, file)
+	}
+	for _, m := range mediaImageClass.FindAllStringSubmatch(item.Content, -1) {
+		id, _ := strconv.Atoi(m[1])
+		refs.useID(item, id)
+	}
+	refs.hotlinks(item)
+
+	var walk func(blocks []Block)
+	walk = func(blocks []Block) {
+		for _, block := range blocks {
+			if attr, ok := mediaIDBlocks[block.Name]; ok {
+				refs.useID(item, block.IntAttr(attr))
+			}
+			if ids, ok := block.Attrs["ids"].([]interface{}); ok && block.Name == "core/gallery" {
+				for _, id := range ids {
+					if f, ok := id.(float64); ok {
+						refs.useID(item, int(f))
+					}
+				}
+			}
+			walk(block.InnerBlocks)
+		}
+	}
+	if hasBlockMarkup(item.Content) {
+		walk(ParseBlocks(item.Content))
+	}
+
+	var shortcodes func(content string)
+	shortcodes = func(content string) {
+		for _, sc := range ParseShortcodes(content) {
+			if sc.Name == "gallery" {
+				refs.useIDList(item, sc.Attr("ids", sc.Attr("include", "")))
+			}
+			if sc.Enclosing {
+				shortcodes(sc.Content)
+			}
+		}
+	}
+	shortcodes(item.Content)
+
+	for _, meta := range item.PostMeta {
+		switch meta.Key {
+		case "_thumbnail_id", "_product_image_gallery":
+			refs.useIDList(item, meta.Value)
+			continue
+		}
+		if !strings.Contains(meta.Value, "uploads") {
+			continue
+		}
+		for _, link := range mediaMetaURL.FindAllString(strings.ReplaceAll(meta.Value, `\/`, "/"), -1) {
+			if file, ok := refs.mapper.Match(link); ok {
+				refs.useFile(item, file)
+			}
+		}
+	}
+}
+
+// Helper function to find the upload files HTML refers to
+func (refs *mediaRefsCollector) contentFiles(content string) []MediaFile {
+	files := &mediaRefs{}
+	refs.mapper.rewriteHTML(content, files)
+	return files.files
+}
+
+// Helper function to record images and other media loaded from other
+// sites. Only media elements count: embeds such as <iframe> and scripts
+// are not media, and links (href) only when they point at a media file.
+func (refs *mediaRefsCollector) hotlinks(item *Item) {
+	for _, element := range mediaElement.FindAllStringSubmatch(item.Content, -1) {
+		link := strings.EqualFold(element[1], "a")
+		for _, m := range mediaAttribute.FindAllStringSubmatch(element[0], -1) {
+			name := strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(m[1]), "=")))
+			if (name == "href") != link || strings.HasSuffix(name, "-file") {
+				continue
+			}
+			value := html.UnescapeString(m[2] + m[3])
+			var urls []string
+			if strings.HasSuffix(name, "srcset") {
+				for _, candidate := range strings.Split(value, ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						urls = append(urls, fields[0])
+					}
+				}
+			} else {
+				urls = []string{strings.TrimSpace(value)}
+			}
+			for _, u := range urls {
+				if link && !mediaFileLink(u) {
+					continue
+				}
+				if (strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "//")) &&
+					!refs.mapper.IsUpload(u) && !refs.onSite(u) {
+					refs.miss(item, mediaKey(u, 0), MissingMedia{URL: u, Hotlinked: true})
+				}
+			}
+		}
+	}
+}
+
+// Helper function to tell whether a link points at an image, audio,
+// video or PDF file by its extension
+func mediaFileLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || path.Ext(u.Path) == "" {
+		return false
+	}
+	mimeType := mediaMIMEType(u.Path)
+	return strings.HasPrefix(mimeType, "image/") || strings.HasPrefix(mimeType, "audio/") ||
+		strings.HasPrefix(mimeType, "video/") || mimeType == "application/pdf"
+}
+
+// Helper function to check whether an absolute URL is on the site
+func (refs *mediaRefsCollector) onSite(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && refs.mapper.hosts[linkHost(u)]
+}
+
+// Helper function to record the use of an upload file
+func (refs *mediaRefsCollector) useFile(item *Item, file MediaFile) {
+	if file.Attachment != nil {
+		refs.use(item, file.Attachment.PostID)
+		return
+	}
+	refs.miss(item, mediaKey(file.Original, 0), MissingMedia{URL: file.URL})
+}
+
+// Helper function to record the use of an attachment by ID
+func (refs *mediaRefsCollector) useID(item *Item, id int) {
+	if id <= 0 {
+		return
+	}
+	if refs.byID[id] == nil {
+		refs.miss(item, mediaKey("", id), MissingMedia{AttachmentID: id})
+		return
+	}
+	refs.use(item, id)
+}
+
+// Helper function to record the use of a comma-separated list of IDs
+func (refs *mediaRefsCollector) useIDList(item *Item, ids string) {
+	for _, field := range strings.Split(ids, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			refs.useID(item, id)
+		}
+	}
+}
+
+// Helper function to add an item to the users of an attachment
+func (refs *mediaRefsCollector) use(item *Item, id int) {
+	if refs.used[id] == nil {
+		refs.used[id] = make(map[int]bool)
+	}
+	refs.used[id][item.PostID] = true
+}
+
+// Helper function to add an item to the users of missing media
+func (refs *mediaRefsCollector) miss(item *Item, key string, media MissingMedia) {
+	missing := refs.missing[key]
+	if missing == nil {
+		missing = &media
+		refs.missing[key] = missing
+	}
+	for _, id := range missing.UsedBy {
+		if id == item.PostID {
+			return
+		}
+	}
+	missing.UsedBy = append(missing.UsedBy, item.PostID)
+}
+
+// Helper function to key missing media by file or ID
+func mediaKey(file string, id int) string {
+	if file != "" {
+		return "file:" + file
+	}
+	return "id:" + strconv.Itoa(id)
+}
+
+// Helper function to get the path below wp-content/uploads of an
+// attachment's file
+func attachmentPath(m *MediaMapper, item *Item) string {
+	if file := item.GetMetaValue("_wp_attached_file"); file != "" {
+		return strings.TrimPrefix(file, "/")
+	}
+	if filePath, ok := m.uploadPath(item.GetAttachmentURL()); ok {
+		return filePath
+	}
+	return path.Base(item.GetAttachmentURL())
+}
+
+// Helper function to read the file size WordPress (6.0 and later) keeps
+// in the attachment metadata. The sizes of the resized copies come after
+// the file's own size, so the search stops there.
+func attachmentFileSize(item *Item) int64 {
+	metadata := item.GetMetaValue("_wp_attachment_metadata")
+	if i := strings.Index(metadata, `s:5:"sizes"`); i != -1 {
+		metadata = metadata[:i]
+	}
+	m := mediaFileSize.FindStringSubmatch(metadata)
+	if m == nil {
+		return 0
+	}
+	size, _ := strconv.ParseInt(m[1], 10, 64)
+	return size
+}
+
+// Helper function to get the MIME type of a file from its extension
+func mediaMIMEType(filePath string) string {
+	ext := strings.ToLower(path.Ext(filePath))
+	if mimeType, ok := mediaMIMETypes[ext]; ok {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// Helper function to get the file name of an upload without the suffix
+// WordPress adds to large images, e.g. "photo.jpg" for
+// "2020/01/photo-scaled.jpg"
+func uploadName(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	if name == "." || name == "/" {
+		return ""
+	}
+	ext := path.Ext(name)
+	return strings.TrimSuffix(strings.TrimSuffix(name, ext), "-scaled") + ext
+}
+
+// Helper function to get the name an upload had before WordPress made it
+// unique, e.g. "photo.jpg" for "photo-1.jpg". The result is only a guess,
+// as the number may be part of the name.
+func uploadOriginalName(name string) string {
+	ext := path.Ext(name)
+	return mediaUploadSuffix.ReplaceAllString(strings.TrimSuffix(name, ext), "") + ext
+}
+
+// Helper function to check that an upload with the given size can be a
+// copy of one of the uploads with the given sizes. Unknown sizes match.
+func uploadSizeMatches(sizes []int64, size int64) bool {
+	for _, s := range sizes {
+		if s == 0 || size == 0 || s == size {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package wpimport
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Helper function to build a site whose attachments are used in different
+// ways
+func mediaInventoryTestSite() *WordPressSite {
+	attachment := func(id int, file string, size string) Item {
+		item := Item{PostID: id, PostType: "attachment", AttachmentURL: "https://oldsite.com/wp-content/uploads/" + file,
+			PostMeta: []PostMeta{{Key: "_wp_attached_file", Value: file}}}
+		if size != "" {
+			item.PostMeta = append(item.PostMeta, PostMeta{Key: "_wp_attachment_metadata",
+				Value: `a:5:{s:5:"width";i:800;s:6:"height";i:600;s:4:"file";s:17:"` + file + `";s:8:"filesize";i:` + size +
+					`;s:5:"sizes";a:1:{s:9:"thumbnail";a:2:{s:4:"file";s:8:"t-150.jpg";s:8:"filesize";i:99;}}}`})
+		}
+		return item
+	}
+
+	site := &WordPressSite{}
+	site.Channel.BaseSiteURL = "https://oldsite.com"
+	site.Channel.Items = []Item{
+		attachment(10, "2019/05/content.jpg", "1000"),
+		attachment(11, "2019/05/featured.png", "2000"),
+		attachment(12, "2019/05/gallery.jpg", "500"),
+		attachment(13, "2019/05/block.jpg", ""),
+		attachment(14, "2019/06/orphan.pdf", "3000"),
+		attachment(15, "2020/01/content-1.jpg", "1000"),
+		attachment(16, "2020/01/meta.jpg", ""),
+		{PostID: 1, PostType: "post", Content: `<p><img class="wp-image-99" src="https://oldsite.com/wp-content/uploads/2019/05/content-300x200.jpg"></p>` +
+			`<p><img src="https://cdn.other.com/hotlinked.gif"> <a href="https://other.com/page">link</a> <a href="https://other.com/guide.pdf">guide</a></p>` +
+			`<iframe src="https://www.youtube.com/embed/abc123"></iframe><script src="https://cdn.other.com/widget.js"></script>` +
+			`<p><a href="/wp-content/uploads/2018/01/gone.zip">download</a></p>[gallery ids="12,15"]`,
+			PostMeta: []PostMeta{{Key: "_thumbnail_id", Value: "11"}}},
+		{PostID: 2, PostType: "page", Content: `<!-- wp:image {"id":13} --><figure class="wp-block-image"><img src="/elsewhere.jpg"></figure><!-- /wp:image -->`,
+			PostMeta: []PostMeta{{Key: "_thumbnail_id", Value: "404"}, {Key: "hero", Value: `{"url":"https:\/\/oldsite.com\/wp-content\/uploads\/2020\/01\/meta.jpg"}`}}},
+		{PostID: 3, PostType: "revision", Content: `<img src="https://oldsite.com/wp-content/uploads/2019/06/orphan.pdf">`},
+	}
+	return site
+}
+
+// TestAnalyzeMedia tests finding used, orphaned, missing and duplicate media
+func TestAnalyzeMedia(t *testing.T) {
+	inv := mediaInventoryTestSite().AnalyzeMedia()
+
+	usedBy := make(map[int][]int)
+	for _, usage := range inv.Attachments {
+		usedBy[usage.Attachment.PostID] = usage.UsedBy
+	}
+	expectedUse := map[int][]int{10: {1}, 11: {1}, 12: {1}, 13: {2}, 14: nil, 15: {1}, 16: {2}}
+	if !reflect.DeepEqual(usedBy, expectedUse) {
+		t.Errorf("Expected use %v, got %v", expectedUse, usedBy)
+	}
+
+	if len(inv.Orphans) != 1 || inv.Orphans[0].PostID != 14 {
+		t.Errorf("Expected attachment 14 to be the only orphan, got %v", inv.Orphans)
+	}
+
+	expectedMissing := []MissingMedia{
+		{URL: "", AttachmentID: 99, UsedBy: []int{1}},
+		{URL: "", AttachmentID: 404, UsedBy: []int{2}},
+		{URL: "/wp-content/uploads/2018/01/gone.zip", UsedBy: []int{1}},
+		{URL: "https://cdn.other.com/hotlinked.gif", Hotlinked: true, UsedBy: []int{1}},
+		{URL: "https://other.com/guide.pdf", Hotlinked: true, UsedBy: []int{1}},
+	}
+	if !reflect.DeepEqual(inv.Missing, expectedMissing) {
+		t.Errorf("Expected missing media %+v, got %+v", expectedMissing, inv.Missing)
+	}
+
+	if len(inv.Duplicates) != 2 {
+		t.Fatalf("Expected 2 duplicate groups, got %+v", inv.Duplicates)
+	}
+	if d := inv.Duplicates[0]; d.Reason != "filename" || d.Key != "content.jpg" || len(d.Attachments) != 2 {
+		t.Errorf("Unexpected filename duplicates: %+v", d)
+	}
+	if d := inv.Duplicates[1]; d.Reason != "size" || d.Key != "1000" || len(d.Attachments) != 2 {
+		t.Errorf("Unexpected size duplicates: %+v", d)
+	}
+
+	expectedSize := map[string]int64{"image/jpeg": 2500, "image/png": 2000, "application/pdf": 3000}
+	if !reflect.DeepEqual(inv.SizeByMIME, expectedSize) {
+		t.Errorf("Expected sizes %v, got %v", expectedSize, inv.SizeByMIME)
+	}
+	if inv.CountByMIME["image/jpeg"] != 5 {
+		t.Errorf("Expected 5 JPEG attachments, got %d", inv.CountByMIME["image/jpeg"])
+	}
+
+	// Sizes from a download fill the gaps
+	inv.AddSizes(&MediaManifest{Files: []MediaDownload{
+		{Path: "uploads/2019/05/block.jpg", Size: 700, Status: DownloadDone},
+		{Path: "uploads/2020/01/meta.jpg", AttachmentID: 16, Size: 800, Status: DownloadSkipped},
+	}})
+	if inv.SizeByMIME["image/jpeg"] != 4000 {
+		t.Errorf("Expected 4000 bytes of JPEG after AddSizes, got %d", inv.SizeByMIME["image/jpeg"])
+	}
+}
+
+// TestMediaDuplicateNames tests telling renamed copies from numbered files
+func TestMediaDuplicateNames(t *testing.T) {
+	inv := &MediaInventory{}
+	for i, upload := range []struct {
+		path string
+		size int64
+	}{
+		{"2019/05/chapter-1.pdf", 100}, {"2019/05/chapter-2.pdf", 100},
+		{"2019/05/img-01.jpg", 0}, {"2019/05/img-02.jpg", 0},
+		{"2019/05/photo.jpg", 500}, {"2020/01/photo-1.jpg", 500}, {"2020/01/photo-2.jpg", 0}, {"2020/02/photo-3.jpg", 900},
+	} {
+		inv.Attachments = append(inv.Attachments, AttachmentUsage{Attachment: &Item{PostID: i + 1}, Path: upload.path, MIMEType: mediaMIMEType(upload.path), Size: upload.size})
+	}
+	inv.summarize()
+
+	var names []DuplicateMedia
+	for _, d := range inv.Duplicates {
+		if d.Reason == "filename" {
+			names = append(names, d)
+		}
+	}
+	if len(names) != 1 || names[0].Key != "photo.jpg" || len(names[0].Attachments) != 3 {
+		t.Fatalf("Expected only the renamed photos of the same size, got %+v", names)
+	}
+	for _, item := range names[0].Attachments {
+		if item.PostID == 8 {
+			t.Error("Expected the photo of another size to be left out")
+		}
+	}
+}
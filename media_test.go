@@ -0,0 +1,97 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// Helper function to build a site with attachments for media tests
+func mediaTestSite() *WordPressSite {
+	site := &WordPressSite{}
+	site.Channel.BaseSiteURL = "https://oldsite.com"
+	site.Channel.Items = []Item{
+		{PostID: 1, PostType: "attachment", PostName: "photo", AttachmentURL: "https://oldsite.com/wp-content/uploads/2019/05/photo.jpg",
+			PostMeta: []PostMeta{{Key: "_wp_attached_file", Value: "2019/05/photo.jpg"}}},
+		{PostID: 2, PostType: "attachment", PostName: "big", AttachmentURL: "https://oldsite.com/wp-content/uploads/2020/01/big-scaled.jpg"},
+		{PostID: 3, PostType: "post", PostName: "post", Link: "https://oldsite.com/post/"},
+	}
+	return site
+}
+
+// TestMediaMatch tests recognising upload URLs and their variants
+func TestMediaMatch(t *testing.T) {
+	m := NewMediaMapper(mediaTestSite(), nil)
+
+	tests := []struct {
+		url      string
+		path     string
+		original string
+		width    int
+		id       int
+	}{
+		{"https://oldsite.com/wp-content/uploads/2019/05/photo.jpg", "2019/05/photo.jpg", "2019/05/photo.jpg", 0, 1},
+		{"/wp-content/uploads/2019/05/photo-300x200.jpg", "2019/05/photo-300x200.jpg", "2019/05/photo.jpg", 300, 1},
+		{"http://www.oldsite.com/wp-content/uploads/2019/05/photo-e1561234567890-150x150.jpg", "2019/05/photo-e1561234567890-150x150.jpg", "2019/05/photo.jpg", 150, 1},
+		{"https://i0.wp.com/oldsite.com/wp-content/uploads/2019/05/photo.jpg?resize=300%2C200", "2019/05/photo.jpg", "2019/05/photo.jpg", 0, 1},
+		{"https://oldsite.com/wp-content/uploads/2020/01/big-1024x768.jpg", "2020/01/big-1024x768.jpg", "2020/01/big-scaled.jpg", 1024, 2},
+		{"https://oldsite.com/wp-content/uploads/2018/01/unknown-100x100.png", "2018/01/unknown-100x100.png", "2018/01/unknown.png", 100, 0},
+	}
+	for _, test := range tests {
+		file, ok := m.Match(test.url)
+		if !ok {
+			t.Errorf("Expected %s to be recognised", test.url)
+			continue
+		}
+		id := 0
+		if file.Attachment != nil {
+			id = file.Attachment.PostID
+		}
+		if file.Path != test.path || file.Original != test.original || file.Width != test.width || id != test.id {
+			t.Errorf("Unexpected match for %s: %+v", test.url, file)
+		}
+	}
+
+	for _, url := range []string{"https://elsewhere.com/wp-content/uploads/a.jpg", "https://oldsite.com/post/", "mailto:a@oldsite.com"} {
+		if m.IsUpload(url) {
+			t.Errorf("Expected %s not to be an upload", url)
+		}
+	}
+}
+
+// TestMediaRewrite tests rewriting src, srcset and href and the manifest
+func TestMediaRewrite(t *testing.T) {
+	site := mediaTestSite()
+	m := NewMediaMapper(site, MediaPrefix("https://cdn.example.com/media/"))
+
+	content := `<a href="https://oldsite.com/wp-content/uploads/2019/05/photo.jpg"><img src="https://oldsite.com/wp-content/uploads/2019/05/photo-300x200.jpg" ` +
+		`srcset="https://oldsite.com/wp-content/uploads/2019/05/photo-300x200.jpg 300w, https://oldsite.com/wp-content/uploads/2019/05/photo.jpg 1200w" alt=""></a>` +
+		`<a href="https://oldsite.com/post/">Post</a>`
+
+	rewritten, files := m.RewriteHTML(content)
+	for _, want := range []string{
+		`href="https://cdn.example.com/media/2019/05/photo.jpg"`,
+		`src="https://cdn.example.com/media/2019/05/photo-300x200.jpg"`,
+		`srcset="https://cdn.example.com/media/2019/05/photo-300x200.jpg 300w, https://cdn.example.com/media/2019/05/photo.jpg 1200w"`,
+		`href="https://oldsite.com/post/"`,
+	} {
+		if !strings.Contains(rewritten, want) {
+			t.Errorf("Expected rewritten HTML to contain %s, got %s", want, rewritten)
+		}
+	}
+	if len(files) != 2 || files[0].Path != "2019/05/photo.jpg" || files[1].Path != "2019/05/photo-300x200.jpg" {
+		t.Errorf("Expected two files in order of appearance, got %+v", files)
+	}
+
+	// With a link resolver as well, upload URLs are left to the mapper
+	c := NewSiteConverter(site)
+	c.Media = NewMediaMapper(site, MediaPrefix("/static"))
+	c.Links = NewLinkResolver(site, func(item *Item) string { return "/" + item.PostName + "/" })
+	markdown := c.ToMarkdown(`<p><img src="/wp-content/uploads/2020/01/big-1024x768.jpg" alt="Big"> <a href="https://oldsite.com/post/">Post</a></p>`)
+	if !strings.Contains(markdown, "![Big](/static/2020/01/big-1024x768.jpg)") || !strings.Contains(markdown, "[Post](/post/)") {
+		t.Errorf("Expected media and links to be rewritten, got %s", markdown)
+	}
+	manifest := c.Media.Manifest()
+	if len(manifest) != 1 || manifest[0].Attachment == nil || manifest[0].Attachment.PostID != 2 {
+		t.Errorf("Expected the manifest to hold the scaled attachment, got %+v", manifest)
+	}
+}
@@ -0,0 +1,109 @@
+package wpimport
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PageTree follows the PostParent links of pages and other hierarchical
+// post types, so items can be placed at paths such as "about/team"
+type PageTree struct {
+	byID     map[int]*Item
+	children map[int][]*Item
+}
+
+// PageTree indexes the items of the site by their parent
+func (site *WordPressSite) PageTree() *PageTree {
+	t := &PageTree{
+		byID:     make(map[int]*Item),
+		children: make(map[int][]*Item),
+	}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostID != 0 {
+			t.byID[item.PostID] = item
+		}
+	}
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if parent := t.Parent(item); parent != nil {
+			t.children[parent.PostID] = append(t.children[parent.PostID], item)
+		}
+	}
+	return t
+}
+
+// Parent returns the item's parent, or nil for top-level items. Parents
+// of another post type, such as the post an attachment was uploaded to,
+// do not count.
+func (t *PageTree) Parent(item *Item) *Item {
+	parent := t.byID[item.PostParent]
+	if parent == nil || parent == item || parent.PostType != item.PostType {
+		return nil
+	}
+	return parent
+}
+
+// Children returns the items whose parent is item, in export order
+func (t *PageTree) Children(item *Item) []*Item {
+	return t.children[item.PostID]
+}
+
+// Ancestors returns the item's parent, grandparent and so on, starting
+// from the top-level item. A loop of parents ends where it repeats.
+func (t *PageTree) Ancestors(item *Item) []*Item {
+	var ancestors []*Item
+	seen := map[*Item]bool{item: true}
+	for parent := t.Parent(item); parent != nil && !seen[parent]; parent = t.Parent(parent) {
+		seen[parent] = true
+		ancestors = append([]*Item{parent}, ancestors...)
+	}
+	return ancestors
+}
+
+// Path returns the slugs of the item's ancestors and the item itself,
+// joined by "/", e.g. "about/team"
+func (t *PageTree) Path(item *Item) string {
+	var slugs []string
+	for _, ancestor := range t.Ancestors(item) {
+		slugs = append(slugs, itemSlug(ancestor))
+	}
+	return strings.Join(append(slugs, itemSlug(item)), "/")
+}
+
+// Helper function to get an item's slug: its post name, or for items
+// without one (such as drafts) its title or ID
+func itemSlug(item *Item) string {
+	name := item.PostName
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if slug := slugify(name); slug != "" {
+		return slug
+	}
+	if slug := slugify(item.Title); slug != "" {
+		return slug
+	}
+	return strconv.Itoa(item.PostID)
+}
+
+// Helper function to make a slug from text: lowercase letters and digits,
+// with anything else between them turned into single dashes
+func slugify(text string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
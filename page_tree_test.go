@@ -0,0 +1,32 @@
+package wpimport
+
+import "testing"
+
+// TestPageTree tests paths built from page parents
+func TestPageTree(t *testing.T) {
+	site := &WordPressSite{}
+	site.Channel.Items = []Item{
+		{PostID: 1, PostType: "page", PostName: "about"},
+		{PostID: 2, PostType: "page", PostName: "team", PostParent: 1},
+		{PostID: 3, PostType: "page", PostName: "%e6%97%a5%e6%9c%ac", PostParent: 2},
+		{PostID: 4, PostType: "page", Title: "Draft Page!", PostParent: 1},
+		{PostID: 5, PostType: "attachment", PostName: "photo", PostParent: 2},
+		{PostID: 6, PostType: "page", PostName: "loop-a", PostParent: 7},
+		{PostID: 7, PostType: "page", PostName: "loop-b", PostParent: 6},
+	}
+	tree := site.PageTree()
+
+	tests := map[int]string{1: "about", 2: "about/team", 3: "about/team/日本", 4: "about/draft-page", 5: "photo", 6: "loop-b/loop-a"}
+	for id, want := range tests {
+		if got := tree.Path(&site.Channel.Items[id-1]); got != want {
+			t.Errorf("Expected path %s for item %d, got %s", want, id, got)
+		}
+	}
+
+	if children := tree.Children(&site.Channel.Items[0]); len(children) != 2 || children[0].PostID != 2 || children[1].PostID != 4 {
+		t.Errorf("Expected pages 2 and 4 as children of 1, got %v", children)
+	}
+	if tree.Parent(&site.Channel.Items[4]) != nil {
+		t.Error("Expected an attachment not to have a page as parent")
+	}
+}
@@ -0,0 +1,259 @@
+package wpimport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnserializePHP decodes a value written by PHP's serialize(), as
+// WordPress stores arrays and objects in post, comment and term meta.
+// Null becomes nil, booleans bool, integers int64, floats float64 and
+// strings string. Arrays with the keys 0, 1, 2... become []interface{},
+// other arrays and objects map[string]interface{} with their keys as
+// strings; the class names of objects and the visibility prefixes of
+// their properties are dropped.
+//
+// String lengths are byte counts, which exports whose encoding was
+// changed after serializing often get wrong. When a length does not end at
+// the closing quote, the string runs to the next `";` instead.
+func UnserializePHP(data string) (interface{}, error) {
+	p := &phpUnserializer{data: data}
+	value, err := p.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(strings.TrimRight(data, " \t\r\n")) {
+		return nil, fmt.Errorf("unexpected data after serialized value at offset %d", p.pos)
+	}
+	return value, nil
+}
+
+// IsSerializedPHP reports whether s looks like a PHP serialized value, as
+// WordPress' is_serialized() decides
+func IsSerializedPHP(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "N;" {
+		return true
+	}
+	if len(s) < 4 || s[1] != ':' {
+		return false
+	}
+	switch s[0] {
+	case 'a', 'O':
+		return strings.HasSuffix(s, "}")
+	case 's':
+		return strings.HasSuffix(s, `";`)
+	case 'b', 'i', 'd':
+		return strings.HasSuffix(s, ";")
+	}
+	return false
+}
+
+// DecodedValue returns the meta value unserialized when it is a PHP
+// serialized value, and the value itself otherwise
+func (m PostMeta) DecodedValue() interface{} {
+	return decodeMetaValue(m.Value)
+}
+
+// Helper function to unserialize a meta value when it is serialized,
+// keeping the raw value when it does not decode
+func decodeMetaValue(value string) interface{} {
+	if !IsSerializedPHP(value) {
+		return value
+	}
+	decoded, err := UnserializePHP(strings.TrimSpace(value))
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// phpUnserializer reads serialized values from data
+type phpUnserializer struct {
+	data string
+	pos  int
+}
+
+// phpMaxDepth bounds the nesting of arrays and objects
+const phpMaxDepth = 512
+
+// Helper function to read one value
+func (p *phpUnserializer) value(depth int) (interface{}, error) {
+	if depth > phpMaxDepth {
+		return nil, fmt.Errorf("serialized value nested too deeply at offset %d", p.pos)
+	}
+	if p.pos+1 >= len(p.data) {
+		return nil, fmt.Errorf("unexpected end of serialized data")
+	}
+	kind := p.data[p.pos]
+	if kind == 'N' {
+		if err := p.expect("N;"); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if err := p.expect(string(kind) + ":"); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case 'b':
+		token, err := p.until(';')
+		if err != nil {
+			return nil, err
+		}
+		return token == "1", nil
+	case 'i':
+		token, err := p.until(';')
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q at offset %d", token, p.pos)
+		}
+		return n, nil
+	case 'd':
+		token, err := p.until(';')
+		if err != nil {
+			return nil, err
+		}
+		switch token {
+		case "INF", "-INF", "NAN":
+			return token, nil
+		}
+		f, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q at offset %d", token, p.pos)
+		}
+		return f, nil
+	case 's':
+		s, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		return s, p.expect(";")
+	case 'a':
+		return p.array(depth)
+	case 'O':
+		if _, err := p.str(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		return p.array(depth)
+	}
+	return nil, fmt.Errorf("unsupported serialized type %q at offset %d", kind, p.pos-2)
+}
+
+// Helper function to read the length-prefixed, quoted string of s: and
+// O: values, up to the closing quote
+func (p *phpUnserializer) str() (string, error) {
+	token, err := p.until(':')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(token)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("invalid string length %q at offset %d", token, p.pos)
+	}
+	if err := p.expect(`"`); err != nil {
+		return "", err
+	}
+	start := p.pos
+	end := start + n
+	if end+1 >= len(p.data) || p.data[end] != '"' || (p.data[end+1] != ';' && p.data[end+1] != ':') {
+		// The length is off; take the string up to the next `";`
+		i := strings.Index(p.data[start:], `";`)
+		if i == -1 {
+			return "", fmt.Errorf("unterminated string at offset %d", start)
+		}
+		end = start + i
+	}
+	p.pos = end + 1
+	return p.data[start:end], nil
+}
+
+// Helper function to read the n:{key;value...} body of an array or object
+func (p *phpUnserializer) array(depth int) (interface{}, error) {
+	token, err := p.until(':')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(token)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid element count %q at offset %d", token, p.pos)
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	// The count comes from the data, so it only sizes the slices as far as
+	// the rest of the data can hold elements of at least 4 bytes ("N;N;")
+	size := min(n, (len(p.data)-p.pos)/4)
+	keys := make([]string, 0, size)
+	values := make([]interface{}, 0, size)
+	list := true
+	for i := 0; i < n; i++ {
+		key, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		var name string
+		switch k := key.(type) {
+		case int64:
+			name = strconv.FormatInt(k, 10)
+			list = list && k == int64(i)
+		case string:
+			// Private and protected properties are prefixed with
+			// \0Class\0 and \0*\0
+			if j := strings.LastIndexByte(k, 0); j != -1 {
+				k = k[j+1:]
+			}
+			name = k
+			list = false
+		default:
+			return nil, fmt.Errorf("invalid array key at offset %d", p.pos)
+		}
+		value, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, name)
+		values = append(values, value)
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	if list {
+		return values, nil
+	}
+	m := make(map[string]interface{}, n)
+	for i, key := range keys {
+		m[key] = values[i]
+	}
+	return m, nil
+}
+
+// Helper function to read the text up to a delimiter, consuming it
+func (p *phpUnserializer) until(delimiter byte) (string, error) {
+	i := strings.IndexByte(p.data[p.pos:], delimiter)
+	if i == -1 {
+		return "", fmt.Errorf("expected %q after offset %d", delimiter, p.pos)
+	}
+	token := p.data[p.pos : p.pos+i]
+	p.pos += i + 1
+	return token, nil
+}
+
+// Helper function to consume an exact piece of text
+func (p *phpUnserializer) expect(text string) error {
+	if !strings.HasPrefix(p.data[p.pos:], text) {
+		return fmt.Errorf("expected %q at offset %d", text, p.pos)
+	}
+	p.pos += len(text)
+	return nil
+}
@@ -0,0 +1,65 @@
+package wpimport
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestUnserializePHP tests decoding PHP serialized values
+func TestUnserializePHP(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"null", "N;", nil},
+		{"bool", "b:1;", true},
+		{"int", "i:-42;", int64(-42)},
+		{"float", "d:1.5;", 1.5},
+		{"string", `s:5:"hello";`, "hello"},
+		{"multibyte string", `s:6:"café!";`, "café!"},
+		{"string with quotes", `s:9:"say "hi";";`, `say "hi";`},
+		{"list", `a:2:{i:0;s:1:"a";i:1;s:1:"b";}`, []interface{}{"a", "b"}},
+		{"empty array", "a:0:{}", []interface{}{}},
+		{"map", `a:2:{s:5:"width";i:300;s:5:"sizes";a:1:{s:5:"thumb";s:9:"thumb.jpg";}}`,
+			map[string]interface{}{"width": int64(300), "sizes": map[string]interface{}{"thumb": "thumb.jpg"}}},
+		{"sparse keys", `a:2:{i:1;s:1:"a";i:5;s:1:"b";}`, map[string]interface{}{"1": "a", "5": "b"}},
+		{"object", "O:8:\"stdClass\":2:{s:4:\"name\";s:3:\"Bob\";s:6:\"\x00*\x00age\";i:40;}",
+			map[string]interface{}{"name": "Bob", "age": int64(40)}},
+		{"wrong length", `a:1:{s:5:"title";s:3:"Café au lait";}`, map[string]interface{}{"title": "Café au lait"}},
+	}
+	for _, tt := range tests {
+		got, err := UnserializePHP(tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tt.name, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"", "x:1;", "i:abc;", `s:5:"hello`, "a:2:{i:0;i:1;}", "i:1;extra", "b:1",
+		// A huge count must not be used to allocate
+		"a:99999999999999:{}", `O:8:"stdClass":99999999999999:{}`} {
+		if _, err := UnserializePHP(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+// TestDecodedMetaValue tests that only serialized values are decoded
+func TestDecodedMetaValue(t *testing.T) {
+	if got := (PostMeta{Value: "plain text"}).DecodedValue(); got != "plain text" {
+		t.Errorf("Expected plain text to be kept, got %#v", got)
+	}
+	if got := (PostMeta{Value: "a:1:{i:0;s:3:\"one\";}\n"}).DecodedValue(); !reflect.DeepEqual(got, []interface{}{"one"}) {
+		t.Errorf("Expected a decoded list, got %#v", got)
+	}
+	if got := (PostMeta{Value: "a:3:{broken}"}).DecodedValue(); got != "a:3:{broken}" {
+		t.Errorf("Expected a broken value to be kept, got %#v", got)
+	}
+	if IsSerializedPHP("i:5") || !IsSerializedPHP(" b:0; ") {
+		t.Error("Unexpected result from IsSerializedPHP")
+	}
+}
@@ -0,0 +1,262 @@
+package wpimport
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlainTextOptions controls plain text output. The zero value strips
+// markup the way ConvertToPlainText always has.
+type PlainTextOptions struct {
+	// Width hard-wraps lines at this many terminal columns, counting wide
+	// (CJK) characters as two. Preformatted text and tables are not
+	// wrapped. Zero disables wrapping.
+	Width int
+
+	// LinkReferences writes links as "text [1]" and lists the URLs in a
+	// numbered references section at the end
+	LinkReferences bool
+
+	// UnderlineHeadings underlines <h1> headings with "=" and other
+	// headings with "-"
+	UnderlineHeadings bool
+
+	// QuoteBlockquotes prefixes each line of a blockquote with "> "
+	QuoteBlockquotes bool
+
+	// ImageAltText describes images by their alt text, e.g. "[Image: A
+	// red bicycle]". Images without alt or title text are left out.
+	ImageAltText bool
+}
+
+var (
+	plainTextLink  = regexp.MustCompile(`(?is)<a\b[^>]*>(.*?)</a>`)
+	plainTextImage = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	plainTextTags  = regexp.MustCompile(`<[^>]*>`)
+	plainTextBreak = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// plainTextClosing lists characters that should not start a wrapped line
+// when breaking between wide characters
+const plainTextClosing = "、。，．：；！？）」』】〉》"
+
+// ConvertToPlainTextWithOptions converts WordPress content to plain text
+// using the given options
+func ConvertToPlainTextWithOptions(content string, opts PlainTextOptions) string {
+	c := NewConverter()
+	c.PlainText = opts
+	return c.ToPlainText(content)
+}
+
+// Helper function to convert an HTML fragment to plain text. Lists,
+// tables, figures and the block elements covered by the plain text
+// options are rendered first and kept in verbatim; the caller restores
+// them.
+func (c *Converter) plainText(fragment string, verbatim *verbatimStore) string {
+	fragment = c.replaceTextBlocks(fragment, verbatim)
+	fragment = c.replaceLists(fragment, FormatPlainText, verbatim)
+	fragment = c.replaceTables(fragment, FormatPlainText, verbatim)
+	fragment = c.replaceFigures(fragment, FormatPlainText, verbatim)
+	if c.PlainText.ImageAltText {
+		fragment = plainTextImage.ReplaceAllStringFunc(fragment, func(tag string) string {
+			img := parseImage(tag)
+			if img.Alt == "" && img.Title == "" {
+				return ""
+			}
+			return plainTextMediaReference("Image", mediaItem{Alt: img.Alt, Title: img.Title})
+		})
+	}
+
+	text := convertHTMLToPlainText(fragment)
+	if c.PlainText.Width > 0 {
+		text = wrapText(text, c.PlainText.Width, verbatim.measure)
+	}
+	return text
+}
+
+// Helper function to get a converter for plain text that is indented by
+// n columns, so it wraps n columns earlier
+func (c *Converter) indented(n int) *Converter {
+	if c.PlainText.Width == 0 {
+		return c
+	}
+	indented := *c
+	indented.PlainText.Width = max(c.PlainText.Width-n, 1)
+	return &indented
+}
+
+// Helper function to replace the outermost headings, blockquotes and
+// preformatted blocks that the plain text options render specially.
+// Lists and tables are left to their own passes, which convert their
+// content with this converter again.
+func (c *Converter) replaceTextBlocks(content string, verbatim *verbatimStore) string {
+	opts := c.PlainText
+	if !opts.UnderlineHeadings && !opts.QuoteBlockquotes && opts.Width == 0 {
+		return content
+	}
+
+	var found []*htmlNode
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		for _, child := range n.Children {
+			switch child.Tag {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				if opts.UnderlineHeadings {
+					found = append(found, child)
+					continue
+				}
+			case "blockquote":
+				if opts.QuoteBlockquotes {
+					found = append(found, child)
+					continue
+				}
+			case "pre":
+				if opts.Width > 0 {
+					found = append(found, child)
+				}
+				continue
+			case "ul", "ol", "table":
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(parseHTML(content))
+
+	var sb strings.Builder
+	pos := 0
+	for _, n := range found {
+		var rendered string
+		switch n.Tag {
+		case "blockquote":
+			rendered = quoteLines(c.indented(2).fragmentToPlainText(n.InnerHTML()))
+		case "pre":
+			text := plainTextTags.ReplaceAllString(plainTextBreak.ReplaceAllString(n.InnerHTML(), "\n"), "")
+			rendered = strings.Trim(html.UnescapeString(text), "\n")
+		default:
+			rendered = underline(c.fragmentToPlainText(n.InnerHTML()), n.Tag == "h1")
+		}
+		sb.WriteString(content[pos:n.Start])
+		sb.WriteString("\n\n" + verbatim.protect(rendered) + "\n\n")
+		pos = n.End
+	}
+	sb.WriteString(content[pos:])
+	return sb.String()
+}
+
+// Helper function to prefix every line of text with "> "
+func quoteLines(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Helper function to underline a heading with "=" (for the top level) or
+// "-" as wide as its longest line
+func underline(text string, top bool) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	width := 0
+	for _, line := range strings.Split(text, "\n") {
+		width = max(width, displayWidth(line))
+	}
+	rule := "-"
+	if top {
+		rule = "="
+	}
+	return text + "\n" + strings.Repeat(rule, width)
+}
+
+// Helper function to write links as "text [n]". It returns the numbered
+// URLs; a URL linked several times keeps its first number. Links whose
+// text is the URL itself and links within the page are left as text.
+func plainTextLinks(content string) (string, []string) {
+	var urls []string
+	numbers := make(map[string]int)
+	content = plainTextLink.ReplaceAllStringFunc(content, func(match string) string {
+		m := plainTextLink.FindStringSubmatch(match)
+		href := html.UnescapeString(getHTMLAttribute(match[:strings.Index(match, ">")+1], "href"))
+		text := strings.TrimSpace(html.UnescapeString(plainTextTags.ReplaceAllString(m[1], "")))
+		if href == "" || strings.HasPrefix(href, "#") || text == href || "mailto:"+text == href {
+			return m[1]
+		}
+		n, ok := numbers[href]
+		if !ok {
+			urls = append(urls, href)
+			n = len(urls)
+			numbers[href] = n
+		}
+		return m[1] + " [" + strconv.Itoa(n) + "]"
+	})
+	return content, urls
+}
+
+// Helper function to write the references section for plainTextLinks
+func plainTextReferences(urls []string) string {
+	var sb strings.Builder
+	sb.WriteString("References:")
+	for i, url := range urls {
+		sb.WriteString("\n[" + strconv.Itoa(i+1) + "] " + url)
+	}
+	return sb.String()
+}
+
+// Helper function to hard-wrap text at width columns. Lines are broken
+// at spaces and between wide characters, and keep their indentation.
+// measure returns the width of some text, so that placeholders count as the
+// text they stand for. Words wider than the line are not broken.
+func wrapText(text string, width int, measure func(string) int) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	for _, line := range lines {
+		if measure(line) <= width {
+			out = append(out, line)
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		current, currentWidth := indent, displayWidth(indent)
+		empty := true
+		for _, word := range strings.Fields(line) {
+			for i, piece := range wrapPieces(word) {
+				pieceWidth := measure(piece)
+				gap := 0
+				if i == 0 && !empty {
+					gap = 1
+				}
+				if !empty && currentWidth+gap+pieceWidth > width {
+					out = append(out, current)
+					current, currentWidth, gap = indent, displayWidth(indent), 0
+				}
+				current += strings.Repeat(" ", gap) + piece
+				currentWidth += gap + pieceWidth
+				empty = false
+			}
+		}
+		out = append(out, current)
+	}
+	return strings.Join(out, "\n")
+}
+
+// Helper function to split a word where a line may break inside it,
+// which is between wide characters
+func wrapPieces(word string) []string {
+	var pieces []string
+	start := 0
+	prevWide := false
+	for i, r := range word {
+		wide := runeWidth(r) == 2
+		if i > start && (wide || prevWide) && !strings.ContainsRune(plainTextClosing, r) {
+			pieces = append(pieces, word[start:i])
+			start = i
+		}
+		prevWide = wide
+	}
+	return append(pieces, word[start:])
+}
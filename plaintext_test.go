@@ -0,0 +1,79 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPlainTextOptions tests link references, headings, blockquotes,
+// images and wrapping together
+func TestPlainTextOptions(t *testing.T) {
+	content := `<h1>Weekly digest</h1><p>Read the <a href="https://example.com/post">latest post</a> and the <a href="https://example.com/docs">docs</a> before <a href="https://example.com/post">Friday</a>. See <a href="https://example.com">https://example.com</a> too.</p>
+<h2>Quotes</h2><blockquote><p>A long quotation that certainly goes past the wrapping width of forty columns.</p><blockquote>Nested</blockquote></blockquote>
+<p><img src="a.png" alt="A red bicycle"> <img src="spacer.gif"></p>
+<ul><li>An item that is long enough to need wrapping inside the list indentation</li><li>Short<ol><li>Nested item also quite long to wrap around the edge</li></ol></li></ul>
+<pre>code   stays    as it is even when it is very long and would otherwise wrap</pre>`
+
+	want := `Weekly digest
+=============
+
+Read the latest post [1] and the docs
+[2] before Friday [1]. See
+https://example.com too.
+
+Quotes
+------
+
+> A long quotation that certainly goes
+> past the wrapping width of forty
+> columns.
+>
+> > Nested
+
+[Image: A red bicycle]
+
+• An item that is long enough to need
+  wrapping inside the list indentation
+• Short
+  1. Nested item also quite long to wrap
+     around the edge
+
+code   stays    as it is even when it is very long and would otherwise wrap
+
+References:
+[1] https://example.com/post
+[2] https://example.com/docs`
+
+	got := ConvertToPlainTextWithOptions(content, PlainTextOptions{
+		Width:             40,
+		LinkReferences:    true,
+		UnderlineHeadings: true,
+		QuoteBlockquotes:  true,
+		ImageAltText:      true,
+	})
+	if got != want {
+		t.Errorf("Expected formatted plain text:\n%s\ngot:\n%s", want, got)
+	}
+
+	// The zero options keep the plain conversion
+	plain := ConvertToPlainText(content)
+	if strings.Contains(plain, "[1]") || strings.Contains(plain, "=====") || strings.Contains(plain, "> ") {
+		t.Errorf("Expected no plain text options by default, got:\n%s", plain)
+	}
+}
+
+// TestWrapText tests wrapping with wide characters and indentation. A
+// closing mark never starts a line.
+func TestWrapText(t *testing.T) {
+	got := wrapText("日本語のテキストは空白がなくても、正しく折り返されるべきです。", 20, displayWidth)
+	want := "日本語のテキストは空\n白がなくても、正しく\n折り返されるべきで\nす。"
+	if got != want {
+		t.Errorf("Expected CJK text to wrap between characters:\n%s\ngot:\n%s", want, got)
+	}
+
+	got = wrapText("  indented words wrap under the indent", 16, displayWidth)
+	want = "  indented words\n  wrap under the\n  indent"
+	if got != want {
+		t.Errorf("Expected indentation to be kept:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,415 @@
+package wpimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedirectFormat is the server or host configuration a redirect map is
+// written as
+type RedirectFormat int
+
+const (
+	// RedirectNginx is an nginx map of $request_uri to the new URL
+	RedirectNginx RedirectFormat = iota
+	// RedirectApache is mod_rewrite rules for an .htaccess file
+	RedirectApache
+	// RedirectNetlify is a Netlify (or Cloudflare Pages) _redirects file
+	RedirectNetlify
+	// RedirectVercel is the redirects of a vercel.json file
+	RedirectVercel
+	// RedirectCloudflare is a Cloudflare Bulk Redirects list as JSON
+	RedirectCloudflare
+)
+
+// Redirect sends an old URL to its new home
+type Redirect struct {
+	// From is the path and query of the old URL, e.g. "/2019/05/hello/"
+	// or "/?p=10"
+	From string
+
+	// To is the new URL
+	To string
+
+	// Source is where the old URL came from: "link" for the URL the site
+	// used, "guid", "id" for ?p=, ?page_id= and ?attachment_id= links, and
+	// "old_slug" for permalinks from before a post was renamed
+	Source string
+
+	// URL is the public URL of the old site the redirect belongs to
+	URL SiteURL
+}
+
+// RedirectCollision is an old URL claimed by more than one page. The
+// first target wins; old URLs of pages that still live at them are never
+// redirected.
+type RedirectCollision struct {
+	From    string
+	Targets []string
+}
+
+// RedirectMap holds the redirects of a migration and the problems found
+// building them
+type RedirectMap struct {
+	Redirects []Redirect
+
+	// Chains are redirects whose target is itself redirected, from the old
+	// URL to the final one. They are shortened to point at the final URL.
+	Chains [][]string
+
+	// Loops are redirects that lead back to where they started. They are
+	// left out.
+	Loops [][]string
+
+	// Collisions are old URLs with more than one target
+	Collisions []RedirectCollision
+
+	host   string
+	hosts  map[string]bool
+	byItem map[*Item][]string
+}
+
+// NewRedirectMap builds the redirects from the site's public URLs to the
+// ones newURL returns. Each item is also redirected from its GUID, from
+// links by ID and from the permalinks of its old slugs (_wp_old_slug).
+// URLs newURL returns "" for, and URLs that do not change, are not
+// redirected.
+func NewRedirectMap(site *WordPressSite, newURL func(SiteURL) string) *RedirectMap {
+	m := &RedirectMap{hosts: siteHosts(site), byItem: make(map[*Item][]string)}
+	if u, err := url.Parse(siteHomeURL(site)); err == nil {
+		m.host = u.Host
+	}
+
+	type target struct {
+		to  string
+		url SiteURL
+	}
+	var froms []string
+	targets := make(map[string]target)
+	sources := make(map[string]string)
+	live := make(map[string]string)
+	collisions := make(map[string]int)
+	add := func(rawFrom, source string, u SiteURL, to string) {
+		from, ok := m.key(rawFrom)
+		if !ok {
+			return
+		}
+		if toKey, ok := m.key(to); ok && toKey == from {
+			live[from] = to
+			return
+		}
+		existing, ok := targets[from]
+		if current, isLive := live[from]; ok || isLive {
+			if ok && existing.to == to {
+				return
+			}
+			first := current
+			if ok {
+				first = existing.to
+			}
+			if i, seen := collisions[from]; seen {
+				m.Collisions[i].Targets = uniqueStrings(append(m.Collisions[i].Targets, to))
+			} else {
+				collisions[from] = len(m.Collisions)
+				m.Collisions = append(m.Collisions, RedirectCollision{From: from, Targets: uniqueStrings([]string{first, to})})
+			}
+			return
+		}
+		froms = append(froms, from)
+		targets[from] = target{to: to, url: u}
+		sources[from] = source
+	}
+
+	// Old URLs are added by priority, so a page's current URL wins over
+	// another page's GUID, ID link or old slug
+	urls := site.PublicURLs()
+	news := make([]string, len(urls))
+	for i, u := range urls {
+		if newURL != nil {
+			news[i] = newURL(u)
+		}
+	}
+	for i, u := range urls {
+		if news[i] != "" {
+			add(u.URL, "link", u, news[i])
+		}
+	}
+	for i, u := range urls {
+		if news[i] == "" || u.Item == nil {
+			continue
+		}
+		add(u.Item.GUID, "guid", u, news[i])
+		id := strconv.Itoa(u.Item.PostID)
+		add("/?p="+id, "id", u, news[i])
+		switch u.Item.PostType {
+		case "page":
+			add("/?page_id="+id, "id", u, news[i])
+		case "attachment":
+			add("/?attachment_id="+id, "id", u, news[i])
+		}
+	}
+	for i, u := range urls {
+		if news[i] == "" || u.Item == nil {
+			continue
+		}
+		for _, meta := range u.Item.PostMeta {
+			if meta.Key == "_wp_old_slug" && meta.Value != "" {
+				if old := oldSlugURL(u.URL, meta.Value); old != "" {
+					add(old, "old_slug", u, news[i])
+				}
+			}
+		}
+	}
+
+	// Follow targets that are redirected themselves
+	for _, from := range froms {
+		chain := []string{from}
+		seen := map[string]bool{from: true}
+		to := targets[from].to
+		loop := false
+		for {
+			key, ok := m.key(to)
+			next, redirected := targets[key]
+			if !ok || !redirected {
+				break
+			}
+			chain = append(chain, to)
+			if seen[key] {
+				loop = true
+				break
+			}
+			seen[key] = true
+			to = next.to
+		}
+		if loop {
+			m.Loops = append(m.Loops, chain)
+			continue
+		}
+		if len(chain) > 1 {
+			m.Chains = append(m.Chains, append(chain, to))
+		}
+		m.Redirects = append(m.Redirects, Redirect{From: from, To: to, Source: sources[from], URL: targets[from].url})
+		if item := targets[from].url.Item; item != nil && !strings.Contains(from, "?") {
+			m.byItem[item] = append(m.byItem[item], from)
+		}
+	}
+	return m
+}
+
+// Helper function to get the path and query an old URL is matched by.
+// URLs on other hosts have none.
+func (m *RedirectMap) key(rawURL string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || rawURL == "" || (u.Host != "" && !m.hosts[linkHost(u)]) {
+		return "", false
+	}
+	key := u.EscapedPath()
+	if key == "" {
+		key = "/"
+	}
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, true
+}
+
+// Helper function to get the permalink an item had under an old slug: its
+// link with the last path segment replaced
+func oldSlugURL(link, slug string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery != "" || strings.Trim(u.Path, "/") == "" {
+		return ""
+	}
+	trailing := strings.HasSuffix(u.Path, "/")
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	segments[len(segments)-1] = slug
+	u.Path = "/" + strings.Join(segments, "/")
+	if trailing {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+// Aliases returns the old paths redirected to an item, without query
+// strings, for Hugo's aliases
+func (m *RedirectMap) Aliases(item *Item) []string {
+	return append([]string(nil), m.byItem[item]...)
+}
+
+// Write writes the redirects in a format. Netlify and Cloudflare cannot
+// match query strings, so redirects like /?p=10 are listed as comments in
+// _redirects and left out of Cloudflare lists.
+func (m *RedirectMap) Write(w io.Writer, format RedirectFormat) error {
+	switch format {
+	case RedirectNginx:
+		return m.writeNginx(w)
+	case RedirectApache:
+		return m.writeApache(w)
+	case RedirectNetlify:
+		return m.writeNetlify(w)
+	case RedirectVercel:
+		return m.writeVercel(w)
+	case RedirectCloudflare:
+		return m.writeCloudflare(w)
+	}
+	return fmt.Errorf("unknown redirect format: %d", format)
+}
+
+// Helper function to write an nginx map. $request_uri includes the
+// query string, so ID links match exactly.
+func (m *RedirectMap) writeNginx(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# Include in the http block, then redirect in the server block:\n")
+	sb.WriteString("#     if ($redirect_uri) { return 301 $redirect_uri; }\n")
+	sb.WriteString("map $request_uri $redirect_uri {\n")
+	for _, r := range m.Redirects {
+		fmt.Fprintf(&sb, "    %s %s;\n", nginxString(r.From), nginxString(strings.ReplaceAll(r.To, "$", "%24")))
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Helper function to quote a string for nginx. Map values cannot escape
+// "$", which starts a variable, so targets have it percent-encoded.
+func nginxString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// apacheSpecial are the characters escaped in mod_rewrite substitutions
+var apacheSpecial = regexp.MustCompile(`[\\$%\s"]`)
+
+// Helper function to write mod_rewrite rules. Rules match the decoded
+// path without its leading slash, and query strings by RewriteCond.
+func (m *RedirectMap) writeApache(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("<IfModule mod_rewrite.c>\nRewriteEngine On\n")
+	for _, r := range m.Redirects {
+		path, query, _ := strings.Cut(r.From, "?")
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.TrimPrefix(path, "/")), " ", `\s`)
+		to := apacheSpecial.ReplaceAllStringFunc(r.To, func(c string) string {
+			if c == " " {
+				return "%20"
+			}
+			return `\` + c
+		})
+		flags := "R=301,L,NE"
+		if query != "" {
+			fmt.Fprintf(&sb, "RewriteCond %%{QUERY_STRING} ^%s$\n", strings.ReplaceAll(regexp.QuoteMeta(query), " ", `\s`))
+			flags += ",QSD"
+		}
+		fmt.Fprintf(&sb, "RewriteRule ^%s$ %s [%s]\n", pattern, to, flags)
+	}
+	sb.WriteString("</IfModule>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Helper function to write a _redirects file
+func (m *RedirectMap) writeNetlify(w io.Writer) error {
+	var sb strings.Builder
+	for _, r := range m.Redirects {
+		if strings.Contains(r.From, "?") {
+			fmt.Fprintf(&sb, "# Cannot match the query string of %s -> %s\n", r.From, r.To)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s  %s  301\n", r.From, r.To)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// vercelRedirect is a redirect in vercel.json
+type vercelRedirect struct {
+	Source      string        `json:"source"`
+	Has         []vercelQuery `json:"has,omitempty"`
+	Destination string        `json:"destination"`
+	Permanent   bool          `json:"permanent"`
+}
+
+type vercelQuery struct {
+	Type  string `json:"type"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// vercelSpecial are the characters with a meaning in Vercel's source
+// patterns
+var vercelSpecial = regexp.MustCompile(`[:()*+?{}\\]`)
+
+// Helper function to write the redirects of a vercel.json file, matching
+// query strings by their parameters
+func (m *RedirectMap) writeVercel(w io.Writer) error {
+	doc := struct {
+		Redirects []vercelRedirect `json:"redirects"`
+	}{Redirects: []vercelRedirect{}}
+	for _, r := range m.Redirects {
+		path, query, _ := strings.Cut(r.From, "?")
+		redirect := vercelRedirect{
+			Source:      vercelSpecial.ReplaceAllString(path, `\$0`),
+			Destination: r.To,
+			Permanent:   true,
+		}
+		if values, err := url.ParseQuery(query); err == nil {
+			for _, key := range sortedQueryKeys(values) {
+				redirect.Has = append(redirect.Has, vercelQuery{Type: "query", Key: key, Value: values.Get(key)})
+			}
+		}
+		doc.Redirects = append(doc.Redirects, redirect)
+	}
+	return writeRedirectJSON(w, doc)
+}
+
+// Helper function to list the keys of a query in a stable order
+func sortedQueryKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cloudflareRedirect is an item of a Bulk Redirects list
+type cloudflareRedirect struct {
+	Redirect struct {
+		SourceURL  string `json:"source_url"`
+		TargetURL  string `json:"target_url"`
+		StatusCode int    `json:"status_code"`
+	} `json:"redirect"`
+}
+
+// Helper function to write a Bulk Redirects list, whose source URLs
+// include the old host
+func (m *RedirectMap) writeCloudflare(w io.Writer) error {
+	list := []cloudflareRedirect{}
+	for _, r := range m.Redirects {
+		if strings.Contains(r.From, "?") {
+			continue
+		}
+		var redirect cloudflareRedirect
+		redirect.Redirect.SourceURL = m.host + r.From
+		redirect.Redirect.TargetURL = r.To
+		redirect.Redirect.StatusCode = 301
+		list = append(list, redirect)
+	}
+	return writeRedirectJSON(w, list)
+}
+
+// Helper function to write indented JSON without escaping HTML characters
+func writeRedirectJSON(w io.Writer, doc interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,196 @@
+package wpimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Helper function to map the old URLs of the test site to new paths:
+// items by slug, archives and the home page by path
+func redirectTestURL(u SiteURL) string {
+	if u.Item != nil {
+		if u.Item.PostName == "" {
+			return ""
+		}
+		return "/" + u.Type + "/" + u.Item.PostName + "/"
+	}
+	return strings.TrimPrefix(u.URL, "https://oldsite.com")
+}
+
+// Helper function to index redirects by their old URL
+func redirectsByFrom(m *RedirectMap) map[string]Redirect {
+	redirects := make(map[string]Redirect)
+	for _, r := range m.Redirects {
+		redirects[r.From] = r
+	}
+	return redirects
+}
+
+// TestRedirectMap tests the old URLs that are redirected
+func TestRedirectMap(t *testing.T) {
+	site := feedTestSite()
+	site.Channel.Items[0].PostMeta = append(site.Channel.Items[0].PostMeta,
+		PostMeta{Key: "_wp_old_slug", Value: "hello"}, PostMeta{Key: "_wp_old_slug", Value: "hi-there"})
+	site.Channel.Items[4].GUID = "https://oldsite.com/?p=10"
+
+	m := NewRedirectMap(site, redirectTestURL)
+	redirects := redirectsByFrom(m)
+	expected := map[string]string{
+		"/2019/05/hello-world/": "/post/hello-world/",
+		"/?p=10":                "/post/hello-world/",
+		"/2019/05/hello/":       "/post/hello-world/",
+		"/2019/05/hi-there/":    "/post/hello-world/",
+		"/about/":               "/page/about/",
+		"/?page_id=20":          "/page/about/",
+		"/?p=20":                "/page/about/",
+		"/?attachment_id=30":    "/attachment/cover/",
+		"/?p=22":                "/book/dune/",
+	}
+	for from, to := range expected {
+		if redirects[from].To != to {
+			t.Errorf("Expected %s to redirect to %s, got %+v", from, to, redirects[from])
+		}
+	}
+	if r := redirects["/2019/05/hello/"]; r.Source != "old_slug" || r.URL.Item != &site.Channel.Items[0] {
+		t.Errorf("Unexpected old slug redirect: %+v", r)
+	}
+
+	// Archives and the book keep their paths, and items without a new
+	// URL stay
+	for _, from := range []string{"/category/news/", "/", "/book/dune/", "/?p=43"} {
+		if r, ok := redirects[from]; ok {
+			t.Errorf("Expected no redirect from %s, got %+v", from, r)
+		}
+	}
+
+	// The book's GUID claims the post's ID link
+	if len(m.Collisions) != 1 || m.Collisions[0].From != "/?p=10" || strings.Join(m.Collisions[0].Targets, " ") != "/post/hello-world/ /book/dune/" {
+		t.Errorf("Unexpected collisions: %+v", m.Collisions)
+	}
+
+	aliases := m.Aliases(&site.Channel.Items[0])
+	if strings.Join(aliases, " ") != "/2019/05/hello-world/ /2019/05/hello/ /2019/05/hi-there/" {
+		t.Errorf("Unexpected aliases: %v", aliases)
+	}
+}
+
+// TestRedirectChains tests shortening chains and dropping loops
+func TestRedirectChains(t *testing.T) {
+	site := &WordPressSite{}
+	site.Channel.Link = "https://oldsite.com"
+	page := func(id int, name string) Item {
+		return Item{PostID: id, PostType: "page", Status: "publish", PostName: name, Link: "https://oldsite.com/" + name + "/"}
+	}
+	site.Channel.Items = []Item{page(1, "a"), page(2, "b"), page(3, "c"), page(4, "x"), page(5, "y"), page(6, "kept")}
+	site.Channel.Items[2].PostMeta = []PostMeta{{Key: "_wp_old_slug", Value: "kept"}}
+	next := map[string]string{"a": "/b/", "b": "https://oldsite.com/c/", "c": "/new/", "x": "/y/", "y": "/x/", "kept": "/kept/"}
+
+	m := NewRedirectMap(site, func(u SiteURL) string {
+		if u.Item == nil {
+			return ""
+		}
+		return next[u.Item.PostName]
+	})
+	redirects := redirectsByFrom(m)
+	if redirects["/a/"].To != "/new/" || redirects["/b/"].To != "/new/" || redirects["/c/"].To != "/new/" {
+		t.Errorf("Expected chains to point at the final URL, got %+v", m.Redirects)
+	}
+	if len(m.Chains) != 6 || strings.Join(m.Chains[0], " ") != "/a/ /b/ https://oldsite.com/c/ /new/" {
+		t.Errorf("Unexpected chains: %v", m.Chains)
+	}
+	if len(m.Loops) != 6 || redirects["/x/"].To != "" || redirects["/y/"].To != "" {
+		t.Errorf("Expected the loop between /x/ and /y/ to be dropped, got %v", m.Loops)
+	}
+
+	// A page that keeps its URL is not redirected, and the old slug of
+	// another page cannot claim it
+	if _, ok := redirects["/kept/"]; ok || len(m.Collisions) != 1 || strings.Join(m.Collisions[0].Targets, " ") != "/kept/ /new/" {
+		t.Errorf("Unexpected redirects for /kept/: %+v", m.Collisions)
+	}
+}
+
+// TestRedirectFormats tests writing the map for each server and host
+func TestRedirectFormats(t *testing.T) {
+	site := &WordPressSite{}
+	site.Channel.Link = "https://oldsite.com"
+	site.Channel.Items = []Item{{PostID: 7, PostType: "post", Status: "publish", PostName: "caf%c3%a9",
+		Link: "https://oldsite.com/2020/caf%c3%a9/"}}
+	m := NewRedirectMap(site, func(u SiteURL) string {
+		if u.Item == nil {
+			return ""
+		}
+		return "https://newsite.com/blog/caf%C3%A9/?ref=old$1"
+	})
+
+	tests := []struct {
+		format   RedirectFormat
+		expected []string
+	}{
+		{RedirectNginx, []string{
+			"map $request_uri $redirect_uri {",
+			`    "/2020/caf%c3%a9/" "https://newsite.com/blog/caf%C3%A9/?ref=old%241";`,
+			`    "/?p=7" "https://newsite.com/blog/caf%C3%A9/?ref=old%241";`,
+		}},
+		{RedirectApache, []string{
+			"RewriteEngine On",
+			`RewriteRule ^2020/café/$ https://newsite.com/blog/caf\%C3\%A9/?ref=old\$1 [R=301,L,NE]`,
+			"RewriteCond %{QUERY_STRING} ^p=7$\nRewriteRule ^$ https://newsite.com/blog/caf\\%C3\\%A9/?ref=old\\$1 [R=301,L,NE,QSD]",
+		}},
+		{RedirectNetlify, []string{
+			"/2020/caf%c3%a9/  https://newsite.com/blog/caf%C3%A9/?ref=old$1  301\n",
+			"# Cannot match the query string of /?p=7",
+		}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := m.Write(&buf, test.format); err != nil {
+			t.Fatalf("Write(%d) failed: %v", test.format, err)
+		}
+		for _, s := range test.expected {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("Expected format %d to contain %q, got:\n%s", test.format, s, buf.String())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	m.Write(&buf, RedirectVercel)
+	var vercel struct {
+		Redirects []vercelRedirect `json:"redirects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &vercel); err != nil || len(vercel.Redirects) != 2 {
+		t.Fatalf("Unexpected vercel.json: %s (%v)", buf.String(), err)
+	}
+	if r := vercel.Redirects[1]; r.Source != "/" || len(r.Has) != 1 || r.Has[0].Key != "p" || r.Has[0].Value != "7" || !r.Permanent {
+		t.Errorf("Expected the query matched by has, got %+v", r)
+	}
+
+	buf.Reset()
+	m.Write(&buf, RedirectCloudflare)
+	var list []cloudflareRedirect
+	if err := json.Unmarshal(buf.Bytes(), &list); err != nil || len(list) != 1 || list[0].Redirect.SourceURL != "oldsite.com/2020/caf%c3%a9/" || list[0].Redirect.StatusCode != 301 {
+		t.Errorf("Unexpected bulk redirects: %s (%v)", buf.String(), err)
+	}
+
+	if err := m.Write(&buf, RedirectFormat(9)); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+// TestHugoExportRedirects tests aliases from a redirect map
+func TestHugoExportRedirects(t *testing.T) {
+	site := exportTestSite()
+	site.Channel.Items[0].PostMeta = append(site.Channel.Items[0].PostMeta, PostMeta{Key: "_wp_old_slug", Value: "hello"})
+
+	dir := t.TempDir()
+	exporter := NewHugoExporter()
+	exporter.Redirects = NewRedirectMap(site, func(u SiteURL) string { return "/posts/hello-world/" })
+	if err := exporter.Export(site, dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if post := readExport(t, dir, "content/posts/hello-world/index.md"); !strings.Contains(post, `aliases: ["/2019/05/hello-world/", "/2019/05/hello/"]`) {
+		t.Errorf("Expected the old permalinks as aliases, got:\n%s", post)
+	}
+}
@@ -0,0 +1,95 @@
+package wpimport
+
+import (
+	"sort"
+	"strings"
+)
+
+// GetReusableBlocks returns all reusable blocks (synced patterns) in the
+// export, which are stored as items of type wp_block
+func (site *WordPressSite) GetReusableBlocks() []Item {
+	return site.GetPostsByType("wp_block")
+}
+
+// ExpandReusableBlocks replaces <!-- wp:block {"ref":N} /--> references with
+// the content of the referenced wp_block item. Nested references are
+// expanded too. References that cannot be expanded, because the block is
+// not in the export or refers back to a block that is already being
+// expanded, are left in place. The IDs of references that could not be
+// found in the export are returned in order of first appearance.
+func (site *WordPressSite) ExpandReusableBlocks(content string) (string, []int) {
+	var missing []int
+	seen := make(map[int]bool)
+	expanded := site.expandReusableBlocks(content, map[int]bool{}, func(ref int, cycle bool) {
+		if !cycle && !seen[ref] {
+			seen[ref] = true
+			missing = append(missing, ref)
+		}
+	})
+	return expanded, missing
+}
+
+// FindMissingReusableBlocks reports, per item ID, the reusable block
+// references that point at blocks not present in the export
+func (site *WordPressSite) FindMissingReusableBlocks() map[int][]int {
+	report := make(map[int][]int)
+	for _, item := range site.Channel.Items {
+		if !strings.Contains(item.Content, "wp:block") {
+			continue
+		}
+		if _, missing := site.ExpandReusableBlocks(item.Content); len(missing) > 0 {
+			sort.Ints(missing)
+			report[item.PostID] = missing
+		}
+	}
+	return report
+}
+
+// Helper function to expand references, tracking the blocks on the current
+// expansion path for cycle protection. Unresolved references are passed to
+// report, with cycle set for references back into the path.
+func (site *WordPressSite) expandReusableBlocks(content string, active map[int]bool, report func(ref int, cycle bool)) string {
+	if !strings.Contains(content, "wp:block") {
+		return content
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for pos < len(content) {
+		delim, ok := nextBlockDelimiter(content, pos)
+		if !ok {
+			break
+		}
+		sb.WriteString(content[pos:delim.start])
+		pos = delim.end
+
+		if delim.name != "core/block" || delim.closer {
+			sb.WriteString(content[delim.start:delim.end])
+			continue
+		}
+
+		ref := (&Block{Attrs: delim.attrs}).IntAttr("ref")
+		if ref == 0 {
+			continue
+		}
+		if active[ref] {
+			sb.WriteString(content[delim.start:delim.end])
+			report(ref, true)
+			continue
+		}
+
+		block := site.GetPostByID(ref)
+		if block == nil || block.PostType != "wp_block" {
+			sb.WriteString(content[delim.start:delim.end])
+			report(ref, false)
+			continue
+		}
+
+		active[ref] = true
+		sb.WriteString(site.expandReusableBlocks(block.Content, active, report))
+		delete(active, ref)
+	}
+	sb.WriteString(content[pos:])
+
+	return sb.String()
+}
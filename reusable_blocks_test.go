@@ -0,0 +1,80 @@
+package wpimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExpandReusableBlocks tests expansion of core/block references
+func TestExpandReusableBlocks(t *testing.T) {
+	site := &WordPressSite{Channel: Channel{Items: []Item{
+		{PostID: 1, PostType: "post", Content: `<!-- wp:paragraph --><p>Before</p><!-- /wp:paragraph -->
+<!-- wp:block {"ref":10} /-->
+<!-- wp:block {"ref":99} /-->`},
+		{PostID: 10, PostType: "wp_block", Content: `<!-- wp:paragraph --><p>Shared CTA</p><!-- /wp:paragraph -->
+<!-- wp:block {"ref":11} /-->`},
+		// 11 refers back to 10, which must not loop forever
+		{PostID: 11, PostType: "wp_block", Content: `<!-- wp:paragraph --><p>Nested</p><!-- /wp:paragraph -->
+<!-- wp:block {"ref":10} /-->`},
+	}}}
+
+	expanded, missing := site.ExpandReusableBlocks(site.Channel.Items[0].Content)
+	if !strings.Contains(expanded, "Shared CTA") || !strings.Contains(expanded, "Nested") {
+		t.Errorf("Expected reusable block content to be expanded, got:\n%s", expanded)
+	}
+	if strings.Count(expanded, "Shared CTA") != 1 {
+		t.Errorf("Expected cycle to be broken, got:\n%s", expanded)
+	}
+	if !reflect.DeepEqual(missing, []int{99}) {
+		t.Errorf("Expected missing refs [99], got %v", missing)
+	}
+
+	report := site.FindMissingReusableBlocks()
+	if !reflect.DeepEqual(report, map[int][]int{1: {99}}) {
+		t.Errorf("Expected missing report for post 1, got %v", report)
+	}
+
+	c := NewSiteConverter(site)
+	if md := c.ToMarkdown(site.Channel.Items[0].Content); !strings.Contains(md, "Shared CTA") {
+		t.Errorf("Expected Markdown to include reusable block, got:\n%s", md)
+	}
+	if text := c.ToPlainText(site.Channel.Items[0].Content); !strings.Contains(text, "Nested") {
+		t.Errorf("Expected plain text to include nested reusable block, got:\n%s", text)
+	}
+	if cleaned := c.ToHTML(site.Channel.Items[0].Content); !strings.Contains(cleaned, "<p>Shared CTA</p>") {
+		t.Errorf("Expected HTML to include reusable block, got:\n%s", cleaned)
+	}
+}
+
+// TestUnresolvedReusableBlocks tests reporting references that cannot be
+// expanded
+func TestUnresolvedReusableBlocks(t *testing.T) {
+	site := &WordPressSite{Channel: Channel{Items: []Item{
+		{PostID: 10, PostType: "wp_block", Content: `<!-- wp:paragraph --><p>Loop</p><!-- /wp:paragraph -->
+<!-- wp:block {"ref":10} /-->`},
+	}}}
+	content := `<!-- wp:block {"ref":10} /-->
+<!-- wp:block {"ref":99} /-->`
+
+	expanded, _ := site.ExpandReusableBlocks(content)
+	if !strings.Contains(expanded, `<!-- wp:block {"ref":99} /-->`) || strings.Count(expanded, `{"ref":10}`) != 1 {
+		t.Errorf("Expected unresolved references to be left in place, got:\n%s", expanded)
+	}
+
+	var missing, cycles []int
+	c := NewSiteConverter(site)
+	c.UnresolvedBlock = func(ref int, cycle bool) {
+		if cycle {
+			cycles = append(cycles, ref)
+		} else {
+			missing = append(missing, ref)
+		}
+	}
+	if md := c.ToMarkdown(content); md != "Loop" {
+		t.Errorf("Expected the block once, got %q", md)
+	}
+	if !reflect.DeepEqual(missing, []int{99}) || !reflect.DeepEqual(cycles, []int{10}) {
+		t.Errorf("Expected missing [99] and cycles [10], got %v and %v", missing, cycles)
+	}
+}
@@ -0,0 +1,251 @@
+package wpimport
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// SanitizePolicy decides which HTML survives sanitizing. Anything not on
+// an allowlist is removed: disallowed elements are unwrapped so their text
+// stays, except for elements such as <script> whose content is never
+// shown. Comments are always removed.
+//
+// Event handler attributes (on*) and style are never kept unless listed,
+// and URLs are checked against URLSchemes wherever they appear.
+type SanitizePolicy struct {
+	// Elements maps each allowed element to the attributes allowed on it
+	Elements map[string][]string
+
+	// GlobalAttributes are allowed on every allowed element
+	GlobalAttributes []string
+
+	// URLSchemes lists the schemes allowed in URL attributes such as href
+	// and src. Relative URLs are always allowed.
+	URLSchemes []string
+
+	// IframeHosts lists the hosts an allowed <iframe> may load from
+	// (subdomains included). Iframes from other hosts, or over plain
+	// HTTP, are removed.
+	IframeHosts []string
+
+	// LinkRel, when set, is added to the rel attribute of every link,
+	// e.g. "nofollow noopener"
+	LinkRel string
+}
+
+// sanitizeDropContent lists elements removed together with their content
+// when they are not allowed
+var sanitizeDropContent = map[string]bool{
+	"script": true, "style": true, "template": true, "noscript": true, "iframe": true,
+	"object": true, "embed": true, "applet": true, "frame": true, "frameset": true,
+	"noframes": true, "noembed": true, "textarea": true, "select": true, "title": true,
+	"head": true, "svg": true, "math": true, "xmp": true,
+}
+
+// sanitizeURLAttributes lists the attributes that hold a URL
+var sanitizeURLAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true,
+	"poster": true, "background": true, "longdesc": true, "usemap": true,
+}
+
+var (
+	sanitizeURLScheme = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+	sanitizeURLIgnore = regexp.MustCompile(`[\x00-\x20\x7f]+`)
+)
+
+// StrictCommentPolicy allows the markup WordPress accepts in comments
+// from visitors: basic inline formatting, quotes and links, with links
+// marked nofollow
+func StrictCommentPolicy() *SanitizePolicy {
+	return &SanitizePolicy{
+		Elements: map[string][]string{
+			"a": {"href", "title"}, "abbr": {"title"}, "acronym": {"title"}, "b": nil,
+			"blockquote": {"cite"}, "br": nil, "cite": nil, "code": nil, "del": {"datetime"},
+			"em": nil, "i": nil, "p": nil, "q": {"cite"}, "s": nil, "strike": nil, "strong": nil,
+		},
+		URLSchemes: []string{"http", "https", "mailto"},
+		LinkRel:    "nofollow noopener",
+	}
+}
+
+// PostBodyPolicy allows the markup of post content written by the
+// site's authors: text structure, lists, tables, images, figures, audio
+// and video. Scripts, iframes, forms, styles and event handlers are
+// removed.
+func PostBodyPolicy() *SanitizePolicy {
+	cell := []string{"colspan", "rowspan", "scope", "headers", "align"}
+	return &SanitizePolicy{
+		Elements: map[string][]string{
+			"a": {"href", "target", "rel", "name", "hreflang"}, "abbr": nil, "address": nil,
+			"article": nil, "aside": nil, "audio": {"src", "controls", "preload", "loop", "muted"},
+			"b": nil, "blockquote": {"cite"}, "br": nil, "caption": nil, "cite": nil, "code": nil,
+			"col": {"span"}, "colgroup": {"span"}, "dd": nil, "del": {"datetime", "cite"},
+			"details": {"open"}, "dfn": nil, "div": nil, "dl": nil, "dt": nil, "em": nil,
+			"figcaption": nil, "figure": nil, "footer": nil, "h1": nil, "h2": nil, "h3": nil,
+			"h4": nil, "h5": nil, "h6": nil, "header": nil, "hr": nil, "i": nil,
+			"img": {"src", "srcset", "sizes", "alt", "width", "height", "loading"},
+			"ins": {"datetime", "cite"}, "kbd": nil, "li": {"value"}, "mark": nil,
+			"ol": {"start", "reversed", "type"}, "p": nil, "pre": nil, "q": {"cite"}, "s": nil,
+			"samp": nil, "section": nil, "small": nil,
+			"source": {"src", "srcset", "type", "media", "sizes"}, "span": nil, "strike": nil,
+			"strong": nil, "sub": nil, "summary": nil, "sup": nil, "table": nil, "tbody": nil,
+			"td": cell, "tfoot": nil, "th": cell, "thead": nil, "time": {"datetime"}, "tr": nil,
+			"track": {"src", "kind", "srclang", "label", "default"}, "u": nil, "ul": nil,
+			"var": nil, "video": {"src", "poster", "controls", "width", "height", "preload", "loop", "muted", "playsinline"},
+			"wbr": nil,
+		},
+		GlobalAttributes: []string{"id", "class", "title", "lang", "dir"},
+		URLSchemes:       []string{"http", "https", "mailto", "tel"},
+	}
+}
+
+// UGCEmbedPolicy extends StrictCommentPolicy for user-generated content
+// that may hold lists, code, images and embeds. Iframes are kept only
+// from the providers in DefaultEmbeds, and the blockquotes that tweet and
+// Instagram embeds use keep their class.
+func UGCEmbedPolicy() *SanitizePolicy {
+	p := StrictCommentPolicy()
+	for tag, attrs := range map[string][]string{
+		"ul": nil, "ol": {"start", "reversed"}, "li": nil, "pre": nil,
+		"img":        {"src", "alt", "width", "height"},
+		"blockquote": {"cite", "class"},
+		"iframe":     {"src", "width", "height", "title", "allow", "allowfullscreen", "frameborder"},
+	} {
+		p.Elements[tag] = attrs
+	}
+	p.IframeHosts = []string{
+		"youtube.com", "youtube-nocookie.com", "player.vimeo.com", "open.spotify.com",
+		"w.soundcloud.com", "instagram.com", "platform.twitter.com",
+	}
+	return p
+}
+
+// SanitizedContent returns the comment's content as HTML that is safe to
+// show: line breaks become paragraphs like WordPress displays them, and
+// the markup is limited by StrictCommentPolicy
+func (c *Comment) SanitizedContent() string {
+	return StrictCommentPolicy().Sanitize(Autop(c.Content))
+}
+
+// Sanitize removes everything the policy does not allow from content. The
+// result is well-formed: text is escaped and open elements are closed.
+func (p *SanitizePolicy) Sanitize(content string) string {
+	var sb strings.Builder
+	for _, child := range parseHTML(content).Children {
+		p.write(&sb, child)
+	}
+	return sb.String()
+}
+
+// Helper function to write a node as the policy allows it
+func (p *SanitizePolicy) write(sb *strings.Builder, n *htmlNode) {
+	switch n.Tag {
+	case "":
+		sb.WriteString(html.EscapeString(html.UnescapeString(n.Text)))
+		return
+	case "!--":
+		return
+	}
+
+	allowed, ok := p.Elements[n.Tag]
+	if ok && n.Tag == "iframe" && !p.allowIframe(n.Attr("src")) {
+		return
+	}
+	if !ok || htmlRawTextElements[n.Tag] {
+		if !sanitizeDropContent[n.Tag] {
+			for _, child := range n.Children {
+				p.write(sb, child)
+			}
+		}
+		return
+	}
+
+	sb.WriteString("<" + n.Tag)
+	var rel []string
+	for _, attr := range n.Attrs {
+		if !p.allowAttr(allowed, attr) {
+			continue
+		}
+		if attr.Name == "rel" && p.LinkRel != "" && n.Tag == "a" {
+			rel = append(rel, strings.Fields(attr.Value)...)
+			continue
+		}
+		sb.WriteString(" " + attr.Name + `="` + html.EscapeString(attr.Value) + `"`)
+	}
+	if n.Tag == "a" && p.LinkRel != "" && n.HasAttr("href") {
+		for _, token := range strings.Fields(p.LinkRel) {
+			if !containsFold(rel, token) {
+				rel = append(rel, token)
+			}
+		}
+		sb.WriteString(` rel="` + html.EscapeString(strings.Join(rel, " ")) + `"`)
+	}
+	sb.WriteString(">")
+
+	if htmlVoidElements[n.Tag] {
+		return
+	}
+	if n.Tag != "iframe" {
+		for _, child := range n.Children {
+			p.write(sb, child)
+		}
+	}
+	sb.WriteString("</" + n.Tag + ">")
+}
+
+// Helper function to check an attribute against the allowlists and, for
+// URL attributes, the allowed schemes
+func (p *SanitizePolicy) allowAttr(allowed []string, attr htmlAttr) bool {
+	if !containsFold(allowed, attr.Name) && !containsFold(p.GlobalAttributes, attr.Name) {
+		return false
+	}
+	switch {
+	case sanitizeURLAttributes[attr.Name]:
+		return p.allowURL(attr.Value)
+	case attr.Name == "srcset":
+		for _, candidate := range strings.Split(attr.Value, ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 && !p.allowURL(fields[0]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Helper function to check a URL's scheme. Browsers ignore whitespace
+// and control characters inside a scheme, so those are removed first.
+func (p *SanitizePolicy) allowURL(value string) bool {
+	m := sanitizeURLScheme.FindStringSubmatch(sanitizeURLIgnore.ReplaceAllString(value, ""))
+	return m == nil || containsFold(p.URLSchemes, m[1])
+}
+
+// Helper function to check an iframe source against IframeHosts
+func (p *SanitizePolicy) allowIframe(src string) bool {
+	if strings.HasPrefix(src, "//") {
+		src = "https:" + src
+	}
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range p.IframeHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to check whether list holds s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSanitizeComment tests the strict comment policy against common
+// script injection tricks
+func TestSanitizeComment(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`Nice <b>post</b><script>alert(1)</script>`, `Nice <b>post</b>`},
+		{`<img src=x onerror=alert(1)>Hi`, `Hi`},
+		{`<a href="javascript:alert(1)" onclick="x()">link</a>`, `<a rel="nofollow noopener">link</a>`},
+		{`<a href="jav&#x09;ascript:alert(1)">tab</a>`, `<a rel="nofollow noopener">tab</a>`},
+		{`<a href="https://example.com/?a=1&amp;b=2" rel="ugc" title="t">ok</a>`, `<a href="https://example.com/?a=1&amp;b=2" title="t" rel="nofollow noopener">ok</a>`},
+		{`<a href="/relative">rel</a>`, `<a href="/relative" rel="nofollow noopener">rel</a>`},
+		{`<div style="color:red">Unwrapped <iframe src="https://evil.example"></iframe></div>`, `Unwrapped `},
+		{`<svg><script>alert(1)</script></svg>x<!-- note -->`, `x`},
+		{`1 < 2 & <em>3 > 2`, `1 &lt; 2 &amp; <em>3 &gt; 2</em>`},
+	}
+	policy := StrictCommentPolicy()
+	for _, test := range tests {
+		if got := policy.Sanitize(test.input); got != test.want {
+			t.Errorf("Expected %q to sanitize to %q, got %q", test.input, test.want, got)
+		}
+	}
+
+	comment := Comment{Content: "First line\nsecond <script>x</script>line\n\nNew paragraph"}
+	if got, want := comment.SanitizedContent(), "<p>First line<br>\nsecond line</p>\n<p>New paragraph</p>\n"; got != want {
+		t.Errorf("Expected sanitized comment %q, got %q", want, got)
+	}
+}
+
+// TestSanitizePolicies tests the post body and UGC presets
+func TestSanitizePolicies(t *testing.T) {
+	content := `<figure class="wp-block-image"><img src="https://example.com/a.jpg" srcset="https://example.com/a.jpg 1x, javascript:x 2x" alt="A"></figure>` +
+		`<iframe src="https://www.youtube-nocookie.com/embed/abc" width="560" allowfullscreen>fallback</iframe>` +
+		`<iframe src="http://www.youtube.com/embed/abc"></iframe>` +
+		`<iframe src="https://evil.example/embed"></iframe>` +
+		`<form action="/x"><input name="q"></form><table><tr><td colspan="2" onmouseover="x()">Cell</td></tr></table>`
+
+	body := PostBodyPolicy().Sanitize(content)
+	for _, want := range []string{
+		`<figure class="wp-block-image"><img src="https://example.com/a.jpg" alt="A"></figure>`,
+		`<table><tr><td colspan="2">Cell</td></tr></table>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected post body to contain %q, got %q", want, body)
+		}
+	}
+	if strings.Contains(body, "iframe") || strings.Contains(body, "form") || strings.Contains(body, "input") {
+		t.Errorf("Expected iframes and forms to be removed, got %q", body)
+	}
+
+	ugc := UGCEmbedPolicy().Sanitize(content)
+	if !strings.Contains(ugc, `<iframe src="https://www.youtube-nocookie.com/embed/abc" width="560" allowfullscreen=""></iframe>`) {
+		t.Errorf("Expected embed iframe to be kept, got %q", ugc)
+	}
+	if strings.Count(ugc, "<iframe") != 1 {
+		t.Errorf("Expected HTTP and unknown iframes to be removed, got %q", ugc)
+	}
+	if strings.Contains(ugc, "<figure") || strings.Contains(ugc, "<table") {
+		t.Errorf("Expected elements outside the UGC policy to be unwrapped, got %q", ugc)
+	}
+
+	c := NewConverter()
+	c.Sanitize = StrictCommentPolicy()
+	if got := c.ToHTML(`<p>Hi <span onclick="x()">there</span></p>`); got != "<p>Hi there</p>" {
+		t.Errorf("Expected converter to sanitize HTML output, got %q", got)
+	}
+}
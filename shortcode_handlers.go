@@ -0,0 +1,325 @@
+package wpimport
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaItem is an image, audio or video file referenced by a shortcode
+type mediaItem struct {
+	Src     string
+	Alt     string
+	Title   string
+	Caption string
+}
+
+// registerCoreShortcodes adds the handlers for the shortcodes that ship
+// with WordPress core
+func registerCoreShortcodes(r *ShortcodeRegistry) {
+	r.Register("caption", captionShortcode)
+	r.Register("wp_caption", captionShortcode)
+	r.Register("gallery", galleryShortcode)
+	r.Register("audio", audioShortcode)
+	r.Register("video", videoShortcode)
+	r.Register("playlist", playlistShortcode)
+	r.Register("embed", embedShortcode)
+}
+
+// captionShortcode renders [caption]<img .../> Caption text[/caption] as a
+// <figure>, which the converters then write in their own figure style
+func captionShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	content := ctx.Do(sc.Content)
+
+	img := findHTMLTag(content, "img")
+	if img == "" {
+		return content
+	}
+	image := img
+	if m := figureLinkedImage.FindString(content); m != "" {
+		image = m
+	}
+	content = strings.Replace(content, image, "", 1)
+
+	// The caption is either an attribute (older exports) or the text that
+	// follows the image
+	caption := sc.Attr("caption", "")
+	if caption == "" {
+		caption = strings.TrimSpace(content)
+	}
+
+	attrs := ""
+	if id := sc.Attr("id", ""); id != "" {
+		attrs += ` id="` + html.EscapeString(id) + `"`
+	}
+	if width := sc.Attr("width", ""); width != "" {
+		attrs += ` style="width: ` + html.EscapeString(width) + `px"`
+	}
+	if caption != "" {
+		image += "<figcaption>" + caption + "</figcaption>"
+	}
+	return "<figure" + attrs + ">" + image + "</figure>"
+}
+
+// galleryShortcode renders [gallery ids="1,2,3"] from the attachments in
+// the export. Without ids, the images attached to the post are used.
+func galleryShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	if ctx.Site == nil {
+		// Without the export the attachments cannot be resolved
+		return sc.Raw
+	}
+	items := shortcodeAttachments(sc, ctx, "image/")
+	if len(items) == 0 {
+		return ctx.Registry.unknown(sc, ctx)
+	}
+
+	switch ctx.Format {
+	case FormatMarkdown:
+		var lines []string
+		for _, item := range items {
+			line := "- " + markdownImage(item)
+			if item.Caption != "" {
+				line += "\n  *" + html.EscapeString(item.Caption) + "*"
+			}
+			lines = append(lines, line)
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	case FormatPlainText:
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, plainTextMediaReference("Image", item))
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	}
+
+	var sb strings.Builder
+	columns := sc.Attr("columns", "3")
+	sb.WriteString(`<div class="gallery gallery-columns-` + html.EscapeString(columns) + `">`)
+	for _, item := range items {
+		sb.WriteString(`<figure class="gallery-item">`)
+		sb.WriteString(htmlImage(item))
+		if item.Caption != "" {
+			sb.WriteString("<figcaption>" + html.EscapeString(item.Caption) + "</figcaption>")
+		}
+		sb.WriteString("</figure>")
+	}
+	sb.WriteString("</div>")
+	return sb.String()
+}
+
+// audioShortcode renders [audio src="..."] and its format-specific variants
+func audioShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	src := mediaShortcodeSource(sc, "mp3", "ogg", "oga", "wav", "m4a", "wma", "flac")
+	if src == "" {
+		return ""
+	}
+	switch ctx.Format {
+	case FormatMarkdown:
+		return "[" + html.EscapeString(path.Base(src)) + "](" + src + ")"
+	case FormatPlainText:
+		return plainTextMediaReference("Audio", mediaItem{Src: src})
+	}
+	return `<audio controls src="` + html.EscapeString(src) + `"></audio>`
+}
+
+// videoShortcode renders [video src="..."] and its format-specific variants
+func videoShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	src := mediaShortcodeSource(sc, "mp4", "m4v", "webm", "ogv", "wmv", "flv")
+	if src == "" {
+		return ""
+	}
+	switch ctx.Format {
+	case FormatMarkdown:
+		return "[" + html.EscapeString(path.Base(src)) + "](" + src + ")"
+	case FormatPlainText:
+		return plainTextMediaReference("Video", mediaItem{Src: src})
+	}
+
+	attrs := ` controls src="` + html.EscapeString(src) + `"`
+	for _, name := range []string{"poster", "width", "height"} {
+		if v := sc.Attr(name, ""); v != "" {
+			attrs += " " + name + `="` + html.EscapeString(v) + `"`
+		}
+	}
+	return "<video" + attrs + "></video>"
+}
+
+// playlistShortcode renders [playlist ids="..." type="audio|video"] as a
+// numbered list of tracks. Without ids, the files of that type attached to
+// the post are used.
+func playlistShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	if ctx.Site == nil {
+		return sc.Raw
+	}
+	kind := "Audio"
+	if sc.Attr("type", "audio") == "video" {
+		kind = "Video"
+	}
+	items := shortcodeAttachments(sc, ctx, strings.ToLower(kind)+"/")
+	if len(items) == 0 {
+		return ctx.Registry.unknown(sc, ctx)
+	}
+
+	switch ctx.Format {
+	case FormatMarkdown:
+		var lines []string
+		for i, item := range items {
+			lines = append(lines, fmt.Sprintf("%d. [%s](%s)", i+1, html.EscapeString(mediaTitle(item)), item.Src))
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	case FormatPlainText:
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, plainTextMediaReference(kind, item))
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+	}
+
+	tag := strings.ToLower(kind)
+	var sb strings.Builder
+	sb.WriteString(`<ol class="playlist">`)
+	for _, item := range items {
+		sb.WriteString("<li><" + tag + ` controls src="` + html.EscapeString(item.Src) + `"></` + tag + "> ")
+		sb.WriteString(html.EscapeString(mediaTitle(item)) + "</li>")
+	}
+	sb.WriteString("</ol>")
+	return sb.String()
+}
+
+// embedShortcode renders [embed]URL[/embed] as the bare URL
+func embedShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	url := strings.TrimSpace(sc.Content)
+	if url == "" && len(sc.Positional) > 0 {
+		url = sc.Positional[0]
+	}
+	if url == "" {
+		return ""
+	}
+	switch ctx.Format {
+	case FormatMarkdown, FormatPlainText:
+		return "\n\n" + url + "\n\n"
+	}
+	return `<p><a href="` + html.EscapeString(url) + `">` + html.EscapeString(url) + "</a></p>"
+}
+
+// Helper function to read the source of an [audio] or [video] shortcode
+func mediaShortcodeSource(sc Shortcode, extensions ...string) string {
+	if src := sc.Attr("src", ""); src != "" {
+		return src
+	}
+	for _, ext := range extensions {
+		if src := sc.Attr(ext, ""); src != "" {
+			return src
+		}
+	}
+	if len(sc.Positional) > 0 {
+		return sc.Positional[0]
+	}
+	return ""
+}
+
+// Helper function to resolve a comma-separated list of attachment IDs
+func resolveAttachments(site *WordPressSite, ids string) []mediaItem {
+	if site == nil || ids == "" {
+		return nil
+	}
+	var items []mediaItem
+	for _, field := range strings.Split(ids, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			continue
+		}
+		attachment := site.GetAttachmentByID(id)
+		if attachment == nil {
+			continue
+		}
+		items = append(items, attachmentMediaItem(attachment))
+	}
+	return items
+}
+
+// Helper function to resolve the attachments of a gallery or playlist:
+// the ids attribute when given, otherwise the files of the given MIME type
+// attached to the post in the id attribute or the current item, in menu
+// order like WordPress
+func shortcodeAttachments(sc Shortcode, ctx *ShortcodeContext, mimePrefix string) []mediaItem {
+	if ids := sc.Attr("ids", sc.Attr("include", "")); ids != "" {
+		return resolveAttachments(ctx.Site, ids)
+	}
+	parent, _ := strconv.Atoi(sc.Attr("id", ""))
+	if parent == 0 && ctx.Item != nil {
+		parent = ctx.Item.PostID
+	}
+	if parent == 0 {
+		return nil
+	}
+
+	var attachments []*Item
+	for i := range ctx.Site.Channel.Items {
+		item := &ctx.Site.Channel.Items[i]
+		if item.PostType == "attachment" && item.PostParent == parent &&
+			strings.HasPrefix(mediaMIMEType(item.GetAttachmentURL()), mimePrefix) {
+			attachments = append(attachments, item)
+		}
+	}
+	sort.SliceStable(attachments, func(i, j int) bool {
+		if attachments[i].MenuOrder != attachments[j].MenuOrder {
+			return attachments[i].MenuOrder < attachments[j].MenuOrder
+		}
+		return attachments[i].PostID < attachments[j].PostID
+	})
+
+	items := make([]mediaItem, 0, len(attachments))
+	for _, attachment := range attachments {
+		items = append(items, attachmentMediaItem(attachment))
+	}
+	return items
+}
+
+// Helper function to describe an attachment for a shortcode
+func attachmentMediaItem(attachment *Item) mediaItem {
+	return mediaItem{
+		Src:     attachment.GetAttachmentURL(),
+		Alt:     attachment.GetMetaValue("_wp_attachment_image_alt"),
+		Title:   attachment.Title,
+		Caption: attachment.Excerpt,
+	}
+}
+
+// Helper function to pick a display title for a media file
+func mediaTitle(item mediaItem) string {
+	if item.Title != "" {
+		return item.Title
+	}
+	return path.Base(item.Src)
+}
+
+// Helper function to render a Markdown image
+func markdownImage(item mediaItem) string {
+	return "![" + html.EscapeString(item.Alt) + "](" + item.Src + ")"
+}
+
+// Helper function to render an HTML image
+func htmlImage(item mediaItem) string {
+	return `<img src="` + html.EscapeString(item.Src) + `" alt="` + html.EscapeString(item.Alt) + `">`
+}
+
+// Helper function to describe media in plain text, e.g. "[Image: alt — caption]"
+func plainTextMediaReference(kind string, item mediaItem) string {
+	var parts []string
+	if item.Alt != "" {
+		parts = append(parts, item.Alt)
+	} else if item.Title != "" {
+		parts = append(parts, item.Title)
+	}
+	if item.Caption != "" && item.Caption != item.Alt {
+		parts = append(parts, item.Caption)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, item.Src)
+	}
+	return "[" + kind + ": " + html.EscapeString(strings.Join(parts, " — ")) + "]"
+}
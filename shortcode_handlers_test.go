@@ -0,0 +1,132 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// Helper function to build a site with two image attachments
+func newGalleryTestSite() *WordPressSite {
+	return &WordPressSite{Channel: Channel{Items: []Item{
+		{PostID: 1, PostType: "post", Content: `<p>Our trip:</p>
+[gallery ids="10, 11,404" columns="2"]`},
+		{PostID: 10, PostType: "attachment", Title: "Beach", Excerpt: "Sunset at the beach",
+			AttachmentURL: "https://example.com/wp-content/uploads/2024/05/beach.jpg",
+			PostMeta:      []PostMeta{{Key: "_wp_attachment_image_alt", Value: "A beach"}}},
+		{PostID: 11, PostType: "attachment", Title: "Hills",
+			GUID: "https://example.com/wp-content/uploads/2024/05/hills.jpg"},
+	}}}
+}
+
+// TestGalleryShortcode tests [gallery ids] resolution in every format
+func TestGalleryShortcode(t *testing.T) {
+	site := newGalleryTestSite()
+	c := NewSiteConverter(site)
+	content := site.Channel.Items[0].Content
+
+	markdown := c.ToMarkdown(content)
+	if !strings.Contains(markdown, "- ![A beach](https://example.com/wp-content/uploads/2024/05/beach.jpg)\n  *Sunset at the beach*") ||
+		!strings.Contains(markdown, "- ![](https://example.com/wp-content/uploads/2024/05/hills.jpg)") {
+		t.Errorf("Unexpected gallery Markdown:\n%s", markdown)
+	}
+
+	cleaned := c.ToHTML(content)
+	if !strings.Contains(cleaned, `<figcaption>Sunset at the beach</figcaption>`) ||
+		!strings.Contains(cleaned, `<img src="https://example.com/wp-content/uploads/2024/05/hills.jpg" alt="">`) {
+		t.Errorf("Unexpected gallery HTML:\n%s", cleaned)
+	}
+
+	text := c.ToPlainText(content)
+	if !strings.Contains(text, "[Image: A beach — Sunset at the beach]\n[Image: Hills]") {
+		t.Errorf("Unexpected gallery plain text:\n%s", text)
+	}
+
+	// Without a site the shortcode is left for the caller
+	if md := ConvertToMarkdown(content); !strings.Contains(md, `[gallery ids="10, 11,404" columns="2"]`) {
+		t.Errorf("Expected unresolved gallery to be kept, got:\n%s", md)
+	}
+}
+
+// TestGalleryShortcodeWithoutIDs tests galleries and playlists of the
+// files attached to the post
+func TestGalleryShortcodeWithoutIDs(t *testing.T) {
+	site := newGalleryTestSite()
+	site.Channel.Items[1].PostParent = 1
+	site.Channel.Items[1].MenuOrder = 2
+	site.Channel.Items[2].PostParent = 1
+	site.Channel.Items[2].MenuOrder = 1
+	site.Channel.Items = append(site.Channel.Items,
+		Item{PostID: 12, PostType: "attachment", PostParent: 1, Title: "Waves",
+			AttachmentURL: "https://example.com/wp-content/uploads/2024/05/waves.mp3"},
+		Item{PostID: 2, PostType: "post"})
+	post := &site.Channel.Items[0]
+
+	c := NewSiteConverter(site).ForItem(post)
+	text := c.ToPlainText("[gallery]\n\n[playlist]")
+	if !strings.Contains(text, "[Image: Hills]\n[Image: A beach — Sunset at the beach]") || !strings.Contains(text, "[Audio: Waves]") {
+		t.Errorf("Expected the attached files in menu order, got:\n%s", text)
+	}
+	if strings.Count(text, "Waves") != 1 {
+		t.Errorf("Expected the audio file to be left out of the gallery, got:\n%s", text)
+	}
+
+	// The id attribute picks another post's attachments
+	other := NewSiteConverter(site).ForItem(&site.Channel.Items[4])
+	if md := other.ToMarkdown(`[gallery id="1"]`); !strings.Contains(md, "hills.jpg") {
+		t.Errorf("Expected the gallery of post 1, got:\n%s", md)
+	}
+
+	// Without attachments the shortcode is handled like an unknown one
+	if md := other.ToMarkdown("[gallery]"); md != "[gallery]" {
+		t.Errorf("Expected the empty gallery to be kept, got %q", md)
+	}
+	c = other
+	c.Shortcodes = NewShortcodeRegistry()
+	c.Shortcodes.Unknown = DropUnknownShortcodes
+	if md := c.ToMarkdown("Before [playlist type=\"video\"] after"); md != "Before  after" {
+		t.Errorf("Expected the empty playlist to be dropped, got %q", md)
+	}
+}
+
+// TestCaptionShortcode tests [caption] rendering
+func TestCaptionShortcode(t *testing.T) {
+	content := `[caption id="attachment_5" align="alignleft" width="300"]<a href="https://example.com/full.jpg"><img src="https://example.com/a.jpg" alt="Dog" width="300" /></a> A <strong>good</strong> dog[/caption]`
+
+	markdown := ConvertToMarkdown(content)
+	if !strings.Contains(markdown, "[![Dog](https://example.com/a.jpg)](https://example.com/full.jpg)\n*A **good** dog*") {
+		t.Errorf("Unexpected caption Markdown:\n%s", markdown)
+	}
+
+	text := ConvertToPlainText(content)
+	if text != "[Image: Dog — A good dog]" {
+		t.Errorf("Unexpected caption plain text: %q", text)
+	}
+
+	cleaned := CleanHTML(content)
+	if !strings.Contains(cleaned, "<figcaption>A <strong>good</strong> dog</figcaption>") {
+		t.Errorf("Unexpected caption HTML:\n%s", cleaned)
+	}
+}
+
+// TestMediaShortcodes tests [audio], [video] and [embed]
+func TestMediaShortcodes(t *testing.T) {
+	content := `[audio mp3="https://example.com/song.mp3"]
+[video src="https://example.com/clip.mp4" poster="https://example.com/p.jpg"][/video]
+[embed]https://vimeo.com/123[/embed]`
+
+	cleaned := DoShortcodes(content, FormatHTML)
+	for _, want := range []string{
+		`<audio controls src="https://example.com/song.mp3"></audio>`,
+		`<video controls src="https://example.com/clip.mp4" poster="https://example.com/p.jpg"></video>`,
+		`<a href="https://vimeo.com/123">`,
+	} {
+		if !strings.Contains(cleaned, want) {
+			t.Errorf("Expected HTML to contain %q, got:\n%s", want, cleaned)
+		}
+	}
+
+	text := DoShortcodes(content, FormatPlainText)
+	if !strings.Contains(text, "[Audio: https://example.com/song.mp3]") || !strings.Contains(text, "https://vimeo.com/123") {
+		t.Errorf("Unexpected plain text media references:\n%s", text)
+	}
+}
@@ -0,0 +1,348 @@
+package wpimport
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// OutputFormat identifies the format content is being converted to
+type OutputFormat int
+
+const (
+	// FormatHTML is cleaned HTML output
+	FormatHTML OutputFormat = iota
+	// FormatMarkdown is Markdown output
+	FormatMarkdown
+	// FormatPlainText is plain text output
+	FormatPlainText
+)
+
+// Shortcode represents a parsed WordPress shortcode
+type Shortcode struct {
+	// Name is the shortcode tag, e.g. "caption"
+	Name string
+
+	// Attrs holds named attributes with lowercased keys
+	Attrs map[string]string
+
+	// Positional holds attributes given without a name, e.g. [embed URL]
+	Positional []string
+
+	// Content is the enclosed content of [name]...[/name] shortcodes
+	Content string
+
+	// Enclosing is true for [name]...[/name] and false for self-closing
+	// or bare [name] shortcodes
+	Enclosing bool
+
+	// Raw is the original shortcode text
+	Raw string
+}
+
+// Attr returns a named attribute, or def if it is missing or empty
+func (sc *Shortcode) Attr(name, def string) string {
+	if v := sc.Attrs[strings.ToLower(name)]; v != "" {
+		return v
+	}
+	return def
+}
+
+// ShortcodeContext is passed to shortcode handlers
+type ShortcodeContext struct {
+	// Format is the output format the content is being converted to
+	Format OutputFormat
+
+	// Site is the export the content belongs to, or nil
+	Site *WordPressSite
+
+	// Item is the item whose content is being processed, or nil
+	Item *Item
+
+	// Registry is the registry processing the content
+	Registry *ShortcodeRegistry
+}
+
+// Do processes shortcodes in nested content, such as the content of an
+// enclosing shortcode
+func (ctx *ShortcodeContext) Do(content string) string {
+	return ctx.Registry.do(content, ctx)
+}
+
+// ShortcodeHandler renders a shortcode. The returned string replaces the
+// shortcode before the surrounding content is converted, so it should be
+// HTML, or Markdown/plain text without HTML special characters.
+type ShortcodeHandler func(sc Shortcode, ctx *ShortcodeContext) string
+
+// UnknownShortcodePolicy controls what happens to shortcodes that have no
+// registered handler
+type UnknownShortcodePolicy int
+
+const (
+	// KeepUnknownShortcodes leaves the tags of unknown shortcodes
+	// untouched, but processes shortcodes in their content
+	KeepUnknownShortcodes UnknownShortcodePolicy = iota
+	// StripUnknownShortcodes removes the shortcode tags but keeps the
+	// enclosed content
+	StripUnknownShortcodes
+	// DropUnknownShortcodes removes unknown shortcodes and their content
+	DropUnknownShortcodes
+)
+
+// ShortcodeRegistry maps shortcode names to handlers
+type ShortcodeRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]ShortcodeHandler
+
+	// Unknown is the policy for shortcodes without a handler
+	Unknown UnknownShortcodePolicy
+}
+
+// DefaultShortcodes is the registry used by the package-level conversion
+// functions
+var DefaultShortcodes = NewShortcodeRegistry()
+
+// NewShortcodeRegistry creates a registry with the built-in handlers
+// registered and unknown shortcodes kept as-is
+func NewShortcodeRegistry() *ShortcodeRegistry {
+	r := &ShortcodeRegistry{handlers: make(map[string]ShortcodeHandler)}
+	registerCoreShortcodes(r)
+	r.Register("code", codeShortcode)
+	r.Register("sourcecode", codeShortcode)
+	return r
+}
+
+// Register adds or replaces the handler for a shortcode name
+func (r *ShortcodeRegistry) Register(name string, handler ShortcodeHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[strings.ToLower(name)] = handler
+}
+
+// Unregister removes the handler for a shortcode name
+func (r *ShortcodeRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.handlers, strings.ToLower(name))
+}
+
+// Lookup returns the handler for a shortcode name, or nil
+func (r *ShortcodeRegistry) Lookup(name string) ShortcodeHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handlers[strings.ToLower(name)]
+}
+
+// Do replaces all shortcodes in content using the registered handlers
+func (r *ShortcodeRegistry) Do(content string, format OutputFormat, site *WordPressSite) string {
+	return r.do(content, &ShortcodeContext{Format: format, Site: site, Registry: r})
+}
+
+// RegisterShortcode registers a handler in the default registry
+func RegisterShortcode(name string, handler ShortcodeHandler) {
+	DefaultShortcodes.Register(name, handler)
+}
+
+// DoShortcodes replaces shortcodes in content using the default registry
+func DoShortcodes(content string, format OutputFormat) string {
+	return DefaultShortcodes.Do(content, format, nil)
+}
+
+// ParseShortcodes returns the top-level shortcodes found in content,
+// excluding escaped [[shortcodes]]
+func ParseShortcodes(content string) []Shortcode {
+	var shortcodes []Shortcode
+	pos := 0
+	for {
+		sc, _, end, escaped, ok := nextShortcode(content, pos)
+		if !ok {
+			return shortcodes
+		}
+		if !escaped {
+			shortcodes = append(shortcodes, sc)
+		}
+		pos = end
+	}
+}
+
+// Helper function to process shortcodes with the given context
+func (r *ShortcodeRegistry) do(content string, ctx *ShortcodeContext) string {
+	if !strings.Contains(content, "[") {
+		return content
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for {
+		sc, start, end, escaped, ok := nextShortcode(content, pos)
+		if !ok {
+			break
+		}
+		sb.WriteString(content[pos:start])
+		pos = end
+
+		if escaped {
+			// [[name]] is printed literally as [name]
+			sb.WriteString(sc.Raw)
+			continue
+		}
+
+		if handler := r.Lookup(sc.Name); handler != nil {
+			sb.WriteString(handler(sc, ctx))
+			continue
+		}
+
+		sb.WriteString(r.unknown(sc, ctx))
+	}
+	sb.WriteString(content[pos:])
+
+	return sb.String()
+}
+
+// Helper function to handle a shortcode according to the Unknown policy,
+// also used by handlers that cannot render a shortcode
+func (r *ShortcodeRegistry) unknown(sc Shortcode, ctx *ShortcodeContext) string {
+	switch r.Unknown {
+	case StripUnknownShortcodes:
+		return r.do(sc.Content, ctx)
+	case DropUnknownShortcodes:
+		return ""
+	}
+	if !sc.Enclosing {
+		return sc.Raw
+	}
+	// Keep the tags, e.g. of a page builder's [vc_row], around the
+	// processed content, as WordPress renders nested shortcodes then too
+	closing := strings.LastIndex(sc.Raw, "[/")
+	opening := closing - len(sc.Content)
+	if closing == -1 || opening < 0 || sc.Raw[opening:closing] != sc.Content {
+		return sc.Raw
+	}
+	return sc.Raw[:opening] + r.do(sc.Content, ctx) + sc.Raw[closing:]
+}
+
+// Helper function to find the next shortcode at or after pos, following the
+// rules of WordPress's get_shortcode_regex. It returns the byte range the
+// shortcode occupies and whether it was escaped with double brackets.
+func nextShortcode(content string, pos int) (sc Shortcode, start, end int, escaped, ok bool) {
+	for pos < len(content) {
+		idx := strings.IndexByte(content[pos:], '[')
+		if idx == -1 {
+			return Shortcode{}, 0, 0, false, false
+		}
+		start = pos + idx
+		pos = start + 1
+
+		// Name: letters, digits, underscores and hyphens
+		nameStart := start + 1
+		if nameStart < len(content) && content[nameStart] == '[' {
+			// Possibly an escaped shortcode, check the inner one
+			continue
+		}
+		nameEnd := nameStart
+		for nameEnd < len(content) && isShortcodeNameChar(content[nameEnd]) {
+			nameEnd++
+		}
+		if nameEnd == nameStart || nameEnd >= len(content) {
+			continue
+		}
+		if c := content[nameEnd]; c != ']' && c != '/' && c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			continue
+		}
+		name := content[nameStart:nameEnd]
+
+		// Attributes run up to the closing bracket
+		closeIdx := strings.IndexByte(content[nameEnd:], ']')
+		if closeIdx == -1 {
+			return Shortcode{}, 0, 0, false, false
+		}
+		tagEnd := nameEnd + closeIdx
+		attrText := content[nameEnd:tagEnd]
+		selfClosing := strings.HasSuffix(attrText, "/")
+		if selfClosing {
+			attrText = attrText[:len(attrText)-1]
+		}
+		end = tagEnd + 1
+
+		sc = Shortcode{Name: name}
+		sc.Attrs, sc.Positional = parseShortcodeAttrs(attrText)
+		escapedOpen := start > 0 && content[start-1] == '['
+
+		// [[name]] escapes a bare shortcode
+		if escapedOpen && end < len(content) && content[end] == ']' {
+			sc.Raw = content[start:end]
+			return sc, start - 1, end + 1, true, true
+		}
+
+		if !selfClosing {
+			closing := "[/" + name + "]"
+			if cIdx := strings.Index(content[end:], closing); cIdx != -1 {
+				sc.Content = content[end : end+cIdx]
+				sc.Enclosing = true
+				end = end + cIdx + len(closing)
+			}
+		}
+
+		// [[name]...[/name]] escapes an enclosing shortcode
+		if escapedOpen && end < len(content) && content[end] == ']' {
+			sc.Raw = content[start:end]
+			return sc, start - 1, end + 1, true, true
+		}
+
+		sc.Raw = content[start:end]
+		return sc, start, end, false, true
+	}
+	return Shortcode{}, 0, 0, false, false
+}
+
+// Helper function to check for valid shortcode name characters
+func isShortcodeNameChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// shortcodeAttrRegex mirrors the pattern used by WordPress's shortcode_parse_atts
+var shortcodeAttrRegex = regexp.MustCompile(`([\w-]+)\s*=\s*"([^"]*)"(?:\s|$)|([\w-]+)\s*=\s*'([^']*)'(?:\s|$)|([\w-]+)\s*=\s*([^\s'"]+)(?:\s|$)|"([^"]*)"(?:\s|$)|'([^']*)'(?:\s|$)|(\S+)(?:\s|$)`)
+
+// Helper function to parse shortcode attributes into named and positional
+// values
+func parseShortcodeAttrs(text string) (map[string]string, []string) {
+	attrs := make(map[string]string)
+	var positional []string
+
+	text = strings.NewReplacer("\u00a0", " ", "\u200b", " ").Replace(text)
+	for _, m := range shortcodeAttrRegex.FindAllStringSubmatch(text+" ", -1) {
+		switch {
+		case m[1] != "":
+			attrs[strings.ToLower(m[1])] = m[2]
+		case m[3] != "":
+			attrs[strings.ToLower(m[3])] = m[4]
+		case m[5] != "":
+			attrs[strings.ToLower(m[5])] = m[6]
+		case m[7] != "" || strings.HasPrefix(m[0], `"`):
+			positional = append(positional, m[7])
+		case m[8] != "" || strings.HasPrefix(m[0], `'`):
+			positional = append(positional, m[8])
+		default:
+			positional = append(positional, m[9])
+		}
+	}
+
+	return attrs, positional
+}
+
+// codeShortcode renders [code lang="go"]...[/code] (SyntaxHighlighter style)
+// as a preformatted code block
+func codeShortcode(sc Shortcode, ctx *ShortcodeContext) string {
+	language := sc.Attr("lang", sc.Attr("language", ""))
+	if language == "" && len(sc.Positional) > 0 {
+		language = sc.Positional[0]
+	}
+
+	// The content is stored encoded by some plugins and raw by others
+	code := html.EscapeString(html.UnescapeString(strings.Trim(sc.Content, "\r\n")))
+	if ctx.Format == FormatPlainText || language == "" {
+		return "<pre><code>" + code + "</code></pre>"
+	}
+	return `<pre><code class="language-` + html.EscapeString(language) + `">` + code + "</code></pre>"
+}
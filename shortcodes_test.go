@@ -0,0 +1,104 @@
+package wpimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseShortcodes tests the shortcode parser
+func TestParseShortcodes(t *testing.T) {
+	content := `Intro [gallery ids="1,2,3" columns=3 link='file' size=large]
+[embed]https://example.com/video[/embed]
+[audio "https://example.com/a.mp3" loop]
+[[caption]] is escaped
+[foo/] and [caption id="c1"]Outer [b]bold[/b][/caption]`
+
+	shortcodes := ParseShortcodes(content)
+	if len(shortcodes) != 5 {
+		t.Fatalf("Expected 5 shortcodes, got %d: %+v", len(shortcodes), shortcodes)
+	}
+
+	gallery := shortcodes[0]
+	want := map[string]string{"ids": "1,2,3", "columns": "3", "link": "file", "size": "large"}
+	if gallery.Name != "gallery" || !reflect.DeepEqual(gallery.Attrs, want) || gallery.Enclosing {
+		t.Errorf("Unexpected gallery shortcode: %+v", gallery)
+	}
+
+	embed := shortcodes[1]
+	if !embed.Enclosing || embed.Content != "https://example.com/video" {
+		t.Errorf("Expected enclosing embed with URL content, got %+v", embed)
+	}
+
+	audio := shortcodes[2]
+	if !reflect.DeepEqual(audio.Positional, []string{"https://example.com/a.mp3", "loop"}) {
+		t.Errorf("Expected positional audio attributes, got %v", audio.Positional)
+	}
+
+	if shortcodes[3].Name != "foo" || shortcodes[3].Enclosing {
+		t.Errorf("Expected self-closing foo, got %+v", shortcodes[3])
+	}
+
+	caption := shortcodes[4]
+	if caption.Content != "Outer [b]bold[/b]" || caption.Attr("id", "") != "c1" {
+		t.Errorf("Expected caption with nested shortcode content, got %+v", caption)
+	}
+}
+
+// TestShortcodeRegistry tests handlers and unknown shortcode policies
+func TestShortcodeRegistry(t *testing.T) {
+	registry := NewShortcodeRegistry()
+	registry.Register("upper", func(sc Shortcode, ctx *ShortcodeContext) string {
+		return strings.ToUpper(ctx.Do(sc.Content))
+	})
+
+	content := `[upper]hi [unknown x=1]there[/unknown][/upper] [[upper]] [unknown/]`
+
+	if got := registry.Do(content, FormatHTML, nil); got != `HI [UNKNOWN X=1]THERE[/UNKNOWN] [upper] [unknown/]` {
+		t.Errorf("Keep policy: got %q", got)
+	}
+
+	registry.Unknown = StripUnknownShortcodes
+	if got := registry.Do(content, FormatHTML, nil); got != `HI THERE [upper] ` {
+		t.Errorf("Strip policy: got %q", got)
+	}
+
+	registry.Unknown = DropUnknownShortcodes
+	if got := registry.Do(content, FormatHTML, nil); got != `HI  [upper] ` {
+		t.Errorf("Drop policy: got %q", got)
+	}
+}
+
+// TestNestedInUnknownShortcode tests known shortcodes inside an unknown
+// wrapper, such as a page builder's row
+func TestNestedInUnknownShortcode(t *testing.T) {
+	content := `[vc_row full="1"][caption id="attachment_5" width="300"]<img src="https://example.com/a.jpg" alt="Dog" /> A dog[/caption][/vc_row]`
+
+	cleaned := CleanHTML(content)
+	if !strings.HasPrefix(cleaned, `[vc_row full="1"]`) || !strings.HasSuffix(cleaned, "[/vc_row]") ||
+		strings.Contains(cleaned, "[caption") || !strings.Contains(cleaned, "<figcaption>A dog</figcaption>") {
+		t.Errorf("Expected the caption to be rendered inside the kept row, got:\n%s", cleaned)
+	}
+	if markdown := ConvertToMarkdown(content); strings.Contains(markdown, "[caption") || !strings.Contains(markdown, "![Dog](https://example.com/a.jpg)") {
+		t.Errorf("Expected the caption in Markdown, got:\n%s", markdown)
+	}
+	if text := ConvertToPlainText(content); !strings.Contains(text, "[Image: Dog — A dog]") {
+		t.Errorf("Expected the image in plain text, got:\n%s", text)
+	}
+}
+
+// TestCodeShortcodeConversion tests [code] rendering in the converters
+func TestCodeShortcodeConversion(t *testing.T) {
+	content := `<p>Example:</p>
+[code lang="html"]<div class="x">&amp;</div>[/code]`
+
+	markdown := ConvertToMarkdown(content)
+	if !strings.Contains(markdown, "```html\n<div class=\"x\">&</div>\n```") {
+		t.Errorf("Expected fenced html code block, got:\n%s", markdown)
+	}
+
+	text := ConvertToPlainText(content)
+	if !strings.Contains(text, `<div class="x">&</div>`) {
+		t.Errorf("Expected code to survive plain text conversion, got:\n%s", text)
+	}
+}
@@ -0,0 +1,351 @@
+package wpimport
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SitemapMaxURLs is the most URLs a sitemap may list
+const SitemapMaxURLs = 50000
+
+// SiteURL is a public URL of the exported site: an item, an archive of
+// a term or author, or the home page
+type SiteURL struct {
+	// URL is the absolute URL on the old site
+	URL string
+
+	// Type is the post type of an item, the taxonomy of a term archive,
+	// "author" for author archives and "home" for the home page
+	Type string
+
+	// Archive is set for term and author archives and the home page
+	Archive bool
+
+	// ID is the ID of the item, term or author, 0 when the export has
+	// none
+	ID int
+
+	// Title is the title of the item or the name of the term or author
+	Title string
+
+	// LastMod is when the item was last modified or, for archives, the
+	// newest modification of the items they list. It is zero when the
+	// export has no date.
+	LastMod time.Time
+
+	// Item is the item at the URL, nil for archives
+	Item *Item
+}
+
+// PublicURLs lists the URLs the site exposed: published posts, pages and
+// custom post types, the pages of their attachments, the archives of the
+// categories, tags, other terms and authors with published posts, and
+// the home page. Archive URLs follow WordPress' default structure, e.g.
+// /category/<parent>/<slug>/, /tag/<slug>/ and /author/<name>/.
+func (site *WordPressSite) PublicURLs() []SiteURL {
+	base := siteHomeURL(site)
+	var urls []SiteURL
+	archives := make(map[string]*SiteURL)
+	var archiveKeys []string
+	archive := func(key string, u SiteURL, lastMod time.Time) {
+		a, ok := archives[key]
+		if !ok {
+			a = &u
+			archives[key] = a
+			archiveKeys = append(archiveKeys, key)
+		}
+		if lastMod.After(a.LastMod) {
+			a.LastMod = lastMod
+		}
+	}
+
+	published := make(map[int]bool)
+	for i := range site.Channel.Items {
+		if item := &site.Channel.Items[i]; item.Status == "publish" && !exportSkipTypes[item.PostType] {
+			published[item.PostID] = true
+		}
+	}
+
+	terms := newSiteTerms(site)
+	categories := newCategoryParents(site)
+	users := make(map[string]Author)
+	for _, author := range site.Channel.Authors {
+		users[author.Login] = author
+	}
+	archive("home", SiteURL{URL: base + "/", Type: "home", Archive: true, Title: strings.TrimSpace(html.UnescapeString(site.Channel.Title))}, time.Time{})
+
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if item.PostType == "attachment" {
+			// Attachment pages are public while their post is, or when
+			// they are not attached to one
+			if item.PostParent != 0 && !published[item.PostParent] {
+				continue
+			}
+		} else if !published[item.PostID] {
+			continue
+		}
+
+		link := strings.TrimSpace(item.Link)
+		if link == "" {
+			link = base + "/?p=" + strconv.Itoa(item.PostID)
+		}
+		lastMod, _ := item.ModifiedTime()
+		urls = append(urls, SiteURL{URL: link, Type: item.PostType, ID: item.PostID, Title: itemTitle(item), LastMod: lastMod, Item: item})
+		if item.PostType == "attachment" || item.PostType == "page" {
+			continue
+		}
+
+		// The home page and author archives list posts only
+		if item.PostType == "post" {
+			archive("home", SiteURL{}, lastMod)
+			if item.Creator != "" {
+				archive("author\x00"+item.Creator, SiteURL{
+					URL: base + "/author/" + slugify(item.Creator) + "/", Type: "author", Archive: true,
+					ID: users[item.Creator].ID, Title: itemAuthorName(site, item),
+				}, lastMod)
+			}
+		}
+		for _, category := range item.Categories {
+			if category.NiceName == "" || category.Domain == "" {
+				continue
+			}
+			term := terms.find(category)
+			archive(category.Domain+"\x00"+category.NiceName, SiteURL{
+				URL: base + "/" + termArchivePath(categories, term.Taxonomy, term.Slug) + "/", Type: term.Taxonomy, Archive: true,
+				ID: term.ID, Title: term.Name,
+			}, lastMod)
+		}
+	}
+
+	// The home page comes first, archives last
+	urls = append([]SiteURL{*archives["home"]}, urls...)
+	for _, key := range archiveKeys[1:] {
+		urls = append(urls, *archives[key])
+	}
+	return urls
+}
+
+// Helper function to get the home page of a site without a trailing slash
+func siteHomeURL(site *WordPressSite) string {
+	for _, u := range []string{site.Channel.Link, site.Channel.BaseBlogURL, site.Channel.BaseSiteURL} {
+		if u = strings.TrimSpace(u); u != "" {
+			return strings.TrimSuffix(u, "/")
+		}
+	}
+	return ""
+}
+
+// Helper function to get the path of a term archive. Categories include
+// their parents, post formats are listed below /type/ and other
+// taxonomies below their name, as WordPress registers them by default.
+func termArchivePath(categories categoryParents, taxonomy, slug string) string {
+	switch taxonomy {
+	case "category":
+		return "category/" + categories.path(slug)
+	case "post_tag":
+		return "tag/" + slug
+	case "post_format":
+		return "type/" + strings.TrimPrefix(slug, "post-format-")
+	}
+	return taxonomy + "/" + slug
+}
+
+// sitemapURLSet and sitemapIndex are the documents of the sitemaps
+// protocol
+type sitemapURLSet struct {
+	XMLName xml.Name          `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapLocation `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name          `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// WriteSitemap writes the URLs as a sitemap. It fails for more than
+// SitemapMaxURLs; SitemapExporter splits larger sites.
+func WriteSitemap(w io.Writer, urls []SiteURL) error {
+	if len(urls) > SitemapMaxURLs {
+		return fmt.Errorf("sitemap has %d URLs, more than %d", len(urls), SitemapMaxURLs)
+	}
+	doc := sitemapURLSet{URLs: []sitemapLocation{}}
+	for _, u := range urls {
+		doc.URLs = append(doc.URLs, sitemapLocation{Loc: u.URL, LastMod: sitemapTime(u.LastMod)})
+	}
+	return writeXMLDocument(w, doc)
+}
+
+// WriteSitemapIndex writes a sitemap index of the sitemaps at the given
+// URLs, with the time each was last modified
+func WriteSitemapIndex(w io.Writer, sitemaps []string, lastMods []time.Time) error {
+	doc := sitemapIndex{Sitemaps: []sitemapLocation{}}
+	for i, loc := range sitemaps {
+		location := sitemapLocation{Loc: loc}
+		if i < len(lastMods) {
+			location.LastMod = sitemapTime(lastMods[i])
+		}
+		doc.Sitemaps = append(doc.Sitemaps, location)
+	}
+	return writeXMLDocument(w, doc)
+}
+
+// Helper function to format a lastmod date, leaving out unknown dates
+func sitemapTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// urlInventoryColumns are the columns of the URL inventory
+var urlInventoryColumns = []string{"url", "type", "archive", "id", "title", "lastmod", "new_url"}
+
+// WriteURLInventory writes the URLs as a CSV table of url, type, archive,
+// id, title, lastmod and new_url, to check a migration against. newURL
+// gives the new_url column and may be nil.
+func WriteURLInventory(w io.Writer, urls []SiteURL, newURL func(SiteURL) string) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write(urlInventoryColumns); err != nil {
+		return err
+	}
+	for _, u := range urls {
+		record := []string{u.URL, u.Type, strconv.FormatBool(u.Archive), csvID(u.ID), u.Title, sitemapTime(u.LastMod), ""}
+		if newURL != nil {
+			record[6] = newURL(u)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// SitemapExporter writes a site's sitemap, split into sitemaps of at most
+// MaxURLs URLs listed by a sitemap index when it has more, and optionally
+// the URL inventory of the old site. Like WordPress' own sitemaps, it
+// leaves out password protected posts; the inventory lists them.
+type SitemapExporter struct {
+	// URL returns the URL listed in the sitemap for a URL of the old site.
+	// Returning "" leaves it out. When nil, the old URLs are listed.
+	URL func(SiteURL) string
+
+	// BaseURL is where the sitemaps are published, used for the sitemap
+	// index. When empty it is the site's home page.
+	BaseURL string
+
+	// MaxURLs is the number of URLs per sitemap, at most and by default
+	// SitemapMaxURLs
+	MaxURLs int
+
+	// Attachments lists attachment pages, which WordPress leaves out of
+	// its own sitemaps
+	Attachments bool
+
+	// Inventory also writes urls.csv, the inventory of every public URL
+	// of the old site with its new URL
+	Inventory bool
+}
+
+// NewSitemapExporter creates an exporter writing sitemap.xml and the URL
+// inventory
+func NewSitemapExporter() *SitemapExporter {
+	return &SitemapExporter{Inventory: true}
+}
+
+// Export writes sitemap.xml under dir. Sites with more URLs than fit one
+// sitemap get sitemap-1.xml, sitemap-2.xml and so on, and sitemap.xml is
+// their index.
+func (e *SitemapExporter) Export(site *WordPressSite, dir string) error {
+	all := site.PublicURLs()
+	var urls []SiteURL
+	for _, u := range all {
+		if (u.Type == "attachment" && !e.Attachments) || (u.Item != nil && u.Item.PostPassword != "") {
+			continue
+		}
+		if e.URL != nil {
+			if u.URL = e.URL(u); u.URL == "" {
+				continue
+			}
+		}
+		urls = append(urls, u)
+	}
+	max := e.MaxURLs
+	if max <= 0 || max > SitemapMaxURLs {
+		max = SitemapMaxURLs
+	}
+	if len(urls) <= max {
+		if err := e.writeSitemap(dir, "sitemap.xml", urls); err != nil {
+			return err
+		}
+	} else {
+		base := strings.TrimSuffix(e.BaseURL, "/")
+		if base == "" {
+			base = siteHomeURL(site)
+		}
+		var locations []string
+		var lastMods []time.Time
+		for start := 0; start < len(urls); start += max {
+			end := start + max
+			if end > len(urls) {
+				end = len(urls)
+			}
+			name := "sitemap-" + strconv.Itoa(len(locations)+1) + ".xml"
+			if err := e.writeSitemap(dir, name, urls[start:end]); err != nil {
+				return err
+			}
+			var lastMod time.Time
+			for _, u := range urls[start:end] {
+				if u.LastMod.After(lastMod) {
+					lastMod = u.LastMod
+				}
+			}
+			locations = append(locations, base+"/"+name)
+			lastMods = append(lastMods, lastMod)
+		}
+		var sb strings.Builder
+		if err := WriteSitemapIndex(&sb, locations, lastMods); err != nil {
+			return fmt.Errorf("failed to write sitemap.xml: %w", err)
+		}
+		if err := writeExportFile(filepath.Join(dir, "sitemap.xml"), sb.String()); err != nil {
+			return fmt.Errorf("failed to write sitemap.xml: %w", err)
+		}
+	}
+
+	if e.Inventory {
+		var sb strings.Builder
+		if err := WriteURLInventory(&sb, all, e.URL); err != nil {
+			return fmt.Errorf("failed to write urls.csv: %w", err)
+		}
+		if err := writeExportFile(filepath.Join(dir, "urls.csv"), sb.String()); err != nil {
+			return fmt.Errorf("failed to write urls.csv: %w", err)
+		}
+	}
+	return nil
+}
+
+// Helper function to write one sitemap file
+func (e *SitemapExporter) writeSitemap(dir, name string, urls []SiteURL) error {
+	var sb strings.Builder
+	if err := WriteSitemap(&sb, urls); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := writeExportFile(filepath.Join(dir, name), sb.String()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
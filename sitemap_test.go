@@ -0,0 +1,129 @@
+package wpimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestPublicURLs tests the URLs listed for items and archives
+func TestPublicURLs(t *testing.T) {
+	urls := feedTestSite().PublicURLs()
+
+	var got []string
+	byURL := make(map[string]SiteURL)
+	for _, u := range urls {
+		got = append(got, u.URL)
+		byURL[u.URL] = u
+	}
+	expected := []string{
+		"https://oldsite.com/",
+		"https://oldsite.com/2019/05/hello-world/",
+		"https://oldsite.com/about/",
+		"https://oldsite.com/about/team/",
+		"https://oldsite.com/book/dune/",
+		"https://oldsite.com/?p=30",
+		"https://oldsite.com/?p=31",
+		"https://oldsite.com/2019/07/episode-1/",
+		"https://oldsite.com/?p=41",
+		"https://oldsite.com/?p=42",
+		"https://oldsite.com/?p=43",
+		"https://oldsite.com/author/jane/",
+		"https://oldsite.com/category/news/",
+		"https://oldsite.com/tag/go/",
+		"https://oldsite.com/tag/wp/",
+		"https://oldsite.com/genre/sci-fi/",
+		"https://oldsite.com/author/max/",
+		"https://oldsite.com/category/news/local/",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	if home := byURL["https://oldsite.com/"]; home.Type != "home" || sitemapTime(home.LastMod) != "2019-08-01T00:00:00Z" {
+		t.Errorf("Expected the newest post as the home page's lastmod, got %+v", home)
+	}
+	if post := byURL["https://oldsite.com/2019/05/hello-world/"]; post.Item == nil || sitemapTime(post.LastMod) != "2019-06-01T12:00:00+02:00" {
+		t.Errorf("Expected the modified date as lastmod, got %+v", post)
+	}
+	if genre := byURL["https://oldsite.com/genre/sci-fi/"]; !genre.Archive || genre.ID != 7 || genre.Title != "Sci-Fi" || sitemapTime(genre.LastMod) != "2019-06-01T12:00:00+02:00" {
+		t.Errorf("Unexpected term archive: %+v", genre)
+	}
+	if author := byURL["https://oldsite.com/author/jane/"]; author.ID != 1 || author.Title != "Jane Doe" {
+		t.Errorf("Unexpected author archive: %+v", author)
+	}
+}
+
+// TestSitemapExport tests the sitemap and the URL inventory
+func TestSitemapExport(t *testing.T) {
+	dir := t.TempDir()
+	e := NewSitemapExporter()
+	e.URL = func(u SiteURL) string {
+		if u.Type == "book" {
+			return ""
+		}
+		return strings.Replace(u.URL, "oldsite.com", "newsite.com", 1)
+	}
+	if err := e.Export(feedTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var sitemap sitemapURLSet
+	if err := xml.Unmarshal([]byte(readExport(t, dir, "sitemap.xml")), &sitemap); err != nil {
+		t.Fatalf("Expected a valid sitemap: %v", err)
+	}
+	if len(sitemap.URLs) != 12 || sitemap.URLs[1].Loc != "https://newsite.com/2019/05/hello-world/" || sitemap.URLs[1].LastMod != "2019-06-01T12:00:00+02:00" {
+		t.Errorf("Expected the new URLs without books, attachments and protected posts, got %+v", sitemap.URLs)
+	}
+	if sitemap.URLs[2].LastMod != "2019-01-01T00:00:00Z" {
+		t.Errorf("Expected the published date without a modified date, got %q", sitemap.URLs[2].LastMod)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(readExport(t, dir, "urls.csv"))).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected a valid inventory: %v", err)
+	}
+	if len(records) != 19 || strings.Join(records[0], ",") != "url,type,archive,id,title,lastmod,new_url" {
+		t.Fatalf("Unexpected inventory: %v", records)
+	}
+	if row := records[5]; row[0] != "https://oldsite.com/book/dune/" || row[1] != "book" || row[2] != "false" || row[3] != "22" || row[6] != "" {
+		t.Errorf("Unexpected inventory row: %v", row)
+	}
+	if row := records[13]; row[0] != "https://oldsite.com/category/news/" || row[2] != "true" || row[6] != "https://newsite.com/category/news/" {
+		t.Errorf("Unexpected inventory row: %v", row)
+	}
+}
+
+// TestSitemapIndex tests splitting large sitemaps
+func TestSitemapIndex(t *testing.T) {
+	site := &WordPressSite{}
+	site.Channel.Link = "https://oldsite.com/"
+	for i := 1; i <= 5; i++ {
+		site.Channel.Items = append(site.Channel.Items, Item{PostID: i, PostType: "page", Status: "publish",
+			Link: fmt.Sprintf("https://oldsite.com/page-%d/", i), PostDate: fmt.Sprintf("2020-01-0%d 00:00:00", i)})
+	}
+
+	dir := t.TempDir()
+	e := &SitemapExporter{MaxURLs: 2, BaseURL: "https://newsite.com/"}
+	if err := e.Export(site, dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	var index sitemapIndex
+	if err := xml.Unmarshal([]byte(readExport(t, dir, "sitemap.xml")), &index); err != nil {
+		t.Fatalf("Expected a valid sitemap index: %v", err)
+	}
+	if len(index.Sitemaps) != 3 || index.Sitemaps[2].Loc != "https://newsite.com/sitemap-3.xml" || index.Sitemaps[0].LastMod != "2020-01-01T00:00:00Z" {
+		t.Errorf("Unexpected sitemap index: %+v", index.Sitemaps)
+	}
+	if last := readExport(t, dir, "sitemap-3.xml"); strings.Count(last, "<url>") != 2 || !strings.Contains(last, "page-5") {
+		t.Errorf("Unexpected last sitemap:\n%s", last)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSitemap(&buf, make([]SiteURL, SitemapMaxURLs+1)); err == nil {
+		t.Error("Expected an error for too many URLs")
+	}
+}
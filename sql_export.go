@@ -0,0 +1,579 @@
+package wpimport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SQLDialect is the database an SQL dump is written for
+type SQLDialect int
+
+const (
+	// SQLDialectSQLite writes a dump for SQLite, e.g. sqlite3 site.db < site.sql
+	SQLDialectSQLite SQLDialect = iota
+	// SQLDialectMySQL writes a dump for MySQL and MariaDB that matches the
+	// WordPress schema, so it can seed a WordPress database
+	SQLDialectMySQL
+)
+
+// SQLOptions controls the SQL dump
+type SQLOptions struct {
+	// Dialect is the database to write for, SQLite by default
+	Dialect SQLDialect
+
+	// TablePrefix is the prefix of the table names, "wp_" when empty
+	TablePrefix string
+
+	// SkipSchema leaves out the CREATE TABLE statements, to insert into
+	// the tables of an existing WordPress install
+	SkipSchema bool
+
+	// DropTables drops the tables before creating them
+	DropTables bool
+
+	// BatchSize is the number of rows per INSERT statement, 100 when 0
+	BatchSize int
+}
+
+// sqlColumn is a column of a table. Its type is the one WordPress uses in
+// MySQL; SQLite columns are INTEGER or TEXT.
+type sqlColumn struct {
+	name    string
+	mysql   string
+	integer bool
+}
+
+// sqlTable is a table of the WordPress schema
+type sqlTable struct {
+	name       string
+	columns    []sqlColumn
+	primaryKey []string
+	unique     map[string][]string
+	indexes    map[string][]string
+}
+
+// Helper functions to declare columns
+func sqlInt(name, mysql string) sqlColumn  { return sqlColumn{name: name, mysql: mysql, integer: true} }
+func sqlText(name, mysql string) sqlColumn { return sqlColumn{name: name, mysql: mysql} }
+
+// sqlDateTime is the type of WordPress' date columns
+const sqlDateTime = "datetime NOT NULL DEFAULT '0000-00-00 00:00:00'"
+
+// sqlTables is the part of the WordPress schema the dump writes
+var sqlTables = []sqlTable{
+	{
+		name: "users",
+		columns: []sqlColumn{
+			sqlInt("ID", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlText("user_login", "varchar(60) NOT NULL DEFAULT ''"),
+			sqlText("user_pass", "varchar(255) NOT NULL DEFAULT ''"),
+			sqlText("user_nicename", "varchar(50) NOT NULL DEFAULT ''"),
+			sqlText("user_email", "varchar(100) NOT NULL DEFAULT ''"),
+			sqlText("user_url", "varchar(100) NOT NULL DEFAULT ''"),
+			sqlText("user_registered", sqlDateTime),
+			sqlText("user_activation_key", "varchar(255) NOT NULL DEFAULT ''"),
+			sqlInt("user_status", "int(11) NOT NULL DEFAULT '0'"),
+			sqlText("display_name", "varchar(250) NOT NULL DEFAULT ''"),
+		},
+		primaryKey: []string{"ID"},
+		indexes:    map[string][]string{"user_login_key": {"user_login"}, "user_nicename": {"user_nicename"}, "user_email": {"user_email"}},
+	},
+	{
+		name: "usermeta",
+		columns: []sqlColumn{
+			sqlInt("umeta_id", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlInt("user_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("meta_key", "varchar(255) DEFAULT NULL"),
+			sqlText("meta_value", "longtext"),
+		},
+		primaryKey: []string{"umeta_id"},
+		indexes:    map[string][]string{"user_id": {"user_id"}, "meta_key": {"meta_key"}},
+	},
+	{
+		name: "posts",
+		columns: []sqlColumn{
+			sqlInt("ID", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlInt("post_author", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("post_date", sqlDateTime),
+			sqlText("post_date_gmt", sqlDateTime),
+			sqlText("post_content", "longtext NOT NULL"),
+			sqlText("post_title", "text NOT NULL"),
+			sqlText("post_excerpt", "text NOT NULL"),
+			sqlText("post_status", "varchar(20) NOT NULL DEFAULT 'publish'"),
+			sqlText("comment_status", "varchar(20) NOT NULL DEFAULT 'open'"),
+			sqlText("ping_status", "varchar(20) NOT NULL DEFAULT 'open'"),
+			sqlText("post_password", "varchar(255) NOT NULL DEFAULT ''"),
+			sqlText("post_name", "varchar(200) NOT NULL DEFAULT ''"),
+			sqlText("to_ping", "text NOT NULL"),
+			sqlText("pinged", "text NOT NULL"),
+			sqlText("post_modified", sqlDateTime),
+			sqlText("post_modified_gmt", sqlDateTime),
+			sqlText("post_content_filtered", "longtext NOT NULL"),
+			sqlInt("post_parent", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("guid", "varchar(255) NOT NULL DEFAULT ''"),
+			sqlInt("menu_order", "int(11) NOT NULL DEFAULT '0'"),
+			sqlText("post_type", "varchar(20) NOT NULL DEFAULT 'post'"),
+			sqlText("post_mime_type", "varchar(100) NOT NULL DEFAULT ''"),
+			sqlInt("comment_count", "bigint(20) NOT NULL DEFAULT '0'"),
+		},
+		primaryKey: []string{"ID"},
+		indexes: map[string][]string{
+			"post_name": {"post_name"}, "type_status_date": {"post_type", "post_status", "post_date", "ID"},
+			"post_parent": {"post_parent"}, "post_author": {"post_author"},
+		},
+	},
+	{
+		name: "postmeta",
+		columns: []sqlColumn{
+			sqlInt("meta_id", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlInt("post_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("meta_key", "varchar(255) DEFAULT NULL"),
+			sqlText("meta_value", "longtext"),
+		},
+		primaryKey: []string{"meta_id"},
+		indexes:    map[string][]string{"post_id": {"post_id"}, "meta_key": {"meta_key"}},
+	},
+	{
+		name: "comments",
+		columns: []sqlColumn{
+			sqlInt("comment_ID", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlInt("comment_post_ID", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("comment_author", "tinytext NOT NULL"),
+			sqlText("comment_author_email", "varchar(100) NOT NULL DEFAULT ''"),
+			sqlText("comment_author_url", "varchar(200) NOT NULL DEFAULT ''"),
+			sqlText("comment_author_IP", "varchar(100) NOT NULL DEFAULT ''"),
+			sqlText("comment_date", sqlDateTime),
+			sqlText("comment_date_gmt", sqlDateTime),
+			sqlText("comment_content", "text NOT NULL"),
+			sqlInt("comment_karma", "int(11) NOT NULL DEFAULT '0'"),
+			sqlText("comment_approved", "varchar(20) NOT NULL DEFAULT '1'"),
+			sqlText("comment_agent", "varchar(255) NOT NULL DEFAULT ''"),
+			sqlText("comment_type", "varchar(20) NOT NULL DEFAULT 'comment'"),
+			sqlInt("comment_parent", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlInt("user_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+		},
+		primaryKey: []string{"comment_ID"},
+		indexes: map[string][]string{
+			"comment_post_ID": {"comment_post_ID"}, "comment_approved_date_gmt": {"comment_approved", "comment_date_gmt"},
+			"comment_parent": {"comment_parent"},
+		},
+	},
+	{
+		name: "commentmeta",
+		columns: []sqlColumn{
+			sqlInt("meta_id", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlInt("comment_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("meta_key", "varchar(255) DEFAULT NULL"),
+			sqlText("meta_value", "longtext"),
+		},
+		primaryKey: []string{"meta_id"},
+		indexes:    map[string][]string{"comment_id": {"comment_id"}, "meta_key": {"meta_key"}},
+	},
+	{
+		name: "terms",
+		columns: []sqlColumn{
+			sqlInt("term_id", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlText("name", "varchar(200) NOT NULL DEFAULT ''"),
+			sqlText("slug", "varchar(200) NOT NULL DEFAULT ''"),
+			sqlInt("term_group", "bigint(10) NOT NULL DEFAULT '0'"),
+		},
+		primaryKey: []string{"term_id"},
+		indexes:    map[string][]string{"slug": {"slug"}, "name": {"name"}},
+	},
+	{
+		name: "term_taxonomy",
+		columns: []sqlColumn{
+			sqlInt("term_taxonomy_id", "bigint(20) unsigned NOT NULL AUTO_INCREMENT"),
+			sqlInt("term_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlText("taxonomy", "varchar(32) NOT NULL DEFAULT ''"),
+			sqlText("description", "longtext NOT NULL"),
+			sqlInt("parent", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlInt("count", "bigint(20) NOT NULL DEFAULT '0'"),
+		},
+		primaryKey: []string{"term_taxonomy_id"},
+		unique:     map[string][]string{"term_id_taxonomy": {"term_id", "taxonomy"}},
+		indexes:    map[string][]string{"taxonomy": {"taxonomy"}},
+	},
+	{
+		name: "term_relationships",
+		columns: []sqlColumn{
+			sqlInt("object_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlInt("term_taxonomy_id", "bigint(20) unsigned NOT NULL DEFAULT '0'"),
+			sqlInt("term_order", "int(11) NOT NULL DEFAULT '0'"),
+		},
+		primaryKey: []string{"object_id", "term_taxonomy_id"},
+		indexes:    map[string][]string{"term_taxonomy_id": {"term_taxonomy_id"}},
+	},
+}
+
+// sqlVarchar matches the length of a varchar column type
+var sqlVarchar = regexp.MustCompile(`^varchar\((\d+)\)`)
+
+// ExportSQL writes the site as an SQL dump whose tables mirror WordPress'
+// wp_users, wp_usermeta, wp_posts, wp_postmeta, wp_comments,
+// wp_commentmeta, wp_terms, wp_term_taxonomy and wp_term_relationships.
+// Items keep their IDs; authors are linked to posts through their user
+// IDs, and user passwords are left empty.
+func ExportSQL(w io.Writer, site *WordPressSite, opts SQLOptions) error {
+	if opts.TablePrefix == "" {
+		opts.TablePrefix = "wp_"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	d := &sqlDump{opts: opts, out: bufio.NewWriter(w)}
+	rows := sqlRows(site)
+
+	d.printf("-- WordPress export written by wp-import\n")
+	if title := strings.Join(strings.Fields(site.Channel.Title+" "+site.Channel.Link), " "); title != "" {
+		d.printf("-- Site: %s\n", title)
+	}
+	d.printf("\n")
+	if opts.Dialect == SQLDialectMySQL {
+		// Allow explicit IDs and the zero dates WordPress uses for drafts
+		d.printf("SET NAMES utf8mb4;\nSET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';\n\n")
+	}
+	if !opts.SkipSchema {
+		for _, table := range sqlTables {
+			d.createTable(table)
+		}
+	}
+	if opts.Dialect == SQLDialectMySQL {
+		d.printf("START TRANSACTION;\n\n")
+	} else {
+		d.printf("BEGIN TRANSACTION;\n\n")
+	}
+	for _, table := range sqlTables {
+		d.insert(table, rows[table.name])
+	}
+	d.printf("COMMIT;\n")
+	return d.out.Flush()
+}
+
+// sqlDump writes the statements of a dump. Write errors are kept by the
+// bufio.Writer and returned by Flush.
+type sqlDump struct {
+	opts SQLOptions
+	out  *bufio.Writer
+}
+
+// Helper function to write formatted text
+func (d *sqlDump) printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.out, format, args...)
+}
+
+// Helper function to quote an identifier
+func (d *sqlDump) ident(name string) string {
+	if d.opts.Dialect == SQLDialectMySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Helper function to write a value as a literal: ints as numbers, strings
+// quoted for the dialect, and nil as NULL
+func (d *sqlDump) literal(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		if d.opts.Dialect == SQLDialectMySQL {
+			return mysqlString(v)
+		}
+		return sqliteString(v)
+	}
+	return "NULL"
+}
+
+// Helper function to write the CREATE TABLE statement of a table and, for
+// SQLite, its indexes
+func (d *sqlDump) createTable(table sqlTable) {
+	name := d.opts.TablePrefix + table.name
+	if d.opts.DropTables {
+		d.printf("DROP TABLE IF EXISTS %s;\n", d.ident(name))
+	}
+	var lines []string
+	for _, column := range table.columns {
+		if d.opts.Dialect == SQLDialectMySQL {
+			lines = append(lines, d.ident(column.name)+" "+column.mysql)
+		} else if column.integer {
+			lines = append(lines, d.ident(column.name)+" INTEGER NOT NULL DEFAULT 0")
+		} else {
+			lines = append(lines, d.ident(column.name)+" TEXT")
+		}
+	}
+	lines = append(lines, "PRIMARY KEY ("+d.columnList(table, table.primaryKey)+")")
+	for _, key := range sortedKeys(table.unique) {
+		if d.opts.Dialect == SQLDialectMySQL {
+			lines = append(lines, "UNIQUE KEY "+d.ident(key)+" ("+d.columnList(table, table.unique[key])+")")
+		} else {
+			lines = append(lines, "UNIQUE ("+d.columnList(table, table.unique[key])+")")
+		}
+	}
+	if d.opts.Dialect == SQLDialectMySQL {
+		for _, key := range sortedKeys(table.indexes) {
+			lines = append(lines, "KEY "+d.ident(key)+" ("+d.columnList(table, table.indexes[key])+")")
+		}
+	}
+
+	d.printf("CREATE TABLE %s (\n  %s\n)", d.ident(name), strings.Join(lines, ",\n  "))
+	if d.opts.Dialect == SQLDialectMySQL {
+		d.printf(" ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci")
+	}
+	d.printf(";\n")
+	if d.opts.Dialect != SQLDialectMySQL {
+		for _, key := range sortedKeys(table.indexes) {
+			d.printf("CREATE INDEX %s ON %s (%s);\n", d.ident(name+"_"+key), d.ident(name), d.columnList(table, table.indexes[key]))
+		}
+	}
+	d.printf("\n")
+}
+
+// Helper function to list the columns of a key. MySQL indexes long varchar
+// columns by their first 191 characters, as WordPress does, to stay within
+// the key length of utf8mb4 tables.
+func (d *sqlDump) columnList(table sqlTable, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.ident(name)
+		if d.opts.Dialect != SQLDialectMySQL {
+			continue
+		}
+		for _, column := range table.columns {
+			if m := sqlVarchar.FindStringSubmatch(column.mysql); column.name == name && m != nil {
+				if n, _ := strconv.Atoi(m[1]); n > 191 {
+					quoted[i] += "(191)"
+				}
+			}
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Helper function to write the rows of a table in batches
+func (d *sqlDump) insert(table sqlTable, rows [][]interface{}) {
+	if len(rows) == 0 {
+		return
+	}
+	columns := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		columns[i] = d.ident(column.name)
+	}
+	head := "INSERT INTO " + d.ident(d.opts.TablePrefix+table.name) + " (" + strings.Join(columns, ", ") + ") VALUES\n"
+	for start := 0; start < len(rows); start += d.opts.BatchSize {
+		end := start + d.opts.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		d.out.WriteString(head)
+		for i, row := range rows[start:end] {
+			values := make([]string, len(row))
+			for j, value := range row {
+				values[j] = d.literal(value)
+			}
+			d.printf("(%s)", strings.Join(values, ", "))
+			if start+i < end-1 {
+				d.printf(",\n")
+			}
+		}
+		d.printf(";\n")
+	}
+	d.printf("\n")
+}
+
+// Helper function to build the rows of each table, in the order of the
+// table's columns
+func sqlRows(site *WordPressSite) map[string][][]interface{} {
+	rows := make(map[string][][]interface{})
+
+	// Users, with IDs for authors the export has none for
+	userIDs := make(map[string]int)
+	nextUserID := 1
+	for _, author := range site.Channel.Authors {
+		if author.ID >= nextUserID {
+			nextUserID = author.ID + 1
+		}
+	}
+	umetaID := 0
+	for _, author := range site.Channel.Authors {
+		id := author.ID
+		if id == 0 || userIDs[author.Login] != 0 {
+			id = nextUserID
+			nextUserID++
+		}
+		userIDs[author.Login] = id
+		// user_nicename holds 50 characters
+		nicename := slugify(author.Login)
+		if runes := []rune(nicename); len(runes) > 50 {
+			nicename = strings.TrimRight(string(runes[:50]), "-")
+		}
+		rows["users"] = append(rows["users"], []interface{}{
+			id, author.Login, "", nicename, author.Email, "", "0000-00-00 00:00:00", "", 0, author.DisplayName,
+		})
+		for _, meta := range [][2]string{{"nickname", author.Login}, {"first_name", author.FirstName}, {"last_name", author.LastName}} {
+			umetaID++
+			rows["usermeta"] = append(rows["usermeta"], []interface{}{umetaID, id, meta[0], meta[1]})
+		}
+	}
+
+	// Terms; term_taxonomy rows get their own IDs, and terms that share a
+	// term ID with a different slug get a new one
+	terms := newSiteTerms(site)
+	for i := range site.Channel.Items {
+		for _, category := range site.Channel.Items[i].Categories {
+			terms.add(terms.find(category))
+		}
+	}
+	nextTermID := 1
+	for _, term := range terms.list {
+		if term.ID >= nextTermID {
+			nextTermID = term.ID + 1
+		}
+	}
+	termIDs := make([]int, len(terms.list))
+	slugByID := make(map[int]string)
+	for i, term := range terms.list {
+		id := term.ID
+		if slug, taken := slugByID[id]; id == 0 || (taken && slug != term.Slug) {
+			id = nextTermID
+			nextTermID++
+		}
+		termIDs[i] = id
+		if _, written := slugByID[id]; !written {
+			slugByID[id] = term.Slug
+			rows["terms"] = append(rows["terms"], []interface{}{id, term.Name, term.Slug, 0})
+		}
+	}
+	counts := make([]int, len(terms.list))
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		seen := make(map[int]bool)
+		for _, category := range item.Categories {
+			j := terms.index[category.Domain+"\x00"+category.NiceName]
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			counts[j]++
+			rows["term_relationships"] = append(rows["term_relationships"], []interface{}{item.PostID, j + 1, 0})
+		}
+	}
+	for i, term := range terms.list {
+		parent := 0
+		if term.Parent != "" {
+			if j, ok := terms.index[term.Taxonomy+"\x00"+term.Parent]; ok {
+				parent = termIDs[j]
+			}
+		}
+		rows["term_taxonomy"] = append(rows["term_taxonomy"], []interface{}{i + 1, termIDs[i], term.Taxonomy, term.Description, parent, counts[i]})
+	}
+
+	// Posts with their meta and comments
+	metaID, commentMetaID := 0, 0
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		approved := 0
+		for _, comment := range item.Comments {
+			if comment.Approved == "1" {
+				approved++
+			}
+		}
+		modified, modifiedGMT := item.PostModified, item.PostModifiedGMT
+		if modified == "" {
+			modified, modifiedGMT = item.PostDate, item.PostDateGMT
+		}
+		mimeType := ""
+		if item.PostType == "attachment" && item.AttachmentURL != "" {
+			mimeType = mediaMIMEType(item.AttachmentURL)
+		}
+		rows["posts"] = append(rows["posts"], []interface{}{
+			item.PostID, userIDs[item.Creator], sqlDate(item.PostDate), sqlDate(item.PostDateGMT),
+			item.Content, item.Title, item.Excerpt, item.Status,
+			sqlDefault(item.CommentStatus, "open"), sqlDefault(item.PingStatus, "open"),
+			item.PostPassword, item.PostName, "", "", sqlDate(modified), sqlDate(modifiedGMT), "",
+			item.PostParent, item.GUID, item.MenuOrder, item.PostType, mimeType, approved,
+		})
+		for _, meta := range item.PostMeta {
+			metaID++
+			rows["postmeta"] = append(rows["postmeta"], []interface{}{metaID, item.PostID, meta.Key, meta.Value})
+		}
+		for _, comment := range item.Comments {
+			rows["comments"] = append(rows["comments"], []interface{}{
+				comment.ID, item.PostID, comment.Author, comment.AuthorEmail, comment.AuthorURL, comment.AuthorIP,
+				sqlDate(comment.Date), sqlDate(comment.DateGMT), comment.Content, 0, sqlDefault(comment.Approved, "1"), "",
+				sqlDefault(comment.Type, "comment"), comment.Parent, comment.UserID,
+			})
+			for _, meta := range comment.CommentMeta {
+				commentMetaID++
+				rows["commentmeta"] = append(rows["commentmeta"], []interface{}{commentMetaID, comment.ID, meta.Key, meta.Value})
+			}
+		}
+	}
+	return rows
+}
+
+// Helper function to list the names of a table's keys in a stable order
+func sortedKeys(keys map[string][]string) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Helper function to use a default for an empty value
+func sqlDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// Helper function to write a date, using WordPress' zero date when the
+// export has none
+func sqlDate(date string) string {
+	return sqlDefault(strings.TrimSpace(date), "0000-00-00 00:00:00")
+}
+
+// Helper function to quote a string for SQLite: quotes are doubled, and
+// NUL bytes, which SQLite literals cannot hold, are written with char(0)
+func sqliteString(s string) string {
+	quoted := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	return strings.ReplaceAll(quoted, "\x00", "' || char(0) || '")
+}
+
+// Helper function to quote a string for MySQL, which treats backslashes in
+// literals as escapes
+func mysqlString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			sb.WriteString(`\0`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case 0x1a:
+			sb.WriteString(`\Z`)
+		case '\'':
+			sb.WriteString(`\'`)
+		case '\\':
+			sb.WriteString(`\\`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
@@ -0,0 +1,126 @@
+package wpimport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestExportSQLite tests the SQLite schema and rows
+func TestExportSQLite(t *testing.T) {
+	site := jsonTestSite()
+	site.Channel.Items[0].Title = "It's\x00here"
+
+	var buf bytes.Buffer
+	if err := ExportSQL(&buf, site, SQLOptions{DropTables: true}); err != nil {
+		t.Fatalf("ExportSQL failed: %v", err)
+	}
+	dump := buf.String()
+
+	expected := []string{
+		`DROP TABLE IF EXISTS "wp_posts";`,
+		`CREATE TABLE "wp_posts" (`,
+		`"post_content" TEXT,`,
+		`PRIMARY KEY ("object_id", "term_taxonomy_id")`,
+		`UNIQUE ("term_id", "taxonomy")`,
+		`CREATE INDEX "wp_postmeta_meta_key" ON "wp_postmeta" ("meta_key");`,
+		"BEGIN TRANSACTION;",
+		`'It''s' || char(0) || 'here'`,
+		// Users, terms and the joins between them and the posts
+		`(1, 'jane', '', 'jane', 'jane@example.com', '', '0000-00-00 00:00:00', '', 0, 'Jane Doe')`,
+		`(3, 'News', 'news', 0)`,
+		`(2, 7, 'genre', 'Spaceships', 0, 2)`,
+		`(10, 1, 0)`,
+		`(22, 2, 0)`,
+		// Comments keep their threads and status
+		`(3, 10, 'Bob', '', '', '', '0000-00-00 00:00:00', '0000-00-00 00:00:00', 'Me too', 0, '0', '', 'comment', 2, 0)`,
+		"COMMIT;",
+	}
+	for _, s := range expected {
+		if !strings.Contains(dump, s) {
+			t.Errorf("Expected the dump to contain %q", s)
+		}
+	}
+	if strings.Contains(dump, "AUTO_INCREMENT") || strings.Contains(dump, "`") {
+		t.Error("Expected no MySQL syntax in the SQLite dump")
+	}
+
+	// Posts are linked to their author, and count approved comments
+	for _, line := range strings.Split(dump, "\n") {
+		if strings.HasPrefix(line, "(10, ") && strings.Contains(line, "'post'") {
+			if !strings.HasPrefix(line, "(10, 1, '2019-05-01 10:30:00'") || !strings.HasSuffix(line, "'post', '', 3),") {
+				t.Errorf("Unexpected post row: %s", line)
+			}
+		}
+	}
+}
+
+// TestExportMySQL tests the MySQL dump for reseeding WordPress
+func TestExportMySQL(t *testing.T) {
+	site := jsonTestSite()
+	site.Channel.Items[0].Content = "It's a\\path\n"
+
+	var buf bytes.Buffer
+	opts := SQLOptions{Dialect: SQLDialectMySQL, TablePrefix: "blog_", BatchSize: 2}
+	if err := ExportSQL(&buf, site, opts); err != nil {
+		t.Fatalf("ExportSQL failed: %v", err)
+	}
+	dump := buf.String()
+
+	expected := []string{
+		"SET NAMES utf8mb4;",
+		"CREATE TABLE `blog_posts` (",
+		"`ID` bigint(20) unsigned NOT NULL AUTO_INCREMENT,",
+		"KEY `meta_key` (`meta_key`(191))",
+		"UNIQUE KEY `term_id_taxonomy` (`term_id`, `taxonomy`)",
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		"START TRANSACTION;",
+		`'It\'s a\\path\n'`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(dump, s) {
+			t.Errorf("Expected the dump to contain %q", s)
+		}
+	}
+	if strings.Contains(dump, "wp_") || strings.Contains(dump, "CREATE INDEX") {
+		t.Error("Expected the table prefix and inline keys")
+	}
+	if n := strings.Count(dump, "INSERT INTO `blog_posts`"); n != 4 {
+		t.Errorf("Expected 8 posts in batches of 2, got %d statements", n)
+	}
+
+	// The nicename is cut to 50 characters, not bytes
+	site.Channel.Authors[0].Login = strings.Repeat("ü", 49) + "-x"
+	rows := sqlRows(site)
+	if nicename := rows["users"][0][3].(string); nicename != strings.Repeat("ü", 49) || !utf8.ValidString(nicename) {
+		t.Errorf("Unexpected nicename %q", nicename)
+	}
+
+	buf.Reset()
+	opts.SkipSchema = true
+	ExportSQL(&buf, site, opts)
+	if strings.Contains(buf.String(), "CREATE TABLE") || !strings.Contains(buf.String(), "INSERT INTO `blog_users`") {
+		t.Error("Expected only rows without the schema")
+	}
+}
+
+// TestSQLTermIDs tests IDs for undeclared terms and conflicting term IDs
+func TestSQLTermIDs(t *testing.T) {
+	site := jsonTestSite()
+	site.Channel.Terms = append(site.Channel.Terms, Term{TermID: 3, Taxonomy: "genre", Slug: "drama", Name: "Drama", Parent: "sci-fi"})
+
+	rows := sqlRows(site)
+	ids := make(map[string]int)
+	for _, row := range rows["terms"] {
+		ids[row[2].(string)] = row[0].(int)
+	}
+	if ids["news"] != 3 || ids["sci-fi"] != 7 || ids["drama"] != 8 || ids["go"] != 9 {
+		t.Errorf("Unexpected term IDs: %v", ids)
+	}
+	for _, row := range rows["term_taxonomy"] {
+		if row[1] == 8 && row[4] != 7 {
+			t.Errorf("Expected the parent's term ID, got %v", row[4])
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package wpimport
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tableCell is a <td> or <th> placed in a table grid
+type tableCell struct {
+	Node    *htmlNode
+	Header  bool
+	Row     int
+	Col     int
+	Rowspan int
+	Colspan int
+	Align   string
+}
+
+// tableModel is an HTML table expanded into a grid of slots. A cell that
+// spans several rows or columns occupies all of its slots.
+type tableModel struct {
+	Node    *htmlNode
+	Caption *htmlNode
+
+	// Grid holds a row of slots per table row; missing cells are nil
+	Grid [][]*tableCell
+
+	// HeaderRows is the number of leading header rows
+	HeaderRows int
+
+	Columns int
+}
+
+// maxTableSpan caps colspan and rowspan values like browsers do
+const maxTableSpan = 1000
+
+var (
+	tableAlignStyle = regexp.MustCompile(`(?i)text-align\s*:\s*(left|right|center)`)
+	tableCellBreaks = regexp.MustCompile(`\\?[ \t]*\n+[ \t]*`)
+	tableBlankLines = regexp.MustCompile(`\n\s*\n`)
+)
+
+// Helper function to build the grid for a <table> element. Nested tables
+// stay inside their cells.
+func newTableModel(table *htmlNode) *tableModel {
+	m := &tableModel{Node: table}
+
+	var head, body, foot []*htmlNode
+	for _, child := range table.Elements() {
+		switch child.Tag {
+		case "caption":
+			if m.Caption == nil {
+				m.Caption = child
+			}
+		case "thead":
+			head = append(head, tableRows(child)...)
+		case "tbody":
+			body = append(body, tableRows(child)...)
+		case "tfoot":
+			foot = append(foot, tableRows(child)...)
+		case "tr":
+			body = append(body, child)
+		}
+	}
+	rows := append(append(head, body...), foot...)
+	m.Grid = make([][]*tableCell, len(rows))
+
+	for r, tr := range rows {
+		c := 0
+		for _, td := range tr.Elements() {
+			if td.Tag != "td" && td.Tag != "th" {
+				continue
+			}
+			for c < len(m.Grid[r]) && m.Grid[r][c] != nil {
+				c++
+			}
+
+			colspan := tableSpan(td.Attr("colspan"), 1)
+			rowspan := tableSpan(td.Attr("rowspan"), len(rows)-r)
+			if rowspan > len(rows)-r {
+				rowspan = len(rows) - r
+			}
+			cell := &tableCell{
+				Node:    td,
+				Header:  td.Tag == "th",
+				Row:     r,
+				Col:     c,
+				Rowspan: rowspan,
+				Colspan: colspan,
+				Align:   tableCellAlign(td),
+			}
+			for dr := 0; dr < rowspan; dr++ {
+				for dc := 0; dc < colspan; dc++ {
+					for len(m.Grid[r+dr]) <= c+dc {
+						m.Grid[r+dr] = append(m.Grid[r+dr], nil)
+					}
+					if m.Grid[r+dr][c+dc] == nil {
+						m.Grid[r+dr][c+dc] = cell
+					}
+				}
+			}
+			c += colspan
+		}
+	}
+
+	for _, row := range m.Grid {
+		if len(row) > m.Columns {
+			m.Columns = len(row)
+		}
+	}
+	for r := range m.Grid {
+		for len(m.Grid[r]) < m.Columns {
+			m.Grid[r] = append(m.Grid[r], nil)
+		}
+	}
+
+	// Without a <thead>, leading rows of <th> cells are the header
+	m.HeaderRows = len(head)
+	if m.HeaderRows == 0 {
+		for _, row := range m.Grid {
+			if !tableRowIsHeader(row) {
+				break
+			}
+			m.HeaderRows++
+		}
+	}
+	return m
+}
+
+// Helper function to collect the rows of a table section
+func tableRows(section *htmlNode) []*htmlNode {
+	var rows []*htmlNode
+	for _, child := range section.Elements() {
+		if child.Tag == "tr" {
+			rows = append(rows, child)
+		}
+	}
+	return rows
+}
+
+// Helper function to parse a colspan or rowspan value. A rowspan of 0
+// extends to the last row, which is passed as zero.
+func tableSpan(value string, zero int) int {
+	if value == "" {
+		return 1
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil || n < 0:
+		return 1
+	case n == 0:
+		return max(zero, 1)
+	case n > maxTableSpan:
+		return maxTableSpan
+	}
+	return n
+}
+
+// Helper function to read a cell's alignment from align or style
+func tableCellAlign(td *htmlNode) string {
+	if align := strings.ToLower(td.Attr("align")); align == "left" || align == "right" || align == "center" {
+		return align
+	}
+	if m := tableAlignStyle.FindStringSubmatch(td.Attr("style")); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
+
+// Helper function to check whether every cell in a row is a header cell
+func tableRowIsHeader(row []*tableCell) bool {
+	seen := false
+	for _, cell := range row {
+		if cell == nil {
+			continue
+		}
+		if !cell.Header {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+// Helper function to check for cells spanning several slots
+func (m *tableModel) hasSpans() bool {
+	for _, row := range m.Grid {
+		for _, cell := range row {
+			if cell != nil && (cell.Rowspan > 1 || cell.Colspan > 1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Helper function to pick the alignment of a column from its first
+// aligned cell
+func (m *tableModel) columnAlign(col int) string {
+	for _, row := range m.Grid {
+		if cell := row[col]; cell != nil && cell.Align != "" {
+			return cell.Align
+		}
+	}
+	return ""
+}
+
+// tableBlockContent lists elements a GFM table cell cannot hold
+var tableBlockContent = []string{
+	"table", "ul", "ol", "dl", "pre", "blockquote", "figure", "hr", "div",
+	"h1", "h2", "h3", "h4", "h5", "h6",
+}
+
+// Helper function to replace the outermost tables in content with their
+// rendering in the given format
+func (c *Converter) replaceTables(content string, format OutputFormat, verbatim *verbatimStore) string {
+	if !strings.Contains(strings.ToLower(content), "<table") {
+		return content
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for _, table := range parseHTML(content).Find("table") {
+		m := newTableModel(table)
+		var rendered string
+		if format == FormatPlainText {
+			rendered = c.tableToText(m)
+		} else {
+			rendered = c.tableToMarkdown(m)
+		}
+		sb.WriteString(content[pos:table.Start])
+		sb.WriteString("\n\n" + verbatim.protect(rendered) + "\n\n")
+		pos = table.End
+	}
+	sb.WriteString(content[pos:])
+	return sb.String()
+}
+
+// Helper function to render a table as a GFM pipe table, or as cleaned
+// HTML when Markdown cannot express it (spans, several header rows or
+// block content in cells)
+func (c *Converter) tableToMarkdown(m *tableModel) string {
+	caption := ""
+	if m.Caption != nil {
+		if text := strings.TrimSpace(c.HTMLToMarkdown(m.Caption.InnerHTML())); text != "" {
+			caption = "*" + text + "*\n\n"
+		}
+	}
+	if m.Columns == 0 {
+		return strings.TrimSpace(caption)
+	}
+	if m.hasSpans() || m.HeaderRows > 1 {
+		return tableToHTML(m)
+	}
+
+	cells := make([][]string, len(m.Grid))
+	widths := make([]int, m.Columns)
+	for i := range widths {
+		widths[i] = 3
+	}
+	for r, row := range m.Grid {
+		cells[r] = make([]string, m.Columns)
+		for col, cell := range row {
+			if cell == nil {
+				continue
+			}
+			if len(cell.Node.Find(tableBlockContent...)) > 0 || len(cell.Node.Find("p")) > 1 {
+				return tableToHTML(m)
+			}
+			text := strings.TrimSpace(c.HTMLToMarkdown(cell.Node.InnerHTML()))
+			text = tableCellBreaks.ReplaceAllString(text, "<br>")
+			text = strings.ReplaceAll(text, "|", `\|`)
+			cells[r][col] = text
+			widths[col] = max(widths[col], displayWidth(text))
+		}
+	}
+
+	// GFM needs a header row; without one the first row is used
+	var sb strings.Builder
+	sb.WriteString(caption)
+	for r, row := range cells {
+		sb.WriteString("|")
+		for col, text := range row {
+			sb.WriteString(" " + padDisplay(text, widths[col], "") + " |")
+		}
+		sb.WriteString("\n")
+		if r == 0 {
+			sb.WriteString("|")
+			for col, width := range widths {
+				sb.WriteString(" " + tableDelimiter(m.columnAlign(col), width) + " |")
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Helper function to build a GFM delimiter cell for an alignment
+func tableDelimiter(align string, width int) string {
+	switch align {
+	case "left":
+		return ":" + strings.Repeat("-", width-1)
+	case "right":
+		return strings.Repeat("-", width-1) + ":"
+	case "center":
+		return ":" + strings.Repeat("-", width-2) + ":"
+	}
+	return strings.Repeat("-", width)
+}
+
+// tableStructureTags are the elements that make up a table's layout
+var tableStructureTags = map[string]bool{
+	"table": true, "caption": true, "colgroup": true, "col": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+}
+
+// Helper function to write a table as HTML with one row per line, keeping
+// only the structural attributes of its layout elements and dropping
+// presentation attributes
+func tableToHTML(m *tableModel) string {
+	var sb strings.Builder
+	writeTableHTML(&sb, m.Node)
+	return tableBlankLines.ReplaceAllString(sb.String(), "\n")
+}
+
+// Helper function to serialize a node for tableToHTML, laying out nested
+// tables the same way
+func writeTableHTML(sb *strings.Builder, n *htmlNode) {
+	keep := func(tag string, attr htmlAttr) bool {
+		if tableStructureTags[tag] {
+			switch attr.Name {
+			case "colspan", "rowspan", "span", "scope", "headers", "align":
+				return true
+			}
+			return false
+		}
+		return attr.Name != "class" && attr.Name != "style" && !strings.HasPrefix(attr.Name, "data-")
+	}
+	if n.Tag == "" || n.Tag == "!--" {
+		n.writeHTML(sb, nil)
+		return
+	}
+
+	sb.WriteString("<" + n.Tag)
+	for _, attr := range n.Attrs {
+		if keep(n.Tag, attr) {
+			sb.WriteString(" " + attr.Name + `="` + html.EscapeString(attr.Value) + `"`)
+		}
+	}
+	sb.WriteString(">")
+	if htmlVoidElements[n.Tag] {
+		return
+	}
+
+	// Sections go on their own lines, cells stay on their row's line and
+	// cell content is written as-is
+	newline := ""
+	switch n.Tag {
+	case "table", "thead", "tbody", "tfoot", "colgroup":
+		newline = "\n"
+	}
+	for _, child := range n.Children {
+		if newline != "" || n.Tag == "tr" {
+			if child.Tag == "" && strings.TrimSpace(child.Text) == "" {
+				continue
+			}
+		}
+		sb.WriteString(newline)
+		writeTableHTML(sb, child)
+	}
+	sb.WriteString(newline + "</" + n.Tag + ">")
+}
+
+// Helper function to convert an HTML fragment to plain text, including
+// lists, tables and figures
+func (c *Converter) fragmentToPlainText(fragment string) string {
+	verbatim := newVerbatimStore()
+	return verbatim.restore(c.plainText(fragment, verbatim))
+}
+
+// Helper function to render a table as an aligned ASCII table. Cells keep
+// their spans; rows are separated by rules when cells span rows or hold
+// several lines.
+func (c *Converter) tableToText(m *tableModel) string {
+	caption := ""
+	if m.Caption != nil {
+		if text := strings.TrimSpace(c.fragmentToPlainText(m.Caption.InnerHTML())); text != "" {
+			caption = text + "\n"
+		}
+	}
+	if m.Columns == 0 {
+		return strings.TrimSpace(caption)
+	}
+
+	// Cell contents as lines, which are never wrapped
+	cells := *c
+	cells.PlainText.Width = 0
+	lines := make(map[*tableCell][]string)
+	for _, row := range m.Grid {
+		for _, cell := range row {
+			if cell != nil && lines[cell] == nil {
+				text := strings.TrimSpace(cells.fragmentToPlainText(cell.Node.InnerHTML()))
+				lines[cell] = strings.Split(text, "\n")
+			}
+		}
+	}
+
+	// Column widths, widening the last column of a span when needed
+	widths := make([]int, m.Columns)
+	for i := range widths {
+		widths[i] = 1
+	}
+	for _, pass := range []bool{false, true} {
+		for _, row := range m.Grid {
+			for col, cell := range row {
+				if cell == nil || cell.Col != col || (cell.Colspan > 1) != pass {
+					continue
+				}
+				need := 0
+				for _, line := range lines[cell] {
+					need = max(need, displayWidth(line))
+				}
+				have := m.spanWidth(widths, col, cell.Colspan)
+				if need > have {
+					widths[col+cell.Colspan-1] += need - have
+				}
+			}
+		}
+	}
+
+	// Row heights, growing the last row of a row span when needed
+	heights := make([]int, len(m.Grid))
+	multiline := false
+	for r := range heights {
+		heights[r] = 1
+	}
+	for _, pass := range []bool{false, true} {
+		for r, row := range m.Grid {
+			for col, cell := range row {
+				if cell == nil || cell.Row != r || cell.Col != col || (cell.Rowspan > 1) != pass {
+					continue
+				}
+				have := 0
+				for i := r; i < r+cell.Rowspan; i++ {
+					have += heights[i]
+				}
+				if need := len(lines[cell]); need > have {
+					heights[r+cell.Rowspan-1] += need - have
+				}
+			}
+		}
+	}
+	for _, h := range heights {
+		multiline = multiline || h > 1
+	}
+	ruled := multiline || m.hasSpans()
+
+	var sb strings.Builder
+	sb.WriteString(caption)
+	sb.WriteString(m.textRule(widths, -1, '-') + "\n")
+	for r, row := range m.Grid {
+		for k := 0; k < heights[r]; k++ {
+			for col := 0; col < m.Columns; {
+				cell := row[col]
+				if cell == nil {
+					sb.WriteString("| " + strings.Repeat(" ", widths[col]) + " ")
+					col++
+					continue
+				}
+				offset := k
+				for i := cell.Row; i < r; i++ {
+					offset += heights[i]
+				}
+				text := ""
+				if offset < len(lines[cell]) {
+					text = lines[cell][offset]
+				}
+				align := cell.Align
+				if align == "" && cell.Header {
+					align = "center"
+				}
+				width := m.spanWidth(widths, col, cell.Colspan-(col-cell.Col))
+				sb.WriteString("| " + padDisplay(text, width, align) + " ")
+				col += cell.Colspan - (col - cell.Col)
+			}
+			sb.WriteString("|\n")
+		}
+
+		switch {
+		case r == len(m.Grid)-1:
+		case r == m.HeaderRows-1:
+			sb.WriteString(m.textRule(widths, -1, '=') + "\n")
+		case ruled:
+			sb.WriteString(m.textRule(widths, r, '-') + "\n")
+		}
+	}
+	sb.WriteString(m.textRule(widths, -1, '-'))
+	return sb.String()
+}
+
+// Helper function to compute the width of span columns starting at col,
+// including the separators between them
+func (m *tableModel) spanWidth(widths []int, col, span int) int {
+	width := 0
+	for i := col; i < col+span && i < len(widths); i++ {
+		width += widths[i]
+	}
+	return width + 3*(span-1)
+}
+
+// Helper function to draw a horizontal rule. Below row r (when r >= 0)
+// the rule is left open where a cell continues into the next row.
+func (m *tableModel) textRule(widths []int, r int, fill byte) string {
+	var sb strings.Builder
+	open := func(col int) bool {
+		return r >= 0 && col >= 0 && m.Grid[r][col] != nil && m.Grid[r][col] == m.Grid[r+1][col]
+	}
+	for col, width := range widths {
+		switch {
+		case col == 0 || !open(col-1) || !open(col):
+			sb.WriteByte('+')
+		case m.Grid[r][col-1] == m.Grid[r][col]:
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte('|')
+		}
+		segment := fill
+		if open(col) {
+			segment = ' '
+		}
+		sb.WriteString(strings.Repeat(string(segment), width+2))
+	}
+	sb.WriteString("+")
+	return sb.String()
+}
@@ -0,0 +1,92 @@
+package wpimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// spanningTable has a caption, a header colspan and a body rowspan
+const spanningTable = `<table class="wp-table"><caption>Quarterly <b>sales</b></caption>
+<thead><tr><th>Region</th><th colspan="2">Sales</th></tr></thead>
+<tbody><tr><td rowspan="2">North</td><td>Q1</td><td align="right">100</td></tr>
+<tr><td>Q2</td><td style="text-align: right">2000</td></tr>
+<tr><td>日本</td><td>Q1</td><td>5</td></tr></tbody></table>`
+
+// TestTableModel tests span expansion in the table grid
+func TestTableModel(t *testing.T) {
+	m := newTableModel(parseHTML(spanningTable).Find("table")[0])
+
+	if m.Columns != 3 || len(m.Grid) != 4 || m.HeaderRows != 1 {
+		t.Fatalf("Expected 4x3 grid with one header row, got %dx%d with %d", len(m.Grid), m.Columns, m.HeaderRows)
+	}
+	if m.Grid[0][1] != m.Grid[0][2] {
+		t.Error("Expected colspan cell to occupy both header slots")
+	}
+	if m.Grid[1][0] != m.Grid[2][0] || m.Grid[2][1].Node.TextContent() != "Q2" {
+		t.Error("Expected rowspan cell to push the next row's cells right")
+	}
+	if m.Caption == nil || m.Caption.TextContent() != "Quarterly sales" {
+		t.Error("Expected caption to be kept")
+	}
+}
+
+// TestTableMarkdown tests GFM tables and the HTML fallback
+func TestTableMarkdown(t *testing.T) {
+	simple := `<table><caption>Stock</caption><tr><th>Name</th><th align="center">Qty</th></tr>
+<tr><td>A | B</td><td>1<br>2</td></tr><tr><td><strong>x</strong></td><td>3</td></tr></table>`
+
+	want := "*Stock*\n\n" +
+		"| Name   | Qty    |\n" +
+		"| ------ | :----: |\n" +
+		"| A \\| B | 1<br>2 |\n" +
+		"| **x**  | 3      |"
+	if got := ConvertToMarkdown(simple); got != want {
+		t.Errorf("Expected GFM table:\n%s\ngot:\n%s", want, got)
+	}
+
+	fallback := ConvertToMarkdown(spanningTable)
+	for _, want := range []string{
+		"<table>\n<caption>Quarterly <b>sales</b></caption>",
+		`<tr><th>Region</th><th colspan="2">Sales</th></tr>`,
+		`<tr><td rowspan="2">North</td><td>Q1</td><td align="right">100</td></tr>`,
+		"<tr><td>Q2</td><td>2000</td></tr>",
+	} {
+		if !strings.Contains(fallback, want) {
+			t.Errorf("Expected HTML fallback to contain %q, got:\n%s", want, fallback)
+		}
+	}
+
+	nested := ConvertToMarkdown(`<table><tr><td>Outer</td><td><table><tr><td>Inner</td></tr></table></td></tr></table>`)
+	if strings.Count(nested, "<table>") != 2 || !strings.Contains(nested, "<tr><td>Inner</td></tr>") {
+		t.Errorf("Expected nested table to fall back to HTML, got:\n%s", nested)
+	}
+}
+
+// TestTablePlainText tests aligned ASCII tables
+func TestTablePlainText(t *testing.T) {
+	want := `Quarterly sales
++--------+----+------+
+| Region |   Sales   |
++========+====+======+
+| North  | Q1 |  100 |
++        +----+------+
+|        | Q2 | 2000 |
++--------+----+------+
+| 日本   | Q1 | 5    |
++--------+----+------+`
+	if got := ConvertToPlainText(spanningTable); got != want {
+		t.Errorf("Expected ASCII table:\n%s\ngot:\n%s", want, got)
+	}
+
+	nested := ConvertToPlainText(`<table><tr><td>Outer</td><td><table><tr><th>In</th></tr><tr><td>1</td></tr></table></td></tr></table>`)
+	want = `+-------+--------+
+| Outer | +----+ |
+|       | | In | |
+|       | +====+ |
+|       | | 1  | |
+|       | +----+ |
++-------+--------+`
+	if nested != want {
+		t.Errorf("Expected nested ASCII table:\n%s\ngot:\n%s", want, nested)
+	}
+}
@@ -0,0 +1,491 @@
+package wpimport
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ExportItem is the view of an item that export templates work with
+type ExportItem struct {
+	ID     int
+	Type   string
+	Status string
+	Title  string
+
+	// Slug is the item's post name, or one made from its title
+	Slug string
+
+	// Path is the slugs of the item's parents and the item, e.g.
+	// "about/team"
+	Path string
+
+	Date      time.Time
+	Modified  time.Time
+	Draft     bool
+	MenuOrder int
+
+	// Permalink is the path of the item's URL on the old site, e.g.
+	// "/2019/05/hello-world/", or "" for drafts
+	Permalink string
+
+	// URL is the item's new URL, from the URL template
+	URL string
+
+	// Author is the author's display name, AuthorLogin their login
+	Author      string
+	AuthorLogin string
+
+	Categories []string
+	Tags       []string
+
+	// Taxonomies holds the terms of custom taxonomies by taxonomy name
+	Taxonomies map[string][]string
+
+	// Meta holds the item's post meta; for repeated keys the first value
+	Meta map[string]string
+
+	// Excerpt is the item's excerpt as plain text
+	Excerpt string
+
+	// FeaturedImage is the URL of the featured image, mapped like the
+	// body's media
+	FeaturedImage string
+
+	// Body is the content converted to Markdown
+	Body string
+
+	// Item is the exported item
+	Item *Item
+}
+
+// TemplateExporter writes a site using text/template strings over
+// ExportItem for the file layout and front matter, so any static site
+// generator that reads Markdown with front matter can be targeted. Presets
+// are available for Eleventy, Astro, Zola and Docusaurus.
+//
+// Besides the built-in functions, templates can use quote (a string in
+// double quotes, valid in YAML and TOML), list (a list of quoted
+// strings), date (RFC 3339, or "" for the zero time), format (a time in
+// the given layout), slugify, join and lower.
+type TemplateExporter struct {
+	// Path is the template for the file an item is written to, relative
+	// to the export directory. Items for which it gives "" are skipped.
+	Path string
+
+	// FrontMatter is the template for the front matter, including its
+	// delimiters
+	FrontMatter string
+
+	// Content is the template for the text after the front matter; when
+	// empty it is the converted body
+	Content string
+
+	// URL is the template for an item's new URL, which links between
+	// items are rewritten to. When empty, links are left alone.
+	URL string
+
+	// Converter converts the content to Markdown. When nil, a converter
+	// with the default settings for the site is used.
+	Converter *Converter
+
+	// UploadsDir is a local copy of wp-content/uploads. When set, the
+	// files the items use are copied to MediaDir/uploads in the export and
+	// linked as /uploads/....
+	UploadsDir string
+
+	// MediaDir is the directory of the export that is served from the
+	// site root, such as "static" or "public"
+	MediaDir string
+
+	// Drafts exports draft, pending and private items with Draft set
+	Drafts bool
+}
+
+// exportTemplateFuncs are the functions available to export templates
+var exportTemplateFuncs = template.FuncMap{
+	"quote":   quoteFrontMatter,
+	"list":    func(list []string) string { return frontMatterValue(list) },
+	"date":    frontMatterTime,
+	"format":  func(layout string, t time.Time) string { return t.Format(layout) },
+	"slugify": slugify,
+	"join":    func(sep string, list []string) string { return strings.Join(list, sep) },
+	"lower":   strings.ToLower,
+}
+
+// Export writes the site under dir. The Path and URL templates run before
+// the content is converted, so Body and FeaturedImage are empty in them, as
+// is URL in both.
+func (e *TemplateExporter) Export(site *WordPressSite, dir string) error {
+	pathTmpl, err := parseExportTemplate("path", e.Path)
+	if err != nil {
+		return err
+	}
+	frontMatterTmpl, err := parseExportTemplate("front matter", e.FrontMatter)
+	if err != nil {
+		return err
+	}
+	content := e.Content
+	if content == "" {
+		content = "{{.Body}}\n"
+	}
+	contentTmpl, err := parseExportTemplate("content", content)
+	if err != nil {
+		return err
+	}
+	urlTmpl, err := parseExportTemplate("url", e.URL)
+	if err != nil {
+		return err
+	}
+
+	// Place the items
+	tree := site.PageTree()
+	taken := make(map[string]bool)
+	var entries []exportEntry
+	var views []*ExportItem
+	for i := range site.Channel.Items {
+		item := &site.Channel.Items[i]
+		if !exportable(item, e.Drafts) {
+			continue
+		}
+		view := newExportItem(site, tree, item)
+		file, err := executeExportTemplate(pathTmpl, view)
+		if err != nil {
+			return err
+		}
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		file = path.Clean(file)
+		if path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+			return fmt.Errorf("path template gives %q for item %d, which is outside the export", file, item.PostID)
+		}
+		if taken[file] {
+			ext := path.Ext(file)
+			file = strings.TrimSuffix(file, ext) + "-" + strconv.Itoa(item.PostID) + ext
+		}
+		taken[file] = true
+
+		url, err := executeExportTemplate(urlTmpl, view)
+		if err != nil {
+			return err
+		}
+		view.URL = strings.TrimSpace(url)
+		entries = append(entries, exportEntry{item: item, file: file, url: view.URL})
+		views = append(views, view)
+	}
+
+	converter := exportConverter(site, e.Converter, entries)
+	var uploads *exportBundle
+	if e.UploadsDir != "" {
+		uploads = newExportBundle(e.UploadsDir)
+		uploads.keepPaths = true
+		converter.Media = NewMediaMapper(site, func(file MediaFile) string {
+			if name := uploads.add(file); name != "" {
+				return "/" + name
+			}
+			return ""
+		})
+	}
+
+	for i, entry := range entries {
+		view := views[i]
+		view.Body = strings.TrimSpace(converter.ForItem(entry.item).ToMarkdown(entry.item.Content))
+		view.FeaturedImage = exportFeaturedImage(site, entry.item, converter.Media)
+
+		frontMatter, err := executeExportTemplate(frontMatterTmpl, view)
+		if err != nil {
+			return err
+		}
+		body, err := executeExportTemplate(contentTmpl, view)
+		if err != nil {
+			return err
+		}
+		if err := writeExportFile(filepath.Join(dir, filepath.FromSlash(entry.file)), frontMatter+"\n"+body); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.file, err)
+		}
+	}
+
+	if uploads != nil {
+		if err := uploads.copyTo(filepath.Join(dir, filepath.FromSlash(e.MediaDir))); err != nil {
+			return fmt.Errorf("failed to copy media: %w", err)
+		}
+	}
+	return nil
+}
+
+// Helper function to build the view of an item, without its body
+func newExportItem(site *WordPressSite, tree *PageTree, item *Item) *ExportItem {
+	view := &ExportItem{
+		ID:          item.PostID,
+		Type:        item.PostType,
+		Status:      item.Status,
+		Title:       itemTitle(item),
+		Slug:        itemSlug(item),
+		Path:        tree.Path(item),
+		Draft:       isDraft(item),
+		MenuOrder:   item.MenuOrder,
+		Author:      itemAuthorName(site, item),
+		AuthorLogin: item.Creator,
+		Categories:  itemTermNames(item, "category"),
+		Tags:        itemTermNames(item, "post_tag"),
+		Taxonomies:  make(map[string][]string),
+		Meta:        make(map[string]string),
+		Item:        item,
+	}
+	view.Date, _ = item.PublishedTime()
+	view.Modified, _ = item.ModifiedTime()
+	if !view.Draft {
+		view.Permalink = permalinkPath(item)
+	}
+	for _, taxonomy := range itemCustomTaxonomies(item) {
+		view.Taxonomies[taxonomy] = itemTermNames(item, taxonomy)
+	}
+	for _, meta := range item.PostMeta {
+		if _, ok := view.Meta[meta.Key]; !ok {
+			view.Meta[meta.Key] = meta.Value
+		}
+	}
+	if item.Excerpt != "" {
+		view.Excerpt = strings.TrimSpace(ConvertToPlainText(item.Excerpt))
+	}
+	return view
+}
+
+// Helper function to get the URL of an item's featured image, mapped by
+// media when it rewrites URLs
+func exportFeaturedImage(site *WordPressSite, item *Item, media *MediaMapper) string {
+	image := featuredImage(site, item)
+	if image == nil {
+		return ""
+	}
+	src := image.GetAttachmentURL()
+	if media != nil && media.NewURL != nil {
+		if file, ok := media.Match(src); ok {
+			if newURL := media.NewURL(file); newURL != "" {
+				return newURL
+			}
+		}
+	}
+	return src
+}
+
+// Helper function to parse an export template
+func parseExportTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(exportTemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Helper function to run an export template for an item
+func executeExportTemplate(tmpl *template.Template, view *ExportItem) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, view); err != nil {
+		return "", fmt.Errorf("failed to execute %s template for item %d: %w", tmpl.Name(), view.ID, err)
+	}
+	return sb.String(), nil
+}
+
+// exportPresetPath is the file layout shared by the presets: posts under
+// a directory of their own, pages at their path and custom post types
+// under their type. It takes the posts directory and the prefixes for pages
+// and for custom post types.
+const exportPresetPath = `{{if eq .Type "post"}}%s/{{.Slug}}.md` +
+	`{{else if eq .Type "page"}}%s{{.Path}}.md` +
+	`{{else}}%s{{.Type}}/{{.Path}}.md{{end}}`
+
+// NewEleventyExporter creates a template exporter for Eleventy. Posts go
+// to posts/, pages to their path and custom post types to a directory per
+// type. Each item keeps its old URL as its permalink, and media is copied
+// to uploads/ (add it as a passthrough copy).
+func NewEleventyExporter() *TemplateExporter {
+	return &TemplateExporter{
+		Path: fmt.Sprintf(exportPresetPath, "posts", "", ""),
+		FrontMatter: `---
+title: {{quote .Title}}
+{{- with date .Date}}
+date: {{.}}
+{{- end}}
+{{- if not (.Modified.Equal .Date)}}
+updated: {{date .Modified}}
+{{- end}}
+{{- with .Excerpt}}
+description: {{quote .}}
+{{- end}}
+{{- with .Permalink}}
+permalink: {{quote .}}
+{{- end}}
+{{- if .Draft}}
+draft: true
+{{- end}}
+{{- with .Tags}}
+tags: {{list .}}
+{{- end}}
+{{- with .Categories}}
+categories: {{list .}}
+{{- end}}
+{{- range $taxonomy, $terms := .Taxonomies}}
+{{$taxonomy}}: {{list $terms}}
+{{- end}}
+{{- with .Author}}
+author: {{quote .}}
+{{- end}}
+{{- with .FeaturedImage}}
+image: {{quote .}}
+{{- end}}
+---
+`,
+		URL: `{{.Permalink}}`,
+	}
+}
+
+// NewAstroExporter creates a template exporter for Astro content
+// collections: posts go to src/content/blog with the front matter of
+// Astro's blog template, pages to src/content/pages and custom post types
+// to a collection per type. Media is copied to public/uploads.
+func NewAstroExporter() *TemplateExporter {
+	return &TemplateExporter{
+		Path: fmt.Sprintf(exportPresetPath, "src/content/blog", "src/content/pages/", "src/content/"),
+		FrontMatter: `---
+title: {{quote .Title}}
+{{- with .Excerpt}}
+description: {{quote .}}
+{{- end}}
+{{- with date .Date}}
+pubDate: {{.}}
+{{- end}}
+{{- if not (.Modified.Equal .Date)}}
+updatedDate: {{date .Modified}}
+{{- end}}
+{{- with .FeaturedImage}}
+heroImage: {{quote .}}
+{{- end}}
+{{- if .Draft}}
+draft: true
+{{- end}}
+{{- with .Author}}
+author: {{quote .}}
+{{- end}}
+{{- with .Categories}}
+categories: {{list .}}
+{{- end}}
+{{- with .Tags}}
+tags: {{list .}}
+{{- end}}
+{{- range $taxonomy, $terms := .Taxonomies}}
+{{$taxonomy}}: {{list $terms}}
+{{- end}}
+---
+`,
+		URL:      `{{if eq .Type "post"}}/blog/{{.Slug}}/{{else if eq .Type "page"}}/{{.Path}}/{{else}}/{{.Type}}/{{.Path}}/{{end}}`,
+		MediaDir: "public",
+	}
+}
+
+// NewZolaExporter creates a template exporter for Zola with TOML front
+// matter. Posts go to content/posts, pages to content/<path>.md and custom
+// post types to a section per type; old URLs that changed become aliases. Categories,
+// tags and custom taxonomies must be declared in config.toml. Media is
+// copied to static/uploads.
+func NewZolaExporter() *TemplateExporter {
+	return &TemplateExporter{
+		Path: fmt.Sprintf(exportPresetPath, "content/posts", "content/", "content/"),
+		FrontMatter: `+++
+title = {{quote .Title}}
+{{- with date .Date}}
+date = {{.}}
+{{- end}}
+{{- if not (.Modified.Equal .Date)}}
+updated = {{date .Modified}}
+{{- end}}
+{{- if .Draft}}
+draft = true
+{{- end}}
+slug = {{quote .Slug}}
+{{- if and .Permalink (ne .Permalink .URL)}}
+aliases = [{{quote .Permalink}}]
+{{- end}}
+{{- with .Excerpt}}
+description = {{quote .}}
+{{- end}}
+{{- if or .Categories .Tags .Taxonomies}}
+
+[taxonomies]
+{{- with .Categories}}
+categories = {{list .}}
+{{- end}}
+{{- with .Tags}}
+tags = {{list .}}
+{{- end}}
+{{- range $taxonomy, $terms := .Taxonomies}}
+{{$taxonomy}} = {{list $terms}}
+{{- end}}
+{{- end}}
+{{- if or .Author .FeaturedImage}}
+
+[extra]
+{{- with .Author}}
+author = {{quote .}}
+{{- end}}
+{{- with .FeaturedImage}}
+image = {{quote .}}
+{{- end}}
+{{- end}}
++++
+`,
+		URL:      `{{if eq .Type "post"}}/posts/{{.Slug}}/{{else if eq .Type "page"}}/{{.Path}}/{{else}}/{{.Type}}/{{.Path}}/{{end}}`,
+		MediaDir: "static",
+	}
+}
+
+// NewDocusaurusExporter creates a template exporter for Docusaurus. Posts
+// go to the blog as blog/YYYY-MM-DD-slug.md; pages and custom post types
+// become docs, ordered in the sidebar by their menu order. Media is copied
+// to static/uploads.
+func NewDocusaurusExporter() *TemplateExporter {
+	return &TemplateExporter{
+		Path: `{{if eq .Type "post"}}blog/{{format "2006-01-02" .Date}}-{{.Slug}}.md` +
+			`{{else if eq .Type "page"}}docs/{{.Path}}.md` +
+			`{{else}}docs/{{.Type}}/{{.Path}}.md{{end}}`,
+		FrontMatter: `---
+title: {{quote .Title}}
+{{- if eq .Type "post"}}
+slug: {{quote .Slug}}
+{{- with date .Date}}
+date: {{.}}
+{{- end}}
+{{- with .Author}}
+authors:
+  - name: {{quote .}}
+{{- end}}
+{{- else if .MenuOrder}}
+sidebar_position: {{.MenuOrder}}
+{{- end}}
+{{- if not (.Modified.Equal .Date)}}
+last_update:
+  date: {{date .Modified}}
+{{- end}}
+{{- with .Excerpt}}
+description: {{quote .}}
+{{- end}}
+{{- with .Tags}}
+tags: {{list .}}
+{{- end}}
+{{- with .FeaturedImage}}
+image: {{quote .}}
+{{- end}}
+{{- if .Draft}}
+draft: true
+{{- end}}
+---
+`,
+		URL:      `{{if eq .Type "post"}}/blog/{{.Slug}}{{else if eq .Type "page"}}/docs/{{.Path}}{{else}}/docs/{{.Type}}/{{.Path}}{{end}}`,
+		MediaDir: "static",
+	}
+}
@@ -0,0 +1,159 @@
+package wpimport
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestTemplateExport tests a template exporter with custom templates
+func TestTemplateExport(t *testing.T) {
+	dir := t.TempDir()
+	exporter := &TemplateExporter{
+		Path:        `{{.Type}}/{{.ID}}-{{.Slug}}.md`,
+		FrontMatter: "---\ntitle: {{quote .Title}}\nterms: {{join \",\" .Categories}}|{{index .Taxonomies \"genre\"}}\nthumb: {{index .Meta \"_thumbnail_id\"}}\n---\n",
+		Content:     "{{.Body}}\n\n<!-- {{lower .Author}} -->\n",
+		URL:         `/{{.Type}}/{{.Slug}}/`,
+	}
+	if err := exporter.Export(exportTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	expected := `---
+title: "Hello & \"World\""
+terms: News|[Sci-Fi]
+thumb: 30
+---
+
+See [the team](/page/team/).
+
+![Photo](https://oldsite.com/wp-content/uploads/2019/05/photo-300x200.jpg)
+
+<!-- jane doe -->
+`
+	if got := readExport(t, dir, "post/10-hello-world.md"); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+	readExport(t, dir, "page/21-team.md")
+	readExport(t, dir, "book/22-dune.md")
+}
+
+// TestTemplateExportErrors tests template errors and paths outside the
+// export
+func TestTemplateExportErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter *TemplateExporter
+		want     string
+	}{
+		{"parse", &TemplateExporter{Path: "{{.Slug", FrontMatter: ""}, "failed to parse path template"},
+		{"execute", &TemplateExporter{Path: "{{.Nope}}", FrontMatter: ""}, "failed to execute path template"},
+		{"escape", &TemplateExporter{Path: "../{{.Slug}}.md", FrontMatter: ""}, "outside the export"},
+	}
+	for _, tt := range tests {
+		err := tt.exporter.Export(exportTestSite(), t.TempDir())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Items the path template gives no file for are skipped
+	dir := t.TempDir()
+	exporter := &TemplateExporter{Path: `{{if eq .Type "page"}}{{.Path}}.md{{end}}`, FrontMatter: ""}
+	if err := exporter.Export(exportTestSite(), dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected about.md and about/, got %d entries", len(entries))
+	}
+}
+
+// TestTemplateExportPresets tests the file layout and front matter of each
+// preset
+func TestTemplateExportPresets(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		want  []string
+		files []string
+	}{
+		{"eleventy", "posts/hello-world.md", []string{
+			"---\ntitle: \"Hello & \\\"World\\\"\"\ndate: 2019-05-01T10:30:00+02:00\nupdated: 2019-06-01T12:00:00+02:00\n",
+			"permalink: \"/2019/05/hello-world/\"\ntags: [\"Go\", \"WordPress\"]\ncategories: [\"News\"]\ngenre: [\"Sci-Fi\"]\n",
+			"image: \"/uploads/2019/05/cover.jpg\"\n---\n\nSee [the team](/about/team/).\n\n![Photo](/uploads/2019/05/photo.jpg)\n",
+		}, []string{"about.md", "about/team.md", "book/dune.md", "uploads/2019/05/photo.jpg"}},
+		{"astro", "src/content/blog/hello-world.md", []string{
+			"description: \"A short summary.\"\npubDate: 2019-05-01T10:30:00+02:00\nupdatedDate: 2019-06-01T12:00:00+02:00\nheroImage: \"/uploads/2019/05/cover.jpg\"\n",
+			"See [the team](/about/team/).",
+		}, []string{"src/content/pages/about.md", "src/content/book/dune.md", "public/uploads/2019/05/cover.jpg"}},
+		{"zola", "content/posts/hello-world.md", []string{
+			"+++\ntitle = \"Hello & \\\"World\\\"\"\ndate = 2019-05-01T10:30:00+02:00\n",
+			"slug = \"hello-world\"\naliases = [\"/2019/05/hello-world/\"]\n",
+			"\n\n[taxonomies]\ncategories = [\"News\"]\ntags = [\"Go\", \"WordPress\"]\ngenre = [\"Sci-Fi\"]\n\n[extra]\nauthor = \"Jane Doe\"\n",
+			"+++\n\nSee [the team](/about/team/).",
+		}, []string{"content/about/team.md", "content/book/dune.md", "static/uploads/2019/05/photo.jpg"}},
+		{"zola", "content/about.md", []string{"slug = \"about\"\n+++\n"}, nil},
+		{"docusaurus", "blog/2019-05-01-hello-world.md", []string{
+			"slug: \"hello-world\"\ndate: 2019-05-01T10:30:00+02:00\nauthors:\n  - name: \"Jane Doe\"\nlast_update:\n  date: 2019-06-01T12:00:00+02:00\n",
+			"See [the team](/docs/about/team).",
+		}, []string{"docs/about.md", "docs/about/team.md", "docs/book/dune.md", "static/uploads/2019/05/photo.jpg"}},
+	}
+	for _, tt := range tests {
+		exporter, err := NewExporter(tt.name)
+		if err != nil {
+			t.Fatalf("Expected a %s exporter: %v", tt.name, err)
+		}
+		exporter.(*TemplateExporter).UploadsDir = exportTestUploads(t)
+		dir := t.TempDir()
+		if err := exporter.Export(exportTestSite(), dir); err != nil {
+			t.Fatalf("%s: export failed: %v", tt.name, err)
+		}
+		got := readExport(t, dir, tt.file)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: expected %s to contain %q, got:\n%s", tt.name, tt.file, want, got)
+			}
+		}
+		for _, name := range tt.files {
+			readExport(t, dir, name)
+		}
+	}
+
+	// Docs are ordered by menu order; drafts are marked
+	site := exportTestSite()
+	site.Channel.Items[3].MenuOrder = 2
+	dir := t.TempDir()
+	exporter := NewDocusaurusExporter()
+	exporter.Drafts = true
+	if err := exporter.Export(site, dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if team := readExport(t, dir, "docs/about/team.md"); !strings.Contains(team, "sidebar_position: 2\n") {
+		t.Errorf("Expected a sidebar position, got:\n%s", team)
+	}
+	if draft := readExport(t, dir, "blog/2020-01-01-work-in-progress.md"); !strings.Contains(draft, "draft: true\n") {
+		t.Errorf("Expected a draft, got:\n%s", draft)
+	}
+
+	if _, err := NewExporter("gatsby"); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+	if _, err := NewExporter("Hugo"); err != nil {
+		t.Errorf("Expected exporter names to ignore case, got %v", err)
+	}
+}
+
+// TestNewExportItem tests the view model of an item
+func TestNewExportItem(t *testing.T) {
+	site := exportTestSite()
+	tree := site.PageTree()
+	item := newExportItem(site, tree, &site.Channel.Items[3])
+	if item.Path != "about/team" || item.Slug != "team" || item.Permalink != "/about/team/" {
+		t.Errorf("Unexpected path, slug or permalink: %q, %q, %q", item.Path, item.Slug, item.Permalink)
+	}
+	draft := newExportItem(site, tree, &site.Channel.Items[1])
+	if !draft.Draft || draft.Permalink != "" || draft.Slug != "work-in-progress" {
+		t.Errorf("Unexpected draft view: %+v", draft)
+	}
+}
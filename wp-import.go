@@ -101,6 +101,18 @@ type Item struct {
 	PostPassword string `xml:"http://wordpress.org/export/1.2/ post_password"`
 	IsSticky     int    `xml:"http://wordpress.org/export/1.2/ is_sticky"`
 
+	// Modification dates, present in exports from newer WordPress versions
+	// and some export plugins
+	PostModified    string `xml:"http://wordpress.org/export/1.2/ post_modified"`
+	PostModifiedGMT string `xml:"http://wordpress.org/export/1.2/ post_modified_gmt"`
+
+	// Whether comments and pingbacks are open ("open" or "closed")
+	CommentStatus string `xml:"http://wordpress.org/export/1.2/ comment_status"`
+	PingStatus    string `xml:"http://wordpress.org/export/1.2/ ping_status"`
+
+	// Attachment file URL (attachments only)
+	AttachmentURL string `xml:"http://wordpress.org/export/1.2/ attachment_url"`
+
 	// Categories and Tags for this post
 	Categories []ItemCategory `xml:"category"`
 
@@ -171,6 +183,41 @@ func ParseWordPressDate(dateStr string) (time.Time, error) {
 	return time.Parse("2006-01-02 15:04:05", dateStr)
 }
 
+// PublishedTime returns when the item was published. The export has no
+// time zone, so the offset is taken from the difference between the post
+// date and its GMT counterpart; without one the time is in UTC.
+func (item *Item) PublishedTime() (time.Time, error) {
+	return parseLocalDate(item.PostDate, item.PostDateGMT)
+}
+
+// ModifiedTime returns when the item was last modified, or when it was
+// published if the export does not record modification dates
+func (item *Item) ModifiedTime() (time.Time, error) {
+	if item.PostModified == "" {
+		return item.PublishedTime()
+	}
+	return parseLocalDate(item.PostModified, item.PostModifiedGMT)
+}
+
+// Helper function to parse a local WordPress date in the zone implied by
+// its GMT counterpart. Drafts have a GMT date of all zeros.
+func parseLocalDate(local, gmt string) (time.Time, error) {
+	t, err := ParseWordPressDate(local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	u, err := ParseWordPressDate(gmt)
+	if err != nil {
+		return t, nil
+	}
+	offset := t.Sub(u).Round(15 * time.Minute)
+	if offset == 0 || offset > 14*time.Hour || offset < -14*time.Hour {
+		return t, nil
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0,
+		time.FixedZone("", int(offset/time.Second))), nil
+}
+
 // Helper function to get all posts of a specific type
 func (site *WordPressSite) GetPostsByType(postType string) []Item {
 	var posts []Item
@@ -258,6 +305,23 @@ func (site *WordPressSite) GetAttachmentURLs() []string {
 	return urls
 }
 
+// Helper function to get an attachment by ID
+func (site *WordPressSite) GetAttachmentByID(id int) *Item {
+	item := site.GetPostByID(id)
+	if item == nil || item.PostType != "attachment" {
+		return nil
+	}
+	return item
+}
+
+// Helper function to get the file URL of an attachment
+func (item *Item) GetAttachmentURL() string {
+	if item.AttachmentURL != "" {
+		return item.AttachmentURL
+	}
+	return item.GUID
+}
+
 // Helper function to get post meta by key
 func (item *Item) GetMetaValue(key string) string {
 	for _, meta := range item.PostMeta {
@@ -552,6 +616,11 @@ func SanitizeWordPressContent(content string) string {
 // CleanHTML sanitizes WordPress HTML content while preserving HTML structure
 // This returns valid, cleaned HTML that can be used for display or further processing
 func CleanHTML(content string) string {
+	return NewConverter().ToHTML(content)
+}
+
+// Helper function to clean HTML once shortcodes have been processed
+func cleanHTML(content string) string {
 	// First, use the base sanitize function to remove Gutenberg blocks
 	content = SanitizeWordPressContent(content)
 
@@ -760,13 +829,12 @@ func cleanWhitespace(content string) string {
 
 // ConvertToPlainText removes all HTML tags and returns plain text
 func ConvertToPlainText(content string) string {
-	// First check if we're dealing with nested lists
-	if strings.Contains(content, "<ul") && strings.Contains(content, "<li") &&
-		regexp.MustCompile(`<li[^>]*>.*?<ul[^>]*>`).MatchString(content) {
-		return NestedListsToPlainText(content)
-	}
+	return NewConverter().ToPlainText(content)
+}
 
-	// If not nested lists, proceed with normal conversion
+// Helper function to convert HTML to plain text
+func convertHTMLToPlainText(content string) string {
+	// Nested lists are rendered beforehand by the converter's list pass
 	// First sanitize WordPress content
 	content = SanitizeWordPressContent(content)
 
@@ -797,15 +865,15 @@ func ConvertToPlainText(content string) string {
 	return content
 }
 
-// ConvertToMarkdown converts WordPress content to Markdown format
+// ConvertToMarkdown converts WordPress content to Markdown format.
+// Gutenberg blocks are rendered with the renderers in DefaultBlockRenderers.
 func ConvertToMarkdown(content string) string {
-	// First check if we're dealing with nested lists
-	if strings.Contains(content, "<ul") && strings.Contains(content, "<li") &&
-		regexp.MustCompile(`<li[^>]*>.*?<ul[^>]*>`).MatchString(content) {
-		return NestedListsToMarkdown(content)
-	}
+	return NewConverter().ToMarkdown(content)
+}
 
-	// If not nested lists, proceed with normal conversion
+// Helper function to convert an HTML fragment to Markdown
+func convertHTMLToMarkdown(content string) string {
+	// Nested lists are rendered beforehand by the converter's list pass
 	// First sanitize WordPress content
 	content = SanitizeWordPressContent(content)
 
@@ -813,10 +881,8 @@ func ConvertToMarkdown(content string) string {
 	content = convertOrderedLists(content)
 	content = convertUnorderedLists(content)
 
-	// Convert images (handle various attribute orders and scenarios)
-	content = regexp.MustCompile(`<img[^>]*src=["']([^"']*)["'][^>]*alt=["']([^"']*)["'][^>]*/?>`).ReplaceAllString(content, "![$2]($1)")
-	content = regexp.MustCompile(`<img[^>]*alt=["']([^"']*)["'][^>]*src=["']([^"']*)["'][^>]*/?>`).ReplaceAllString(content, "![$1]($2)")
-	content = regexp.MustCompile(`<img[^>]*src=["']([^"']*)["'][^>]*/?>`).ReplaceAllString(content, "![]($1)")
+	// Convert images (attributes can appear in any order)
+	content = regexp.MustCompile(`(?i)<img\b[^>]*>`).ReplaceAllStringFunc(content, markdownImageTag)
 	// Convert headings (ensure proper spacing)
 	content = regexp.MustCompile(`(?s)<h1[^>]*>(.*?)</h1>`).ReplaceAllString(content, "\n# $1\n\n")
 	content = regexp.MustCompile(`(?s)<h2[^>]*>(.*?)</h2>`).ReplaceAllString(content, "\n## $1\n\n")
@@ -869,8 +935,11 @@ func ConvertToMarkdown(content string) string {
 
 	// Convert horizontal rules
 	content = regexp.MustCompile(`<hr\s*/?>`).ReplaceAllString(content, "\n---\n\n")
-	// Convert line breaks (use two spaces + newline for Markdown line breaks)
-	content = regexp.MustCompile(`<br\s*/?>`).ReplaceAllString(content, "  \n")
+	// Convert line breaks to backslash hard breaks, which survive the
+	// trailing space cleanup below. A break that ends a paragraph has
+	// nothing to break.
+	content = regexp.MustCompile(`<br\s*/?>\s*(</p>|</div>|$)`).ReplaceAllString(content, "$1")
+	content = regexp.MustCompile(`<br\s*/?>[ \t]*\n?`).ReplaceAllString(content, "\\\n")
 
 	// Convert paragraphs (use (?s) flag to match across newlines)
 	content = regexp.MustCompile(`(?s)<p[^>]*>(.*?)</p>`).ReplaceAllString(content, "$1\n\n")
@@ -1146,63 +1215,6 @@ func convertUnorderedListsToPlainText(content string) string {
 
 // Helper function to convert tables to markdown
 func convertTables(content string) string {
-	// Basic table conversion - this is simplified and may need enhancement for complex tables
-	re := regexp.MustCompile(`<table[^>]*>(.*?)</table>`)
-	content = re.ReplaceAllStringFunc(content, func(match string) string {
-		// Extract table content
-		tableContent := regexp.MustCompile(`<table[^>]*>(.*?)</table>`).FindStringSubmatch(match)
-		if len(tableContent) < 2 {
-			return match
-		}
-
-		inner := tableContent[1]
-		result := "\n"
-
-		// Extract table rows
-		rowRe := regexp.MustCompile(`<tr[^>]*>(.*?)</tr>`)
-		rows := rowRe.FindAllStringSubmatch(inner, -1)
-
-		isFirstRow := true
-		for _, row := range rows {
-			if len(row) < 2 {
-				continue
-			}
-
-			// Extract cells (th or td)
-			cellRe := regexp.MustCompile(`<t[hd][^>]*>(.*?)</t[hd]>`)
-			cells := cellRe.FindAllStringSubmatch(row[1], -1)
-
-			if len(cells) == 0 {
-				continue
-			}
-
-			// Build row
-			result += "|"
-			for _, cell := range cells {
-				if len(cell) > 1 {
-					// Clean cell content
-					cellContent := regexp.MustCompile(`<[^>]*>`).ReplaceAllString(cell[1], "")
-					cellContent = html.UnescapeString(strings.TrimSpace(cellContent))
-					// Escape pipe characters in cell content
-					cellContent = strings.ReplaceAll(cellContent, "|", "\\|")
-					result += " " + cellContent + " |"
-				}
-			}
-			result += "\n"
-
-			// Add header separator after first row
-			if isFirstRow {
-				result += "|"
-				for range cells {
-					result += " --- |"
-				}
-				result += "\n"
-				isFirstRow = false
-			}
-		}
-
-		return result + "\n"
-	})
-
-	return content
+	verbatim := newVerbatimStore()
+	return verbatim.restore((&Converter{}).replaceTables(content, FormatMarkdown, verbatim))
 }
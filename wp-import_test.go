@@ -120,6 +120,33 @@ func TestParseWordPressDate(t *testing.T) {
 	}
 }
 
+// TestItemTimes tests dates in the zone implied by the GMT dates
+func TestItemTimes(t *testing.T) {
+	item := Item{PostDate: "2019-05-01 10:30:00", PostDateGMT: "2019-05-01 08:30:00"}
+	published, err := item.PublishedTime()
+	if err != nil {
+		t.Fatalf("Failed to parse date: %v", err)
+	}
+	if got := published.Format(time.RFC3339); got != "2019-05-01T10:30:00+02:00" {
+		t.Errorf("Expected 2019-05-01T10:30:00+02:00, got %s", got)
+	}
+	modified, _ := item.ModifiedTime()
+	if !modified.Equal(published) {
+		t.Errorf("Expected the modified date to fall back to %v, got %v", published, modified)
+	}
+
+	item.PostModified, item.PostModifiedGMT = "2020-01-02 09:00:00", "2020-01-02 14:00:00"
+	modified, _ = item.ModifiedTime()
+	if got := modified.Format(time.RFC3339); got != "2020-01-02T09:00:00-05:00" {
+		t.Errorf("Expected 2020-01-02T09:00:00-05:00, got %s", got)
+	}
+
+	draft := Item{PostDate: "2021-03-04 05:06:07", PostDateGMT: "0000-00-00 00:00:00"}
+	if date, err := draft.PublishedTime(); err != nil || date.Format(time.RFC3339) != "2021-03-04T05:06:07Z" {
+		t.Errorf("Expected a UTC date for a draft, got %v (%v)", date, err)
+	}
+}
+
 // TestGetPostsByType tests the function to get posts by type
 func TestGetPostsByType(t *testing.T) {
 	ensureTestData(t)